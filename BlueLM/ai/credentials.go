@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 凭据查找顺序：请求中的 app_id/app_key > 请求指定的配置档案 vivo_ai.profiles > 环境变量 > config.yaml
+// 环境变量依次查找 BLUELM_APP_ID/BLUELM_APP_KEY 和 APPID/APPKEY
+var envCredentialKeys = [][2]string{
+	{"BLUELM_APP_ID", "BLUELM_APP_KEY"},
+	{"APPID", "APPKEY"},
+}
+
+var (
+	// ErrNoCredentials 查找链上没有可用的凭据
+	ErrNoCredentials = errors.New("no vivo AI credentials configured, provide app_id/app_key or a profile in the request")
+	// ErrUnknownProfile 请求指定的配置档案不存在
+	ErrUnknownProfile = errors.New("unknown credential profile")
+)
+
+// Credentials 请求中携带的凭据，均为可选
+type Credentials struct {
+	AppID   string
+	AppKey  string
+	Profile string // vivo_ai.profiles 中的档案名
+}
+
+// credentialPair 一对 vivo 凭据
+type credentialPair struct {
+	appID  string
+	appKey string
+}
+
+// cacheKey 客户端缓存的键，缓存中不保存明文凭据
+func (p credentialPair) cacheKey() [sha256.Size]byte {
+	return sha256.Sum256([]byte(p.appID + "\x00" + p.appKey))
+}
+
+// usable 两项均已填写且不是配置模板中的占位符（如 YOUR_VIVO_APP_ID）
+func (p credentialPair) usable() bool {
+	return p.appID != "" && p.appKey != "" && !isPlaceholder(p.appID) && !isPlaceholder(p.appKey)
+}
+
+// clientCacheEntry 缓存中的一个 vivo 客户端
+type clientCacheEntry struct {
+	key      [sha256.Size]byte
+	client   *VivoProvider
+	lastUsed time.Time
+}
+
+// Resolver 按查找顺序为每个请求选择凭据，并按凭据缓存 vivo 客户端
+// 缓存为 LRU，条数不超过 vivo_ai.client_cache_size，闲置超过 vivo_ai.client_idle_ttl 的客户端被移出，
+// 因此请求中携带的凭据不会让缓存无限增长，也不会被长期保留
+type Resolver struct {
+	cfg  *config.Config
+	mock Provider
+
+	mu      sync.Mutex
+	lru     *list.List // 最近使用的在前
+	clients map[[sha256.Size]byte]*list.Element
+}
+
+// NewResolver 根据配置创建凭据解析器
+// 使用 vivo 时，环境变量、config.yaml 和配置档案中都没有凭据则返回错误
+func NewResolver(cfg *config.Config) (*Resolver, error) {
+	r := &Resolver{cfg: cfg, lru: list.New(), clients: make(map[[sha256.Size]byte]*list.Element)}
+	switch cfg.AI.Provider {
+	case ProviderMock:
+		r.mock = NewMockProvider()
+		return r, nil
+	case ProviderVivo:
+	default:
+		return nil, fmt.Errorf("unsupported ai provider %q (supported: vivo, mock)", cfg.AI.Provider)
+	}
+
+	if _, source, ok := r.defaultCredentials(); ok {
+		utils.Log.Infof("Default vivo AI credentials loaded from %s", source)
+		return r, nil
+	}
+	if cfg.VivoAI.AppID == "" && cfg.VivoAI.AppKey == "" && len(cfg.VivoAI.Profiles) == 0 {
+		return nil, fmt.Errorf("APPID and APPKEY must be provided via environment variables or config file")
+	}
+	utils.Log.Warnf("No usable default vivo AI credentials, requests must provide app_id/app_key or a profile")
+	return r, nil
+}
+
+// Name 返回配置的提供方名称
+func (r *Resolver) Name() string {
+	return r.cfg.AI.Provider
+}
+
+// Resolve 返回本次请求使用的提供方，模拟提供方忽略凭据
+func (r *Resolver) Resolve(creds Credentials) (Provider, error) {
+	if r.mock != nil {
+		return r.mock, nil
+	}
+
+	pair, source, err := r.lookup(creds)
+	if err != nil {
+		return nil, err
+	}
+	utils.Log.Infof("Using vivo AI credentials from %s (app_id %s)", source, maskAppID(pair.appID))
+	return r.client(pair), nil
+}
+
+// lookup 按查找顺序返回第一组可用的凭据及其来源
+func (r *Resolver) lookup(creds Credentials) (credentialPair, string, error) {
+	if pair := (credentialPair{creds.AppID, creds.AppKey}); pair.usable() {
+		return pair, "request", nil
+	}
+
+	if creds.Profile != "" {
+		profile, exists := r.cfg.VivoAI.Profiles[creds.Profile]
+		if !exists {
+			return credentialPair{}, "", fmt.Errorf("%w: %s", ErrUnknownProfile, creds.Profile)
+		}
+		pair := credentialPair{profile.AppID, profile.AppKey}
+		if !pair.usable() {
+			return credentialPair{}, "", fmt.Errorf("credential profile %s is incomplete", creds.Profile)
+		}
+		return pair, "profile " + creds.Profile, nil
+	}
+
+	if pair, source, ok := r.defaultCredentials(); ok {
+		return pair, source, nil
+	}
+	return credentialPair{}, "", ErrNoCredentials
+}
+
+// defaultCredentials 查找不依赖请求的凭据：环境变量 > config.yaml
+func (r *Resolver) defaultCredentials() (credentialPair, string, bool) {
+	for _, keys := range envCredentialKeys {
+		if pair := (credentialPair{os.Getenv(keys[0]), os.Getenv(keys[1])}); pair.usable() {
+			return pair, "environment " + keys[0] + "/" + keys[1], true
+		}
+	}
+	if pair := (credentialPair{r.cfg.VivoAI.AppID, r.cfg.VivoAI.AppKey}); pair.usable() {
+		return pair, "config.yaml", true
+	}
+	return credentialPair{}, "", false
+}
+
+// client 返回凭据对应的客户端，同一组凭据在缓存期内复用同一个实例
+func (r *Resolver) client(pair credentialPair) *VivoProvider {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	// 最久未使用的在队尾，从队尾移除闲置过久的客户端
+	for back := r.lru.Back(); back != nil; back = r.lru.Back() {
+		entry := back.Value.(*clientCacheEntry)
+		if now.Sub(entry.lastUsed) <= r.cfg.VivoAI.ClientIdleTTL {
+			break
+		}
+		r.lru.Remove(back)
+		delete(r.clients, entry.key)
+	}
+
+	key := pair.cacheKey()
+	if elem, exists := r.clients[key]; exists {
+		entry := elem.Value.(*clientCacheEntry)
+		entry.lastUsed = now
+		r.lru.MoveToFront(elem)
+		return entry.client
+	}
+
+	entry := &clientCacheEntry{key: key, client: NewVivoProvider(pair.appID, pair.appKey), lastUsed: now}
+	r.clients[key] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.cfg.VivoAI.ClientCacheSize {
+		back := r.lru.Back()
+		r.lru.Remove(back)
+		delete(r.clients, back.Value.(*clientCacheEntry).key)
+	}
+	return entry.client
+}
+
+// isPlaceholder 判断是否为配置模板中的占位符
+func isPlaceholder(value string) bool {
+	return strings.HasPrefix(strings.ToUpper(value), "YOUR_")
+}
+
+// maskAppID 日志中只显示 AppID 的前四位
+func maskAppID(appID string) string {
+	if len(appID) <= 4 {
+		return "****"
+	}
+	return appID[:4] + "****"
+}
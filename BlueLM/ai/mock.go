@@ -0,0 +1,291 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MockProvider 不访问网络的模拟实现，相同的输入总是得到相同的输出
+// 用于 CI 和没有 vivo 凭据的开发环境
+type MockProvider struct{}
+
+// NewMockProvider 创建模拟提供方
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+func (p *MockProvider) Name() string {
+	return ProviderMock
+}
+
+func (p *MockProvider) Chat(sessionID string, messages []ChatMessage) (ChatMessage, error) {
+	if len(messages) == 0 {
+		return ChatMessage{}, fmt.Errorf("messages cannot be empty")
+	}
+	return ChatMessage{Role: RoleAssistant, Content: mockReply(messages[len(messages)-1].Content)}, nil
+}
+
+func (p *MockProvider) ChatStream(ctx context.Context, sessionID string, messages []ChatMessage, during func(delta string)) error {
+	reply, err := p.Chat(sessionID, messages)
+	if err != nil {
+		return err
+	}
+	// 按固定字数切分，模拟增量输出
+	runes := []rune(reply.Content)
+	for i := 0; i < len(runes); i += 4 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		during(string(runes[i:min(i+4, len(runes))]))
+	}
+	return nil
+}
+
+func (p *MockProvider) EasyChat(sessionID, prompt, systemPrompt string) (string, error) {
+	return mockReply(prompt), nil
+}
+
+func (p *MockProvider) EvaluationChat(sessionID, prompt, systemPrompt string, scoreKeys []string) (string, error) {
+	if len(scoreKeys) == 0 {
+		return "", fmt.Errorf("score keys cannot be empty")
+	}
+	return mockEvaluation(prompt, scoreKeys), nil
+}
+
+// mockEvaluation 生成评估 JSON，各维度分数由输入决定，在 60-99 之间
+func mockEvaluation(prompt string, keys []string) string {
+	scores := make(map[string]int, len(keys))
+	for _, key := range keys {
+		scores[key] = 60 + int(hash32(key+"\x00"+prompt)%40)
+	}
+	output, _ := json.Marshal(struct {
+		Summary string         `json:"summary"`
+		Scores  map[string]int `json:"scores"`
+		Advice  []string       `json:"advice"`
+	}{
+		Summary: "[mock] 翻译基本准确",
+		Scores:  scores,
+		Advice:  []string{"[mock] 参考标准答案调整措辞"},
+	})
+	return string(output)
+}
+
+// TTS 生成与文本长度成正比的正弦波，音高由音色决定
+func (p *MockProvider) TTS(mode, vcn, text string) ([]byte, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	duration := max(200*time.Millisecond, time.Duration(utf8.RuneCountInString(text))*60*time.Millisecond)
+	frequency := 220 + float64(hash32(vcn)%8)*55
+	samples := int(duration.Seconds() * TTSSampleRate)
+
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		v := 0.3 * math.Sin(2*math.Pi*frequency*float64(i)/TTSSampleRate)
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(v*math.MaxInt16)))
+	}
+	return pcm, nil
+}
+
+// MockOCRPosition 模拟 OCR 的带坐标结果，JSON 结构与 vivo 一致
+type MockOCRPosition struct {
+	Location struct {
+		DownLeft  MockOCRPoint `json:"down_left"`
+		DownRight MockOCRPoint `json:"down_right"`
+		TopLeft   MockOCRPoint `json:"top_left"`
+		TopRight  MockOCRPoint `json:"top_right"`
+	} `json:"location"`
+	Words string `json:"words"`
+}
+
+// MockOCRPoint 坐标点
+type MockOCRPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (p *MockProvider) OCR(image []byte, mode int) (interface{}, error) {
+	if len(image) == 0 {
+		return nil, fmt.Errorf("image cannot be empty")
+	}
+	sum := sha256.Sum256(image)
+	text := fmt.Sprintf("mock OCR result for %d bytes image %s", len(image), hex.EncodeToString(sum[:4]))
+
+	var pos MockOCRPosition
+	pos.Words = text
+	pos.Location.TopRight = MockOCRPoint{X: 100}
+	pos.Location.DownLeft = MockOCRPoint{Y: 20}
+	pos.Location.DownRight = MockOCRPoint{X: 100, Y: 20}
+
+	switch mode {
+	case OCRModePosition:
+		return []MockOCRPosition{pos}, nil
+	case OCRModeAll:
+		return struct {
+			Pos  []MockOCRPosition
+			Word string
+		}{Pos: []MockOCRPosition{pos}, Word: text + "\n"}, nil
+	}
+	return text + "\n", nil
+}
+
+// TextSimilarity 使用字符二元组的 Dice 系数作为相似度
+func (p *MockProvider) TextSimilarity(query string, candidates []string) ([]float64, error) {
+	scores := make([]float64, len(candidates))
+	queryBigrams := bigrams(query)
+	for i, candidate := range candidates {
+		scores[i] = dice(queryBigrams, bigrams(candidate))
+	}
+	return scores, nil
+}
+
+// Embeddings 将字符哈希到固定维度并归一化
+func (p *MockProvider) Embeddings(texts []string) ([][]float64, error) {
+	const dimensions = 64
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, dimensions)
+		for _, r := range text {
+			vector[hash32(string(r))%dimensions]++
+		}
+		var norm float64
+		for _, v := range vector {
+			norm += v * v
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vector {
+				vector[j] /= norm
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (p *MockProvider) Translate(from, to, text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("text cannot be empty")
+	}
+	return fmt.Sprintf("[mock] %s -> %s: %s", from, to, text), nil
+}
+
+func (p *MockProvider) NewTranscription(filePath string) Transcription {
+	return &mockTranscription{filePath: filePath}
+}
+
+// mockTranscription 每次查询进度前进 25%，结果句数由文件大小决定
+type mockTranscription struct {
+	mu       sync.Mutex
+	filePath string
+	size     int64
+	taskID   string
+	progress int
+}
+
+func (t *mockTranscription) Upload() error {
+	info, err := os.Stat(t.filePath)
+	if err != nil {
+		return err
+	}
+	t.size = info.Size()
+	return nil
+}
+
+func (t *mockTranscription) Start() error {
+	if t.taskID != "" {
+		return fmt.Errorf("task already started")
+	}
+	// 任务ID需要全局唯一，不能只由文件内容决定
+	t.taskID = fmt.Sprintf("mock-%x", time.Now().UnixNano())
+	return nil
+}
+
+func (t *mockTranscription) TaskID() string {
+	return t.taskID
+}
+
+func (t *mockTranscription) Progress() (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.taskID == "" {
+		return 0, fmt.Errorf("task uncreated")
+	}
+	t.progress = min(100, t.progress+25)
+	return t.progress, nil
+}
+
+func (t *mockTranscription) Result() ([]TranscriptionSegment, error) {
+	if t.taskID == "" {
+		return nil, fmt.Errorf("task uncreated")
+	}
+	count := int(min(20, 1+t.size/(64*1024)))
+	name := filepath.Base(t.filePath)
+	segments := make([]TranscriptionSegment, count)
+	for i := range segments {
+		segments[i] = TranscriptionSegment{
+			Bg:      i * 3000,
+			Ed:      i*3000 + 2500,
+			Onebest: fmt.Sprintf("模拟转写第%d句（%s）", i+1, name),
+		}
+	}
+	return segments, nil
+}
+
+// mockReply 根据输入生成固定格式的回复
+func mockReply(prompt string) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if utf8.RuneCountInString(prompt) > 50 {
+		prompt = string([]rune(prompt)[:50]) + "…"
+	}
+	return fmt.Sprintf("[mock] 已收到：%s", prompt)
+}
+
+func hash32(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func bigrams(s string) map[string]int {
+	runes := []rune(strings.ToLower(strings.Join(strings.Fields(s), " ")))
+	result := make(map[string]int)
+	if len(runes) == 1 {
+		result[string(runes)]++
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		result[string(runes[i:i+2])]++
+	}
+	return result
+}
+
+func dice(a, b map[string]int) float64 {
+	total := 0
+	for _, n := range a {
+		total += n
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	common := 0
+	for k, n := range a {
+		common += min(n, b[k])
+	}
+	return 2 * float64(common) / float64(total)
+}
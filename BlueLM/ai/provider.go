@@ -0,0 +1,95 @@
+// Package ai 抽象蓝心大模型提供的 AI 能力，便于在没有 vivo 凭据和网络的环境下使用模拟实现
+package ai
+
+import (
+	"context"
+
+	"github.com/dingdinglz/vivo"
+)
+
+// 提供方名称，对应 config.yaml 中的 ai.provider
+const (
+	ProviderVivo = "vivo"
+	ProviderMock = "mock"
+)
+
+// 对话角色，与 vivo SDK 的 CHAT_ROLE_* 一致
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleSystem    = "system"
+	RoleFunction  = "function"
+)
+
+// TTS 输出的 PCM 格式：24kHz、16 位、单声道
+const (
+	TTSSampleRate    = 24000
+	TTSBitsPerSample = 16
+	TTSChannels      = 1
+)
+
+// OCR 模式，与 vivo SDK 的 OCR_MODE_* 一致
+const (
+	OCRModeText     = 0 // 仅返回文字
+	OCRModePosition = 1 // 返回文字及坐标
+	OCRModeAll      = 2 // 同时返回以上两种结果
+)
+
+// ChatMessage 对话消息
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TranscriptionSegment 长语音转写结果中的一句，时间单位为毫秒
+// 字段与 vivo 返回的结果一致，保证转写结果文件格式不变
+type TranscriptionSegment struct {
+	Ed      int    `json:"ed"`
+	Onebest string `json:"onebest"`
+	Bg      int    `json:"bg"`
+}
+
+// Transcription 长语音转写任务
+type Transcription interface {
+	Upload() error
+	Start() error
+	TaskID() string
+	Progress() (int, error) // 0-100
+	Result() ([]TranscriptionSegment, error)
+}
+
+// Provider AI 能力提供方
+type Provider interface {
+	Name() string
+
+	// Chat 多轮对话
+	Chat(sessionID string, messages []ChatMessage) (ChatMessage, error)
+	// ChatStream 流式多轮对话，during 在收到每段增量内容时被调用，ctx 结束时中断上游请求并返回 ctx 的错误
+	ChatStream(ctx context.Context, sessionID string, messages []ChatMessage, during func(delta string)) error
+	// EasyChat 单轮对话
+	EasyChat(sessionID, prompt, systemPrompt string) (string, error)
+	// EvaluationChat 翻译评估的单轮对话，要求回复为 {summary, scores, advice} 格式的 JSON，
+	// scores 包含 scoreKeys 中的每个评分维度；提示词由调用方给出
+	EvaluationChat(sessionID, prompt, systemPrompt string, scoreKeys []string) (string, error)
+
+	// TTS 语音合成，返回 24kHz 16 位单声道 PCM
+	TTS(mode, vcn, text string) ([]byte, error)
+	// OCR 图片文字识别
+	OCR(image []byte, mode int) (interface{}, error)
+
+	// TextSimilarity 计算 query 与每个候选文本的相似度
+	TextSimilarity(query string, candidates []string) ([]float64, error)
+	// Embeddings 计算文本向量
+	Embeddings(texts []string) ([][]float64, error)
+
+	// Translate 机器翻译，语言代码使用 vivo 翻译接口的格式（如 zh-CHS、en）
+	Translate(from, to, text string) (string, error)
+
+	// NewTranscription 创建长语音转写任务
+	NewTranscription(filePath string) Transcription
+}
+
+// NewSessionID 生成会话ID
+func NewSessionID() string {
+	return vivo.GenerateSessionID()
+}
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"reflect"
+
+	"github.com/dingdinglz/vivo"
+)
+
+// VivoProvider 基于 vivo SDK 的实现
+type VivoProvider struct {
+	app *vivo.Vivo
+
+	// 流式对话不经过 SDK，需要自行签名
+	appID  string
+	appKey string
+}
+
+// NewVivoProvider 使用指定凭据创建 vivo 提供方
+func NewVivoProvider(appID, appKey string) *VivoProvider {
+	return &VivoProvider{
+		app: vivo.NewVivoAIGC(vivo.Config{
+			AppID:  appID,
+			AppKey: appKey,
+		}),
+		appID:  appID,
+		appKey: appKey,
+	}
+}
+
+func (p *VivoProvider) Name() string {
+	return ProviderVivo
+}
+
+func (p *VivoProvider) Chat(sessionID string, messages []ChatMessage) (ChatMessage, error) {
+	res, err := p.app.Chat(vivo.GenerateRequestID(), sessionID, toVivoMessages(messages), nil)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	return ChatMessage{Role: res.Role, Content: res.Content}, nil
+}
+
+func (p *VivoProvider) EasyChat(sessionID, prompt, systemPrompt string) (string, error) {
+	if systemPrompt == "" {
+		return p.app.EasyChat(sessionID, prompt)
+	}
+	return p.app.EasyChat(sessionID, prompt, systemPrompt)
+}
+
+// EvaluationChat 输出格式由系统提示词约束，scoreKeys 只供模拟实现使用
+func (p *VivoProvider) EvaluationChat(sessionID, prompt, systemPrompt string, scoreKeys []string) (string, error) {
+	return p.EasyChat(sessionID, prompt, systemPrompt)
+}
+
+func (p *VivoProvider) TTS(mode, vcn, text string) ([]byte, error) {
+	return p.app.TTS(mode, vcn, text)
+}
+
+func (p *VivoProvider) OCR(image []byte, mode int) (interface{}, error) {
+	return p.app.OCR(image, mode)
+}
+
+func (p *VivoProvider) TextSimilarity(query string, candidates []string) ([]float64, error) {
+	return p.app.TextSimilarity(vivo.TEXT_SIMILARITY_MODEL_BGE_LARGE, query, candidates)
+}
+
+func (p *VivoProvider) Embeddings(texts []string) ([][]float64, error) {
+	return p.app.TextVector(vivo.VECTOR_MODEL_M3E, texts)
+}
+
+func (p *VivoProvider) Translate(from, to, text string) (string, error) {
+	return p.app.Translate(from, to, text)
+}
+
+func (p *VivoProvider) NewTranscription(filePath string) Transcription {
+	return &vivoTranscription{trans: p.app.NewTranscription(filePath)}
+}
+
+// vivoTranscription 包装 SDK 的长语音转写任务
+type vivoTranscription struct {
+	trans *vivo.Transcription
+}
+
+func (t *vivoTranscription) Upload() error {
+	return t.trans.Upload()
+}
+
+func (t *vivoTranscription) Start() error {
+	return t.trans.Start()
+}
+
+// TaskID uses reflection to access the private taskID field
+func (t *vivoTranscription) TaskID() string {
+	v := reflect.ValueOf(t.trans).Elem()
+	taskIDField := v.FieldByName("taskID")
+	if !taskIDField.IsValid() {
+		return "unknown"
+	}
+	return taskIDField.String()
+}
+
+func (t *vivoTranscription) Progress() (int, error) {
+	return t.trans.GetTaskInfo()
+}
+
+func (t *vivoTranscription) Result() ([]TranscriptionSegment, error) {
+	result, err := t.trans.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]TranscriptionSegment, 0, len(result))
+	for _, r := range result {
+		segments = append(segments, TranscriptionSegment{Bg: r.Bg, Ed: r.Ed, Onebest: r.Onebest})
+	}
+	return segments, nil
+}
+
+func toVivoMessages(messages []ChatMessage) []vivo.ChatMessage {
+	result := make([]vivo.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, vivo.ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dingdinglz/vivo"
+)
+
+// 蓝心流式对话接口，与 SDK 的 ChatStream 使用相同的地址和模型
+const (
+	vivoChatStreamURL = "https://api-ai.vivo.com.cn/vivogpt/completions/stream"
+	vivoChatModel     = "vivo-BlueLM-TB-Pro"
+)
+
+// vivoChatStreamRequest 流式对话请求体
+type vivoChatStreamRequest struct {
+	Messages  []vivo.ChatMessage `json:"messages"`
+	Model     string             `json:"model"`
+	SessionID string             `json:"sessionId"`
+}
+
+// vivoChatStreamData 流式响应中 data 行的内容
+type vivoChatStreamData struct {
+	Message string `json:"message"`
+	Reply   string `json:"reply"`
+	Msg     string `json:"msg"`
+}
+
+// ChatStream 流式多轮对话
+// SDK 的 ChatStream 不支持 context，这里按相同的协议自行发起请求，ctx 结束时立即断开上游连接
+func (p *VivoProvider) ChatStream(ctx context.Context, sessionID string, messages []ChatMessage, during func(delta string)) error {
+	body, err := json.Marshal(vivoChatStreamRequest{
+		Messages:  toVivoMessages(messages),
+		Model:     vivoChatModel,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return err
+	}
+	query := url.Values{"requestId": {vivo.GenerateRequestID()}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, vivoChatStreamURL+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.sign(req, query)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return vivoResponseError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	errorEvent, antispamEvent := false, false
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			var data vivoChatStreamData
+			json.Unmarshal([]byte(line[len("data:"):]), &data)
+			if errorEvent {
+				return errors.New(data.Msg)
+			}
+			if antispamEvent {
+				return errors.New(data.Reply)
+			}
+			during(data.Message)
+		case strings.HasPrefix(line, "event:"):
+			switch line[len("event:"):] {
+			case "error":
+				errorEvent = true
+			case "antispam":
+				antispamEvent = true
+			case "close":
+				return nil
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// sign 按 vivo AI 网关的要求为请求签名
+func (p *VivoProvider) sign(req *http.Request, query url.Values) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := vivoNonce()
+	signing := strings.Join([]string{
+		req.Method,
+		req.URL.Path,
+		query.Encode(),
+		p.appID,
+		timestamp,
+		"x-ai-gateway-app-id:" + p.appID,
+		"x-ai-gateway-timestamp:" + timestamp,
+		"x-ai-gateway-nonce:" + nonce,
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(p.appKey))
+	mac.Write([]byte(signing))
+
+	req.Header.Set("X-AI-GATEWAY-APP-ID", p.appID)
+	req.Header.Set("X-AI-GATEWAY-TIMESTAMP", timestamp)
+	req.Header.Set("X-AI-GATEWAY-NONCE", nonce)
+	req.Header.Set("X-AI-GATEWAY-SIGNED-HEADERS", "x-ai-gateway-app-id;x-ai-gateway-timestamp;x-ai-gateway-nonce")
+	req.Header.Set("X-AI-GATEWAY-SIGNATURE", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// vivoNonce 生成 8 位小写字母和数字组成的随机串
+func vivoNonce() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	for i, b := range nonce {
+		nonce[i] = letters[int(b)%len(letters)]
+	}
+	return string(nonce)
+}
+
+// vivoResponseError 从非 200 响应中提取错误信息
+func vivoResponseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var result struct {
+		Message string `json:"message"`
+		Msg     string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("vivo AI returned status %d: %s", resp.StatusCode, body)
+	}
+	if result.Message != "" {
+		return errors.New(result.Message)
+	}
+	return errors.New(result.Msg)
+}
@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的输出格式，同时用作文件扩展名
+const (
+	FormatWAV  = "wav"
+	FormatPCM  = "pcm" // 无文件头的 16 位小端有符号整数
+	FormatFLAC = "flac"
+)
+
+// 可选的输出采样率范围
+const (
+	MinSampleRate = 8000
+	MaxSampleRate = 48000
+)
+
+// Spec PCM 数据的采样参数，多声道数据按帧交错排列
+type Spec struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// contentTypes 各格式的 Content-Type
+var contentTypes = map[string]string{
+	FormatWAV:  "audio/wav",
+	FormatPCM:  "application/octet-stream",
+	FormatFLAC: "audio/flac",
+}
+
+// acceptTypes Accept 请求头中可识别的媒体类型
+var acceptTypes = map[string]string{
+	"audio/wav":                FormatWAV,
+	"audio/wave":               FormatWAV,
+	"audio/x-wav":              FormatWAV,
+	"audio/vnd.wave":           FormatWAV,
+	"audio/flac":               FormatFLAC,
+	"audio/x-flac":             FormatFLAC,
+	"audio/pcm":                FormatPCM,
+	"application/octet-stream": FormatPCM,
+}
+
+// Formats 返回支持的输出格式
+func Formats() []string {
+	return []string{FormatWAV, FormatPCM, FormatFLAC}
+}
+
+// ContentType 返回格式的 Content-Type，不支持的格式返回空字符串
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Negotiate 按 Accept 请求头中的 q 值选择输出格式
+// 没有可识别的媒体类型时返回空字符串，*/* 和 audio/* 不指定格式，由调用方使用默认格式
+func Negotiate(accept string) string {
+	type candidate struct {
+		format string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		format, ok := acceptTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{format, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].format
+}
+
+// Samples 将 16 位小端 PCM 转换为采样值，丢弃末尾不完整的采样
+func Samples(pcm []byte) []int16 {
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(pcm[i*2:]))
+	}
+	return samples
+}
+
+// Bytes 将采样值转换为 16 位小端 PCM
+func Bytes(samples []int16) []byte {
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(s))
+	}
+	return pcm
+}
+
+// Encode 将 16 位 PCM 编码为指定格式写入 w，sampleRate 不为0且与 spec 不同时先重采样
+func Encode(w io.Writer, format string, pcm []byte, spec Spec, sampleRate int) error {
+	if spec.BitsPerSample != 16 {
+		return fmt.Errorf("only 16-bit PCM is supported, got %d bits", spec.BitsPerSample)
+	}
+	if spec.Channels <= 0 {
+		return fmt.Errorf("invalid channel count %d", spec.Channels)
+	}
+	if ContentType(format) == "" {
+		return fmt.Errorf("unsupported audio format %q", format)
+	}
+
+	// 丢弃不完整的采样帧，避免声道错位
+	frameSize := spec.Channels * 2
+	pcm = pcm[:len(pcm)-len(pcm)%frameSize]
+	var samples []int16
+	if sampleRate > 0 && sampleRate != spec.SampleRate {
+		samples = Resample(Samples(pcm), spec.Channels, spec.SampleRate, sampleRate)
+		spec.SampleRate = sampleRate
+		pcm = Bytes(samples)
+	}
+
+	switch format {
+	case FormatWAV:
+		ww, err := NewWAVWriter(w, spec, int64(len(pcm)))
+		if err != nil {
+			return err
+		}
+		if _, err := ww.Write(pcm); err != nil {
+			return err
+		}
+		return ww.Close()
+	case FormatPCM:
+		_, err := w.Write(pcm)
+		return err
+	default:
+		if samples == nil {
+			samples = Samples(pcm)
+		}
+		return EncodeFLAC(w, samples, spec)
+	}
+}
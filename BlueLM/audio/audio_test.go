@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"*/*", ""},
+		{"audio/*", ""},
+		{"audio/flac", FormatFLAC},
+		{"audio/x-wav", FormatWAV},
+		{"text/html, application/octet-stream", FormatPCM},
+		{"audio/wav;q=0.5, audio/flac;q=0.9", FormatFLAC},
+		{"audio/flac, audio/wav", FormatFLAC},
+		{"audio/wav, audio/flac", FormatWAV},
+		{"audio/flac;q=0, audio/wav;q=0.1", FormatWAV},
+		{"audio/flac;q=0", ""},
+		{"audio/flac;q=abc, audio/wav;q=0.2", FormatWAV},
+		{"AUDIO/FLAC", FormatFLAC},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.accept); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	spec := Spec{SampleRate: 24000, Channels: 1, BitsPerSample: 16}
+	pcm := Bytes([]int16{1, -2, 3, -4})
+	tests := []struct {
+		name       string
+		format     string
+		pcm        []byte
+		sampleRate int
+		wantLen    int
+		wantErr    bool
+	}{
+		{"pcm", FormatPCM, pcm, 0, 8, false},
+		{"pcm drops incomplete sample", FormatPCM, append(pcm, 1), 0, 8, false},
+		{"pcm resampled", FormatPCM, pcm, 48000, 16, false},
+		{"wav", FormatWAV, pcm, 0, wavHeaderSize + 8, false},
+		{"unsupported format", "mp3", pcm, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Encode(&buf, tt.format, tt.pcm, spec, tt.sampleRate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && buf.Len() != tt.wantLen {
+				t.Errorf("Encode() wrote %d bytes, want %d", buf.Len(), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package audio
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	// flacBlockSize 每帧每个声道的采样数
+	flacBlockSize = 4096
+	// flacMaxFixedOrder 定长预测的最高阶数
+	flacMaxFixedOrder = 4
+	// flacMaxPartitionOrder 残差分区阶数上限
+	flacMaxPartitionOrder = 8
+	// flacMaxRiceParam 4 位 Rice 参数的最大值，15 为转义码
+	flacMaxRiceParam = 14
+)
+
+// flacSampleRateCodes 帧头中可以直接表示的采样率，其他采样率从 STREAMINFO 读取
+var flacSampleRateCodes = map[int]uint64{
+	88200: 0b0001, 176400: 0b0010, 192000: 0b0011,
+	8000: 0b0100, 16000: 0b0101, 22050: 0b0110, 24000: 0b0111,
+	32000: 0b1000, 44100: 0b1001, 48000: 0b1010, 96000: 0b1011,
+}
+
+// EncodeFLAC 将交错排列的 16 位采样编码为 FLAC 写入 w，逐帧写出
+// 每个声道独立编码：在 0-4 阶定长预测中选择残差最小的一种，残差按分区 Rice 编码，
+// 全部相同的采样用常数子帧，压缩无效时退回原样保存
+func EncodeFLAC(w io.Writer, samples []int16, spec Spec) error {
+	if spec.BitsPerSample != 16 {
+		return fmt.Errorf("only 16-bit PCM is supported, got %d bits", spec.BitsPerSample)
+	}
+	if spec.Channels < 1 || spec.Channels > 8 {
+		return fmt.Errorf("FLAC supports 1 to 8 channels, got %d", spec.Channels)
+	}
+	if spec.SampleRate <= 0 || spec.SampleRate >= 1<<20 {
+		return fmt.Errorf("invalid FLAC sample rate %d", spec.SampleRate)
+	}
+
+	frames := len(samples) / spec.Channels
+	samples = samples[:frames*spec.Channels]
+	if _, err := w.Write(flacStreamHeader(samples, frames, spec)); err != nil {
+		return err
+	}
+
+	channel := make([]int32, flacBlockSize)
+	for frame, start := uint64(0), 0; start < frames; frame, start = frame+1, start+flacBlockSize {
+		blockSize := min(flacBlockSize, frames-start)
+		bw := &bitWriter{}
+		writeFLACFrameHeader(bw, frame, blockSize, spec)
+		for ch := range spec.Channels {
+			for i := range blockSize {
+				channel[i] = int32(samples[(start+i)*spec.Channels+ch])
+			}
+			writeFLACSubframe(bw, channel[:blockSize], spec.BitsPerSample)
+		}
+		bw.align()
+		bw.writeBits(uint64(crc16(bw.buf)), 16)
+		if _, err := w.Write(bw.buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flacStreamHeader 返回 "fLaC" 标记和作为唯一元数据块的 STREAMINFO
+func flacStreamHeader(samples []int16, frames int, spec Spec) []byte {
+	blockSize := flacBlockSize
+	if frames > 0 && frames < flacBlockSize {
+		blockSize = frames
+	}
+	sum := md5.Sum(Bytes(samples))
+
+	bw := &bitWriter{buf: []byte("fLaC")}
+	bw.writeBits(1, 1)   // 最后一个元数据块
+	bw.writeBits(0, 7)   // STREAMINFO
+	bw.writeBits(34, 24) // 块长度
+	bw.writeBits(uint64(blockSize), 16)
+	bw.writeBits(uint64(blockSize), 16)
+	bw.writeBits(0, 24) // 最小帧长未知
+	bw.writeBits(0, 24) // 最大帧长未知
+	bw.writeBits(uint64(spec.SampleRate), 20)
+	bw.writeBits(uint64(spec.Channels-1), 3)
+	bw.writeBits(uint64(spec.BitsPerSample-1), 5)
+	bw.writeBits(uint64(frames), 36)
+	bw.buf = append(bw.buf, sum[:]...)
+	return bw.buf
+}
+
+// writeFLACFrameHeader 写出帧头及其 CRC-8
+func writeFLACFrameHeader(bw *bitWriter, frame uint64, blockSize int, spec Spec) {
+	bw.writeBits(0b11111111111110, 14) // 同步码
+	bw.writeBits(0, 1)                 // 保留位
+	bw.writeBits(0, 1)                 // 固定块长
+	bw.writeBits(0b0111, 4)            // 块长在帧头末尾以 16 位给出
+	bw.writeBits(flacSampleRateCodes[spec.SampleRate], 4)
+	bw.writeBits(uint64(spec.Channels-1), 4) // 各声道独立编码
+	bw.writeBits(0b100, 3)                   // 16 位采样
+	bw.writeBits(0, 1)                       // 保留位
+	writeFLACUTF8(bw, frame)
+	bw.writeBits(uint64(blockSize-1), 16)
+	bw.writeBits(uint64(crc8(bw.buf)), 8)
+}
+
+// writeFLACUTF8 以 UTF-8 的扩展编码写出帧号
+func writeFLACUTF8(bw *bitWriter, v uint64) {
+	if v < 0x80 {
+		bw.writeBits(v, 8)
+		return
+	}
+	n := 2
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	bw.writeBits((0xFF<<(8-n))&0xFF|v>>(6*(n-1)), 8)
+	for i := n - 2; i >= 0; i-- {
+		bw.writeBits(0x80|(v>>(6*i))&0x3F, 8)
+	}
+}
+
+// writeFLACSubframe 为一个声道选择编码方式并写出子帧
+func writeFLACSubframe(bw *bitWriter, x []int32, bps int) {
+	constant := true
+	for _, v := range x[1:] {
+		if v != x[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		bw.writeBits(0b00000000, 8) // 填充位、CONSTANT、无浪费位
+		bw.writeBits(uint64(x[0])&(1<<bps-1), uint(bps))
+		return
+	}
+
+	// 按残差绝对值之和选择预测阶数
+	order, bestSum := 0, uint64(0)
+	residuals := make([][]int32, min(flacMaxFixedOrder, len(x)-1)+1)
+	for o := range residuals {
+		residuals[o] = fixedResidual(x, o)
+		var sum uint64
+		for _, r := range residuals[o] {
+			sum += uint64(abs32(r))
+		}
+		if o == 0 || sum < bestSum {
+			order, bestSum = o, sum
+		}
+	}
+
+	partitionOrder, params, residualBits := bestRicePartition(residuals[order], len(x), order)
+	if order*bps+residualBits >= len(x)*bps {
+		bw.writeBits(0b00000010, 8) // 填充位、VERBATIM、无浪费位
+		for _, v := range x {
+			bw.writeBits(uint64(v)&(1<<bps-1), uint(bps))
+		}
+		return
+	}
+
+	bw.writeBits(uint64(0b00010000|order<<1), 8) // 填充位、FIXED 及阶数、无浪费位
+	for _, v := range x[:order] {
+		bw.writeBits(uint64(v)&(1<<bps-1), uint(bps))
+	}
+	writeRiceResidual(bw, residuals[order], len(x), order, partitionOrder, params)
+}
+
+// fixedResidual 计算 order 阶定长预测的残差，长度为 len(x)-order
+func fixedResidual(x []int32, order int) []int32 {
+	res := make([]int32, len(x)-order)
+	for i := order; i < len(x); i++ {
+		var r int32
+		switch order {
+		case 0:
+			r = x[i]
+		case 1:
+			r = x[i] - x[i-1]
+		case 2:
+			r = x[i] - 2*x[i-1] + x[i-2]
+		case 3:
+			r = x[i] - 3*x[i-1] + 3*x[i-2] - x[i-3]
+		case 4:
+			r = x[i] - 4*x[i-1] + 6*x[i-2] - 4*x[i-3] + x[i-4]
+		}
+		res[i-order] = r
+	}
+	return res
+}
+
+// bestRicePartition 选择总位数最少的分区阶数及各分区的 Rice 参数，返回值包含残差编码头部
+// 分区阶数须使块长能被分区数整除，且分区后第一个分区在扣除预测的起始采样后仍有残差
+func bestRicePartition(res []int32, blockSize, order int) (int, []uint, int) {
+	u := make([]uint64, len(res))
+	for i, r := range res {
+		u[i] = zigzag(r)
+	}
+
+	bestOrder, bestBits := -1, 0
+	var bestParams []uint
+	for p := 0; p <= flacMaxPartitionOrder; p++ {
+		if blockSize%(1<<p) != 0 || (p > 0 && blockSize>>p <= order) {
+			break
+		}
+		partSize := blockSize >> p
+		total := 6 // 编码方式和分区阶数
+		params := make([]uint, 1<<p)
+		offset := 0
+		for i := range params {
+			n := partSize
+			if i == 0 {
+				n -= order
+			}
+			k, cost := riceParam(u[offset : offset+n])
+			params[i] = k
+			total += 4 + cost
+			offset += n
+		}
+		if bestOrder < 0 || total < bestBits {
+			bestOrder, bestBits, bestParams = p, total, params
+		}
+	}
+	return bestOrder, bestParams, bestBits
+}
+
+// riceParam 按均值估计 Rice 参数并在相邻的取值中选择位数最少的一个
+func riceParam(u []uint64) (uint, int) {
+	if len(u) == 0 {
+		return 0, 0
+	}
+	var sum uint64
+	for _, v := range u {
+		sum += v
+	}
+	guess := 0
+	if mean := sum / uint64(len(u)); mean > 0 {
+		guess = bits.Len64(mean) - 1
+	}
+
+	bestK, bestCost := uint(0), -1
+	for k := max(guess-1, 0); k <= min(guess+1, flacMaxRiceParam); k++ {
+		cost := len(u) * (k + 1)
+		for _, v := range u {
+			cost += int(v >> k)
+		}
+		if bestCost < 0 || cost < bestCost {
+			bestK, bestCost = uint(k), cost
+		}
+	}
+	return bestK, bestCost
+}
+
+// writeRiceResidual 按分区写出 Rice 编码的残差
+func writeRiceResidual(bw *bitWriter, res []int32, blockSize, order, partitionOrder int, params []uint) {
+	bw.writeBits(0b00, 2) // 4 位 Rice 参数
+	bw.writeBits(uint64(partitionOrder), 4)
+	partSize := blockSize >> partitionOrder
+	offset := 0
+	for i, k := range params {
+		n := partSize
+		if i == 0 {
+			n -= order
+		}
+		bw.writeBits(uint64(k), 4)
+		for _, r := range res[offset : offset+n] {
+			v := zigzag(r)
+			bw.writeUnary(v >> k)
+			bw.writeBits(v&(1<<k-1), k)
+		}
+		offset += n
+	}
+}
+
+// zigzag 将有符号残差映射为无符号数：0, -1, 1, -2 ... 映射为 0, 1, 2, 3 ...
+func zigzag(r int32) uint64 {
+	return uint64(uint32(r<<1) ^ uint32(r>>31))
+}
+
+// abs32 返回绝对值
+func abs32(v int32) int64 {
+	if v < 0 {
+		return -int64(v)
+	}
+	return int64(v)
+}
+
+// bitWriter 按从高位到低位的顺序写出比特
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+// writeBits 写出 v 的低 n 位，n 不超过 32
+func (b *bitWriter) writeBits(v uint64, n uint) {
+	for n > 32 {
+		n -= 32
+		b.writeBits(v>>n, 32)
+	}
+	b.acc = b.acc<<n | v&(1<<n-1)
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.nbits -= 8
+		b.buf = append(b.buf, byte(b.acc>>b.nbits))
+	}
+}
+
+// writeUnary 写出 q 个 0 和一个 1
+func (b *bitWriter) writeUnary(q uint64) {
+	for q >= 32 {
+		b.writeBits(0, 32)
+		q -= 32
+	}
+	b.writeBits(1, uint(q)+1)
+}
+
+// align 以 0 补齐到字节边界
+func (b *bitWriter) align() {
+	if b.nbits > 0 {
+		b.writeBits(0, 8-b.nbits)
+	}
+}
+
+// crc8 帧头校验，多项式 x^8 + x^2 + x + 1
+func crc8(data []byte) byte {
+	var crc byte
+	for _, d := range data {
+		crc ^= d
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 整帧校验，多项式 x^16 + x^15 + x^2 + 1
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, d := range data {
+		crc ^= uint16(d) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
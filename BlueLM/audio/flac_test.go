@@ -0,0 +1,286 @@
+package audio
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestEncodeFLACRoundTrip(t *testing.T) {
+	sine := func(frames, channels int) []int16 {
+		samples := make([]int16, frames*channels)
+		for i := range frames {
+			for ch := range channels {
+				samples[i*channels+ch] = int16(8000 * math.Sin(float64(i*(ch+1))*0.01))
+			}
+		}
+		return samples
+	}
+	noise := make([]int16, 5000)
+	rng := rand.New(rand.NewSource(1))
+	for i := range noise {
+		noise[i] = int16(rng.Intn(1 << 16))
+	}
+	extremes := make([]int16, 4096)
+	for i := range extremes {
+		extremes[i] = math.MaxInt16
+		if i%2 == 1 {
+			extremes[i] = math.MinInt16
+		}
+	}
+
+	tests := []struct {
+		name    string
+		samples []int16
+		spec    Spec
+	}{
+		{"silence", make([]int16, 10000), Spec{SampleRate: 24000, Channels: 1, BitsPerSample: 16}},
+		{"sine mono, several blocks", sine(10000, 1), Spec{SampleRate: 24000, Channels: 1, BitsPerSample: 16}},
+		{"sine stereo", sine(5000, 2), Spec{SampleRate: 44100, Channels: 2, BitsPerSample: 16}},
+		{"noise", noise, Spec{SampleRate: 16000, Channels: 1, BitsPerSample: 16}},
+		{"full scale", extremes, Spec{SampleRate: 48000, Channels: 1, BitsPerSample: 16}},
+		{"uncoded sample rate", sine(3000, 1), Spec{SampleRate: 11025, Channels: 1, BitsPerSample: 16}},
+		{"single sample", []int16{-123}, Spec{SampleRate: 8000, Channels: 1, BitsPerSample: 16}},
+		{"empty", nil, Spec{SampleRate: 24000, Channels: 1, BitsPerSample: 16}},
+		{"incomplete frame dropped", sine(100, 2)[:199], Spec{SampleRate: 24000, Channels: 2, BitsPerSample: 16}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeFLAC(&buf, tt.samples, tt.spec); err != nil {
+				t.Fatalf("EncodeFLAC() error = %v", err)
+			}
+			got, spec, sum, err := decodeFLAC(buf.Bytes())
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			want := tt.samples[:len(tt.samples)/tt.spec.Channels*tt.spec.Channels]
+			if spec != tt.spec {
+				t.Errorf("decoded spec = %+v, want %+v", spec, tt.spec)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("decoded %d samples differ from the %d encoded samples", len(got), len(want))
+			}
+			if sum != md5.Sum(Bytes(want)) {
+				t.Errorf("STREAMINFO MD5 does not match the encoded samples")
+			}
+		})
+	}
+}
+
+func TestEncodeFLACInvalidSpec(t *testing.T) {
+	tests := []Spec{
+		{SampleRate: 24000, Channels: 1, BitsPerSample: 8},
+		{SampleRate: 24000, Channels: 0, BitsPerSample: 16},
+		{SampleRate: 24000, Channels: 9, BitsPerSample: 16},
+		{SampleRate: 0, Channels: 1, BitsPerSample: 16},
+		{SampleRate: 1 << 20, Channels: 1, BitsPerSample: 16},
+	}
+	for _, spec := range tests {
+		if err := EncodeFLAC(&bytes.Buffer{}, []int16{1, 2}, spec); err == nil {
+			t.Errorf("EncodeFLAC(%+v) returned no error", spec)
+		}
+	}
+}
+
+func TestCRC(t *testing.T) {
+	// FLAC 规范中的校验值
+	if got := crc8([]byte("123456789")); got != 0xF4 {
+		t.Errorf("crc8 = %#x, want 0xf4", got)
+	}
+	if got := crc16([]byte("123456789")); got != 0xFEE8 {
+		t.Errorf("crc16 = %#x, want 0xfee8", got)
+	}
+}
+
+// bitReader 按从高位到低位的顺序读取比特
+type bitReader struct {
+	data []byte
+	pos  int // 比特位置
+}
+
+func (r *bitReader) read(n int) (uint64, error) {
+	var v uint64
+	for range n {
+		if r.pos >= len(r.data)*8 {
+			return 0, fmt.Errorf("unexpected end of stream")
+		}
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+func (r *bitReader) signed(n int) (int64, error) {
+	v, err := r.read(n)
+	return int64(v<<(64-n)) >> (64 - n), err
+}
+
+func (r *bitReader) unary() (uint64, error) {
+	var q uint64
+	for {
+		bit, err := r.read(1)
+		if err != nil || bit == 1 {
+			return q, err
+		}
+		q++
+	}
+}
+
+// decodeFLAC 解码 EncodeFLAC 的输出，只支持编码器使用的子帧类型，并校验两种 CRC
+func decodeFLAC(data []byte) ([]int16, Spec, [16]byte, error) {
+	var spec Spec
+	var sum [16]byte
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return nil, spec, sum, fmt.Errorf("missing fLaC marker")
+	}
+	r := &bitReader{data: data, pos: 32}
+	last, _ := r.read(1)
+	blockType, _ := r.read(7)
+	length, _ := r.read(24)
+	if last != 1 || blockType != 0 || length != 34 {
+		return nil, spec, sum, fmt.Errorf("unexpected metadata block header %d/%d/%d", last, blockType, length)
+	}
+	r.read(16 + 16 + 24 + 24)
+	rate, _ := r.read(20)
+	channels, _ := r.read(3)
+	bps, _ := r.read(5)
+	total, err := r.read(36)
+	if err != nil {
+		return nil, spec, sum, err
+	}
+	copy(sum[:], data[r.pos/8:r.pos/8+16])
+	r.pos += 128
+	spec = Spec{SampleRate: int(rate), Channels: int(channels) + 1, BitsPerSample: int(bps) + 1}
+
+	samples := make([]int16, 0, int(total)*spec.Channels)
+	for frame := uint64(0); uint64(len(samples)/spec.Channels) < total; frame++ {
+		start := r.pos / 8
+		if sync, _ := r.read(14); sync != 0b11111111111110 {
+			return nil, spec, sum, fmt.Errorf("frame %d: bad sync code", frame)
+		}
+		r.read(1 + 1 + 4 + 4 + 4 + 3 + 1)
+		first, _ := r.read(8)
+		number := first
+		if first >= 0x80 {
+			n := 0
+			for first&(0x80>>n) != 0 {
+				n++
+			}
+			number = first & (0xFF >> (n + 1))
+			for range n - 1 {
+				b, _ := r.read(8)
+				number = number<<6 | b&0x3F
+			}
+		}
+		if number != frame {
+			return nil, spec, sum, fmt.Errorf("frame number %d, want %d", number, frame)
+		}
+		blockSize, _ := r.read(16)
+		blockSize++
+		headerCRC, err := r.read(8)
+		if err != nil {
+			return nil, spec, sum, err
+		}
+		if byte(headerCRC) != crc8(data[start:r.pos/8-1]) {
+			return nil, spec, sum, fmt.Errorf("frame %d: header CRC mismatch", frame)
+		}
+
+		channelSamples := make([][]int64, spec.Channels)
+		for ch := range channelSamples {
+			if channelSamples[ch], err = decodeSubframe(r, int(blockSize), spec.BitsPerSample); err != nil {
+				return nil, spec, sum, fmt.Errorf("frame %d channel %d: %v", frame, ch, err)
+			}
+		}
+		if r.pos%8 != 0 {
+			r.read(8 - r.pos%8)
+		}
+		frameCRC, err := r.read(16)
+		if err != nil {
+			return nil, spec, sum, err
+		}
+		if uint16(frameCRC) != crc16(data[start:r.pos/8-2]) {
+			return nil, spec, sum, fmt.Errorf("frame %d: frame CRC mismatch", frame)
+		}
+		for i := range int(blockSize) {
+			for ch := range spec.Channels {
+				samples = append(samples, int16(channelSamples[ch][i]))
+			}
+		}
+	}
+	if r.pos/8 != len(data) {
+		return nil, spec, sum, fmt.Errorf("%d trailing bytes", len(data)-r.pos/8)
+	}
+	return samples, spec, sum, nil
+}
+
+func decodeSubframe(r *bitReader, blockSize, bps int) ([]int64, error) {
+	header, err := r.read(8)
+	if err != nil {
+		return nil, err
+	}
+	x := make([]int64, blockSize)
+	switch kind := header >> 1; {
+	case kind == 0: // CONSTANT
+		v, err := r.signed(bps)
+		for i := range x {
+			x[i] = v
+		}
+		return x, err
+	case kind == 1: // VERBATIM
+		for i := range x {
+			if x[i], err = r.signed(bps); err != nil {
+				return nil, err
+			}
+		}
+		return x, nil
+	case kind >= 8 && kind <= 12: // FIXED
+		order := int(kind - 8)
+		for i := range order {
+			if x[i], err = r.signed(bps); err != nil {
+				return nil, err
+			}
+		}
+		if method, _ := r.read(2); method != 0 {
+			return nil, fmt.Errorf("unexpected residual coding method %d", method)
+		}
+		partitionOrder, _ := r.read(4)
+		n := order
+		for p := range 1 << partitionOrder {
+			k, err := r.read(4)
+			if err != nil {
+				return nil, err
+			}
+			count := blockSize >> partitionOrder
+			if p == 0 {
+				count -= order
+			}
+			for range count {
+				q, err := r.unary()
+				if err != nil {
+					return nil, err
+				}
+				low, err := r.read(int(k))
+				if err != nil {
+					return nil, err
+				}
+				u := q<<k | low
+				x[n] = int64(u>>1) ^ -int64(u&1)
+				n++
+			}
+		}
+		coefficients := [][]int64{{}, {1}, {2, -1}, {3, -3, 1}, {4, -6, 4, -1}}[order]
+		for i := order; i < blockSize; i++ {
+			for j, c := range coefficients {
+				x[i] += c * x[i-1-j]
+			}
+		}
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected subframe header %#x", header)
+}
@@ -0,0 +1,71 @@
+package audio
+
+import "math"
+
+// Resample 将交错排列的 16 位采样从 from 转换为 to 采样率
+// 采用线性插值；降采样时先按采样率之比做滑动平均低通，减轻混叠
+func Resample(samples []int16, channels, from, to int) []int16 {
+	if from == to || from <= 0 || to <= 0 || channels <= 0 || len(samples) < channels {
+		return samples
+	}
+	frames := len(samples) / channels
+
+	input := make([][]float64, channels)
+	for ch := range input {
+		input[ch] = make([]float64, frames)
+		for i := range frames {
+			input[ch][i] = float64(samples[i*channels+ch])
+		}
+		if to < from {
+			input[ch] = movingAverage(input[ch], (from+to-1)/to)
+		}
+	}
+
+	outFrames := int(int64(frames) * int64(to) / int64(from))
+	out := make([]int16, outFrames*channels)
+	step := float64(from) / float64(to)
+	for i := range outFrames {
+		pos := float64(i) * step
+		j := int(pos)
+		frac := pos - float64(j)
+		for ch := range channels {
+			v := input[ch][j]
+			if j+1 < frames {
+				v += (input[ch][j+1] - v) * frac
+			}
+			out[i*channels+ch] = clampInt16(v)
+		}
+	}
+	return out
+}
+
+// movingAverage 宽度为 width 的居中滑动平均，边缘按实际覆盖的采样数平均
+func movingAverage(x []float64, width int) []float64 {
+	if width <= 1 {
+		return x
+	}
+	prefix := make([]float64, len(x)+1)
+	for i, v := range x {
+		prefix[i+1] = prefix[i] + v
+	}
+	out := make([]float64, len(x))
+	half := width / 2
+	for i := range x {
+		lo := max(i-half, 0)
+		hi := min(i-half+width, len(x))
+		out[i] = (prefix[hi] - prefix[lo]) / float64(hi-lo)
+	}
+	return out
+}
+
+// clampInt16 四舍五入并限制在 16 位范围内
+func clampInt16(v float64) int16 {
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
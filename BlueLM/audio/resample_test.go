@@ -0,0 +1,49 @@
+package audio
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResample(t *testing.T) {
+	constant := func(n int, values ...int16) []int16 {
+		samples := make([]int16, 0, n*len(values))
+		for range n {
+			samples = append(samples, values...)
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name     string
+		samples  []int16
+		channels int
+		from, to int
+		want     []int16
+	}{
+		{"same rate", []int16{1, 2, 3}, 1, 24000, 24000, []int16{1, 2, 3}},
+		{"invalid rate", []int16{1, 2, 3}, 1, 0, 24000, []int16{1, 2, 3}},
+		{"upsample interpolates", []int16{0, 100, 200}, 1, 8000, 16000, []int16{0, 50, 100, 150, 200, 200}},
+		{"downsample keeps constant", constant(30, 1000), 1, 24000, 16000, constant(20, 1000)},
+		{"stereo channels stay separate", constant(30, 100, -100), 2, 24000, 16000, constant(20, 100, -100)},
+		{"stereo upsample", []int16{0, 10, 100, 20}, 2, 8000, 16000, []int16{0, 10, 50, 15, 100, 20, 100, 20}},
+		{"clamped", []int16{32767, 32767}, 1, 8000, 16000, []int16{32767, 32767, 32767, 32767}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resample(tt.samples, tt.channels, tt.from, tt.to)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Resample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResampleLength(t *testing.T) {
+	samples := make([]int16, 24000)
+	for _, to := range []int{8000, 16000, 22050, 44100, 48000} {
+		if got := len(Resample(samples, 1, 24000, to)); got != to {
+			t.Errorf("resampling one second to %d Hz gave %d samples", to, got)
+		}
+	}
+}
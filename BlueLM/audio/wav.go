@@ -0,0 +1,111 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// wavHeaderSize RIFF 头、fmt 子块和 data 子块头的总字节数
+const wavHeaderSize = 44
+
+// WAVWriter 以流的方式写出 PCM 格式的 WAV，不需要先写临时文件
+type WAVWriter struct {
+	w        io.Writer
+	dataSize int64 // 头部声明的数据长度，-1 表示未知
+	written  int64
+}
+
+// NewWAVWriter 立即写出文件头，之后通过 Write 写入 PCM 数据
+// dataSize 为 PCM 数据的字节数；未知时传 -1，长度字段写为 0xFFFFFFFF（流式播放器会读到数据结束），
+// 若 w 实现了 io.WriteSeeker，Close 时回填实际长度
+func NewWAVWriter(w io.Writer, spec Spec, dataSize int64) (*WAVWriter, error) {
+	if spec.BitsPerSample%8 != 0 {
+		return nil, fmt.Errorf("bits %% 8 must == 0. now bits: %d", spec.BitsPerSample)
+	}
+	if dataSize > math.MaxUint32-36 {
+		return nil, fmt.Errorf("WAV data too large: %d bytes", dataSize)
+	}
+
+	// 计算参数
+	sampleWidth := spec.BitsPerSample / 8
+	byteRate := spec.SampleRate * spec.Channels * sampleWidth
+	blockAlign := spec.Channels * sampleWidth
+	riffSize, chunkSize := uint32(math.MaxUint32), uint32(math.MaxUint32)
+	if dataSize >= 0 {
+		riffSize, chunkSize = uint32(36+dataSize), uint32(dataSize)
+	}
+
+	header := make([]byte, wavHeaderSize)
+	// RIFF头 (12字节)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], riffSize)
+	copy(header[8:], "WAVE")
+	// fmt子块 (24字节)
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)                         // fmt子块大小
+	binary.LittleEndian.PutUint16(header[20:], 1)                          // 音频格式(PCM)
+	binary.LittleEndian.PutUint16(header[22:], uint16(spec.Channels))      // 声道数
+	binary.LittleEndian.PutUint32(header[24:], uint32(spec.SampleRate))    // 采样率
+	binary.LittleEndian.PutUint32(header[28:], uint32(byteRate))           // 字节率
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))         // 块对齐
+	binary.LittleEndian.PutUint16(header[34:], uint16(spec.BitsPerSample)) // 位深度
+	// data子块头 (8字节)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], chunkSize)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &WAVWriter{w: w, dataSize: dataSize}, nil
+}
+
+// Write 写入 PCM 数据
+func (w *WAVWriter) Write(p []byte) (int, error) {
+	if w.dataSize >= 0 && w.written+int64(len(p)) > w.dataSize {
+		return 0, fmt.Errorf("WAV data exceeds declared size %d", w.dataSize)
+	}
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Close 检查写入的数据长度与头部一致；长度未知且可以 Seek 时回填头部的长度字段
+// 不会关闭底层的 io.Writer
+func (w *WAVWriter) Close() error {
+	if w.dataSize >= 0 {
+		if w.written != w.dataSize {
+			return fmt.Errorf("WAV data size mismatch: declared %d, written %d", w.dataSize, w.written)
+		}
+		return nil
+	}
+
+	seeker, ok := w.w.(io.WriteSeeker)
+	if !ok || w.written > math.MaxUint32-36 {
+		return nil
+	}
+	end, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	start := end - w.written - wavHeaderSize
+	size := make([]byte, 4)
+	for _, field := range []struct {
+		offset int64
+		value  uint32
+	}{
+		{4, uint32(36 + w.written)},
+		{40, uint32(w.written)},
+	} {
+		binary.LittleEndian.PutUint32(size, field.value)
+		if _, err := seeker.Seek(start+field.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := seeker.Write(size); err != nil {
+			return err
+		}
+	}
+	_, err = seeker.Seek(end, io.SeekStart)
+	return err
+}
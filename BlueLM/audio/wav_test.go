@@ -0,0 +1,112 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewWAVWriter(t *testing.T) {
+	spec := Spec{SampleRate: 24000, Channels: 2, BitsPerSample: 16}
+	tests := []struct {
+		name      string
+		dataSize  int64
+		write     int
+		riffSize  uint32
+		chunkSize uint32
+		closeErr  bool
+	}{
+		{"known size", 8, 8, 44, 8, false},
+		{"empty", 0, 0, 36, 0, false},
+		{"short write", 8, 4, 44, 8, true},
+		{"unknown size", -1, 6, 0xFFFFFFFF, 0xFFFFFFFF, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWAVWriter(&buf, spec, tt.dataSize)
+			if err != nil {
+				t.Fatalf("NewWAVWriter() error = %v", err)
+			}
+			if _, err := w.Write(make([]byte, tt.write)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); (err != nil) != tt.closeErr {
+				t.Fatalf("Close() error = %v, want error %v", err, tt.closeErr)
+			}
+
+			header := buf.Bytes()
+			if len(header) != wavHeaderSize+tt.write {
+				t.Fatalf("output is %d bytes, want %d", len(header), wavHeaderSize+tt.write)
+			}
+			if got := binary.LittleEndian.Uint32(header[4:]); got != tt.riffSize {
+				t.Errorf("RIFF size = %d, want %d", got, tt.riffSize)
+			}
+			if got := binary.LittleEndian.Uint32(header[40:]); got != tt.chunkSize {
+				t.Errorf("data size = %d, want %d", got, tt.chunkSize)
+			}
+			if got := binary.LittleEndian.Uint32(header[28:]); got != 24000*2*2 {
+				t.Errorf("byte rate = %d, want %d", got, 24000*2*2)
+			}
+			if got := binary.LittleEndian.Uint16(header[32:]); got != 4 {
+				t.Errorf("block align = %d, want 4", got)
+			}
+		})
+	}
+}
+
+func TestWAVWriterRejectsExtraData(t *testing.T) {
+	w, err := NewWAVWriter(io.Discard, Spec{SampleRate: 16000, Channels: 1, BitsPerSample: 16}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(make([]byte, 6)); err == nil {
+		t.Error("Write() beyond the declared size returned no error")
+	}
+	if _, err := NewWAVWriter(io.Discard, Spec{SampleRate: 16000, Channels: 1, BitsPerSample: 12}, 4); err == nil {
+		t.Error("NewWAVWriter() with 12-bit samples returned no error")
+	}
+}
+
+func TestWAVWriterPatchesSizeOnSeeker(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// 文件开头已有其他内容时，回填的位置相对于 WAV 头计算
+	if _, err := f.Write([]byte("prefix")); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWAVWriter(f, Spec{SampleRate: 16000, Channels: 1, BitsPerSample: 16}, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := w.Write(make([]byte, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := data[len("prefix"):]
+	if got := binary.LittleEndian.Uint32(header[4:]); got != 36+30 {
+		t.Errorf("RIFF size = %d, want %d", got, 36+30)
+	}
+	if got := binary.LittleEndian.Uint32(header[40:]); got != 30 {
+		t.Errorf("data size = %d, want 30", got)
+	}
+	if len(header) != wavHeaderSize+30 {
+		t.Errorf("file has %d bytes after the prefix, want %d", len(header), wavHeaderSize+30)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,17 +14,144 @@ type Config struct {
 	Server struct {
 		Port string `yaml:"port"`
 	} `yaml:"server"`
+	AI struct {
+		Provider string `yaml:"provider"` // AI 能力提供方：vivo 或 mock（离线模拟，无需凭据）
+	} `yaml:"ai"`
 	VivoAI struct {
-		AppID  string `yaml:"app_id"`
-		AppKey string `yaml:"app_key"`
+		AppID    string                 `yaml:"app_id"`
+		AppKey   string                 `yaml:"app_key"`
+		Profiles map[string]VivoProfile `yaml:"profiles"` // 命名凭据档案，请求中以 profile 字段选择
+
+		ClientCacheSize int           `yaml:"client_cache_size"` // 按凭据缓存的 vivo 客户端数量上限
+		ClientIdleTTL   time.Duration `yaml:"client_idle_ttl"`   // 客户端闲置超过该时长后移出缓存
 	} `yaml:"vivo_ai"`
 	FilePaths struct {
 		UploadDir   string `yaml:"upload_dir"`
 		DownloadDir string `yaml:"download_dir"`
+		DataDir     string `yaml:"data_dir"` // 嵌入式数据库等持久化数据目录
 	} `yaml:"file_paths"`
 	WhisperX struct {
 		URL string `yaml:"url"`
 	} `yaml:"whisperx"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Webhook     WebhookConfig     `yaml:"webhook"`
+	Subtitle    SubtitleConfig    `yaml:"subtitle"`
+	Translation TranslationConfig `yaml:"translation"`
+	Evaluation  EvaluationConfig  `yaml:"evaluation"`
+	TTS         TTSConfig         `yaml:"tts"`
+}
+
+// TTSConfig 长文本语音合成：按句切分、并发合成后拼接为一个音频
+type TTSConfig struct {
+	MaxTextLength   int           `yaml:"max_text_length"`   // 单次请求的最大字数
+	MaxSegmentChars int           `yaml:"max_segment_chars"` // 每次调用合成的最大字数，超出引擎上限时以引擎上限为准，0表示按引擎上限
+	SentenceSilence time.Duration `yaml:"sentence_silence"`  // 拼接时句与句之间插入的静音时长，0表示不插入
+	Concurrency     int           `yaml:"concurrency"`       // 同时合成的句子数
+	AsyncSegments   int           `yaml:"async_segments"`    // 切分后超过该句数时以异步任务处理
+
+	Cache TTSCacheConfig `yaml:"cache"`
+}
+
+// TTSCacheConfig 合成音频缓存，按模式、音色、文本和输出格式的哈希保存音频文件
+type TTSCacheConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Dir       string `yaml:"dir"`         // 缓存目录，默认为 data_dir 下的 tts_cache
+	MaxSizeMB int64  `yaml:"max_size_mb"` // 缓存目录的容量上限，超出时淘汰最久未使用的音频
+}
+
+// EvaluationConfig 翻译评估的模型输出校验和评分标准
+type EvaluationConfig struct {
+	MaxAttempts   int                     `yaml:"max_attempts"`   // 模型输出未通过 JSON 校验时带上错误重新生成，总尝试次数上限（含首次）
+	DefaultRubric string                  `yaml:"default_rubric"` // 请求未指定 rubric 时使用的评分标准，留空为内置的 standard
+	Rubrics       map[string]RubricConfig `yaml:"rubrics"`        // 命名评分标准，请求中以 rubric 字段选择
+
+	HistoryMaxRecords int `yaml:"history_max_records"` // 请求带 learner_id 时保存评估记录，每个学习者保留的条数
+
+	BatchMaxItems    int `yaml:"batch_max_items"`   // 批量评估单次请求的最大条数
+	BatchConcurrency int `yaml:"batch_concurrency"` // 批量评估同时进行的条目数
+}
+
+// RubricConfig 评分标准：评估维度及权重、相似度占比、评级阈值和附加的评分要求
+// 也可以通过 /translate/rubrics 接口创建，因此同时带有 JSON 标签
+type RubricConfig struct {
+	Description      string            `yaml:"description" json:"description,omitempty"`
+	SimilarityWeight float64           `yaml:"similarity_weight" json:"similarity_weight"` // 与标准答案的相似度在综合评分中的占比（0-1），其余为各维度的加权平均，0表示只看 AI 评分
+	Dimensions       []RubricDimension `yaml:"dimensions" json:"dimensions"`
+	Levels           []RubricLevel     `yaml:"levels" json:"levels"`               // 评级，综合得分不低于 min_score 的最高一档，低于所有阈值时为最低一档
+	ImproveBelow     float64           `yaml:"improve_below" json:"improve_below"` // 维度得分低于该值时给出该维度的改进建议
+	StrengthAt       float64           `yaml:"strength_at" json:"strength_at"`     // 维度得分不低于该值时列为优点
+	Prompt           string            `yaml:"prompt" json:"prompt,omitempty"`     // 附加到评估提示词中的评分要求
+}
+
+// RubricDimension 评估维度，模型按 key 输出该维度的 0-100 分
+type RubricDimension struct {
+	Key      string  `yaml:"key" json:"key"`
+	Label    string  `yaml:"label" json:"label"`                 // 维度名称，写入提示词
+	Weight   float64 `yaml:"weight" json:"weight"`               // 相对权重，按所有维度的权重之和归一化
+	Criteria string  `yaml:"criteria" json:"criteria,omitempty"` // 评分说明，写入提示词
+	Advice   string  `yaml:"advice" json:"advice,omitempty"`     // 得分偏低时的改进建议
+	Strength string  `yaml:"strength" json:"strength,omitempty"` // 得分较高时的优点描述
+}
+
+// RubricLevel 一档评级
+type RubricLevel struct {
+	Label    string  `yaml:"label" json:"label"`
+	MinScore float64 `yaml:"min_score" json:"min_score"`
+}
+
+// TranslationConfig 翻译后端选择，可选 vivo、llm（蓝心大模型）、dictionary（离线词典）
+type TranslationConfig struct {
+	Backend  string   `yaml:"backend"`  // 主后端
+	Fallback []string `yaml:"fallback"` // 主后端失败时依次尝试的后端
+
+	BatchMaxItems    int `yaml:"batch_max_items"`    // 批量翻译单次请求的最大条数
+	BatchConcurrency int `yaml:"batch_concurrency"`  // 批量翻译同时进行的上游请求数
+	BatchMaxAttempts int `yaml:"batch_max_attempts"` // 批量翻译中每条文本的最大尝试次数（含首次）
+
+	Cache TranslationCacheConfig `yaml:"cache"`
+}
+
+// TranslationCacheConfig 翻译结果缓存，内存 LRU 之外可选择持久化到嵌入式数据库
+type TranslationCacheConfig struct {
+	Enabled              bool          `yaml:"enabled"`
+	MaxEntries           int           `yaml:"max_entries"`            // 内存中保留的条数
+	TTL                  time.Duration `yaml:"ttl"`                    // 缓存有效期，0表示不过期
+	Persistent           bool          `yaml:"persistent"`             // 同时写入嵌入式数据库，重启后仍然有效
+	MaxPersistentEntries int           `yaml:"max_persistent_entries"` // 数据库中保留的条数，超出时淘汰最旧的条目
+}
+
+// VivoProfile 一组命名的 vivo AI 凭据
+type VivoProfile struct {
+	AppID  string `yaml:"app_id"`
+	AppKey string `yaml:"app_key"`
+}
+
+// SubtitleConfig 字幕导出时的长句切分限制，可被下载请求的参数覆盖
+type SubtitleConfig struct {
+	MaxLineLength int           `yaml:"max_line_length"` // 每条字幕的最大字符数，0表示不限制
+	MaxDuration   time.Duration `yaml:"max_duration"`    // 每条字幕的最长显示时间，0表示不限制
+}
+
+// WebhookConfig 异步任务完成回调的投递策略
+type WebhookConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`    // 最大投递次数（含首次）
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // 首次重试前的等待时间，之后按指数增长
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // 重试等待时间上限
+	Timeout        time.Duration `yaml:"timeout"`         // 单次请求超时
+}
+
+// RetentionConfig 上传和下载目录的清理策略，保留时长为0表示永久保留该类文件
+type RetentionConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Interval      time.Duration `yaml:"interval"`      // 清理周期
+	Uploads       time.Duration `yaml:"uploads"`       // 上传的原始文件
+	TTSTemp       time.Duration `yaml:"tts_temp"`      // TTS 生成的 temp_*.wav
+	Transcription time.Duration `yaml:"transcription"` // 蓝心转录结果 transcription_<id>.json
+	WhisperX      time.Duration `yaml:"whisperx"`      // WhisperX 结果文件及输出目录
+	Bilingual     time.Duration `yaml:"bilingual"`     // 双语字幕结果 bilingual_<id>.json
+	Evaluation    time.Duration `yaml:"evaluation"`    // 批量评估报告 evaluation_<id>.json
+	TTS           time.Duration `yaml:"tts"`           // 长文本语音合成结果 tts_<id>.wav
+	MaxDiskMB     int64         `yaml:"max_disk_mb"`   // 上传和下载目录的总容量上限，0表示不限制
 }
 
 // LoadConfig 从指定的路径加载和解析YAML配置文件
@@ -40,18 +168,82 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	// 优先使用环境变量
-	if appID := os.Getenv("APPID"); appID != "" {
-		config.VivoAI.AppID = appID
+	if config.AI.Provider == "" {
+		config.AI.Provider = "vivo"
 	}
-	if appKey := os.Getenv("APPKEY"); appKey != "" {
-		config.VivoAI.AppKey = appKey
+	if config.VivoAI.ClientCacheSize <= 0 {
+		config.VivoAI.ClientCacheSize = 64
 	}
-
-	// 验证必要的配置
-	if config.VivoAI.AppID == "" || config.VivoAI.AppKey == "" {
-		return nil, fmt.Errorf("APPID and APPKEY must be provided via environment variables or config file")
+	if config.VivoAI.ClientIdleTTL <= 0 {
+		config.VivoAI.ClientIdleTTL = 30 * time.Minute
+	}
+	if config.Translation.Backend == "" {
+		config.Translation.Backend = "vivo"
+	}
+	if config.Translation.BatchMaxItems <= 0 {
+		config.Translation.BatchMaxItems = 500
+	}
+	if config.Translation.BatchConcurrency <= 0 {
+		config.Translation.BatchConcurrency = 4
+	}
+	if config.Translation.BatchMaxAttempts <= 0 {
+		config.Translation.BatchMaxAttempts = 3
+	}
+	if config.Evaluation.MaxAttempts <= 0 {
+		config.Evaluation.MaxAttempts = 3
+	}
+	if config.Evaluation.HistoryMaxRecords <= 0 {
+		config.Evaluation.HistoryMaxRecords = 5000
+	}
+	if config.Evaluation.BatchMaxItems <= 0 {
+		config.Evaluation.BatchMaxItems = 200
+	}
+	if config.Evaluation.BatchConcurrency <= 0 {
+		config.Evaluation.BatchConcurrency = 4
+	}
+	if config.TTS.MaxTextLength <= 0 {
+		config.TTS.MaxTextLength = 20000
+	}
+	if config.TTS.Concurrency <= 0 {
+		config.TTS.Concurrency = 3
+	}
+	if config.TTS.AsyncSegments <= 0 {
+		config.TTS.AsyncSegments = 10
+	}
+	if config.TTS.Cache.MaxSizeMB <= 0 {
+		config.TTS.Cache.MaxSizeMB = 512
+	}
+	if config.Translation.Cache.MaxEntries <= 0 {
+		config.Translation.Cache.MaxEntries = 1000
+	}
+	if config.Translation.Cache.MaxPersistentEntries <= 0 {
+		config.Translation.Cache.MaxPersistentEntries = 20000
+	}
+	for _, backend := range append([]string{config.Translation.Backend}, config.Translation.Fallback...) {
+		switch backend {
+		case "vivo", "llm", "dictionary":
+		default:
+			return nil, fmt.Errorf("unsupported translation backend %q (supported: vivo, llm, dictionary)", backend)
+		}
+	}
+	if config.FilePaths.DataDir == "" {
+		config.FilePaths.DataDir = "../file_io/data/"
+	}
+	if config.Retention.Interval <= 0 {
+		config.Retention.Interval = time.Hour
+	}
+	if config.Webhook.MaxAttempts <= 0 {
+		config.Webhook.MaxAttempts = 5
+	}
+	if config.Webhook.InitialBackoff <= 0 {
+		config.Webhook.InitialBackoff = 2 * time.Second
+	}
+	if config.Webhook.MaxBackoff <= 0 {
+		config.Webhook.MaxBackoff = 5 * time.Minute
+	}
+	if config.Webhook.Timeout <= 0 {
+		config.Webhook.Timeout = 10 * time.Second
 	}
 
 	return config, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/subtitle"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// BilingualRequest 创建双语字幕任务的请求
+type BilingualRequest struct {
+	TaskID         string `json:"task_id" binding:"required"` // 已完成的蓝心或 WhisperX 转录任务
+	From           string `json:"from,omitempty"`             // 源语言，默认 auto
+	To             string `json:"to" binding:"required"`
+	Backend        string `json:"backend,omitempty"` // 指定主翻译后端，默认使用配置
+	AppID          string `json:"app_id,omitempty"`
+	AppKey         string `json:"app_key,omitempty"`
+	Profile        string `json:"profile,omitempty"` // 使用 vivo_ai.profiles 中的凭据档案
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
+}
+
+// BilingualSegment 双语结果中的一句，时间轴与源转录一致
+type BilingualSegment struct {
+	Index       int    `json:"index"`
+	StartMs     int64  `json:"start_ms"`
+	EndMs       int64  `json:"end_ms"`
+	Speaker     string `json:"speaker,omitempty"`
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+	Backend     string `json:"backend,omitempty"` // 实际产生译文的后端
+	Error       string `json:"error,omitempty"`   // 翻译失败的原因，此时 translation 为空
+}
+
+// BilingualResult 双语结果文件 bilingual_<id>.json 的内容
+type BilingualResult struct {
+	TaskID       string             `json:"task_id"`
+	SourceTaskID string             `json:"source_task_id"`
+	SourceEngine string             `json:"source_engine"`
+	From         string             `json:"from"`
+	To           string             `json:"to"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Total        int                `json:"total"`
+	Failed       int                `json:"failed"`
+	Segments     []BilingualSegment `json:"segments"`
+}
+
+// bilingualResultFileName 返回双语结果文件名
+func bilingualResultFileName(taskID string) string {
+	return "bilingual_" + taskID + ".json"
+}
+
+// BilingualHandler 为已完成的转录任务创建双语字幕任务
+// 逐句翻译源转录结果，完成后可通过 /bluelm/bilingual/download/:task_id 下载 JSON 或双行 SRT/VTT
+func BilingualHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BilingualRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid request format: "+err.Error())
+			return
+		}
+		if req.From == "" {
+			req.From = TRANSLATE_LANGUAGE_AUTO
+		}
+		if _, ok := supportedLanguages[req.From]; !ok {
+			utils.AbortWithBadRequest(c, nil, "Unsupported source language: "+req.From)
+			return
+		}
+		if _, ok := supportedLanguages[req.To]; !ok || req.To == TRANSLATE_LANGUAGE_AUTO {
+			utils.AbortWithBadRequest(c, nil, "Unsupported target language: "+req.To)
+			return
+		}
+		callback, err := newCallbackTarget(req.CallbackURL, req.CallbackSecret)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid callback parameters")
+			return
+		}
+
+		source, exists := GlobalTaskManager.GetTask(req.TaskID)
+		if !exists || taskEngine(source) == TaskEngineBilingual {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Source task not found",
+				"task_id": req.TaskID,
+			})
+			return
+		}
+		if source.Status != TaskStatusCompleted {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Source task is not completed yet",
+				"status":  source.Status,
+				"task_id": req.TaskID,
+			})
+			return
+		}
+
+		segments, status, err := loadSourceSegments(cfg, source)
+		if err != nil {
+			c.JSON(status, gin.H{
+				"error":   err.Error(),
+				"task_id": req.TaskID,
+			})
+			return
+		}
+		if len(segments) == 0 {
+			utils.AbortWithBadRequest(c, nil, "Source task has no segments to translate")
+			return
+		}
+
+		provider := newLazyProvider(resolver, ai.Credentials{AppID: req.AppID, AppKey: req.AppKey, Profile: req.Profile})
+		chain, err := translatorChain(provider, cfg, req.Backend)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+
+		taskID := ai.NewSessionID()
+		GlobalTaskManager.CreateTask(taskID, source.Filename, TaskEngineBilingual)
+		GlobalTaskManager.UpdateTask(taskID, func(task *TaskInfo) {
+			task.Status = TaskStatusProcessing
+			task.Message = fmt.Sprintf("Translating %d segments", len(segments))
+			callback.apply(task)
+		})
+
+		go runBilingualJob(startTaskPoller(taskID), cfg, taskID, source, req, chain, segments)
+
+		c.JSON(http.StatusOK, gin.H{
+			"task_id":        taskID,
+			"source_task_id": req.TaskID,
+			"segments":       len(segments),
+		})
+	}
+}
+
+// loadSourceSegments 读取源转录任务的句子及时间轴
+func loadSourceSegments(cfg *config.Config, source *TaskInfo) ([]subtitle.Segment, int, error) {
+	if taskEngine(source) == TaskEngineWhisperX {
+		return loadWhisperXSegments(cfg, source.TaskID, "transcription")
+	}
+
+	filePath := source.FilePath
+	if filePath == "" {
+		filePath = filepath.Join(cfg.FilePaths.DownloadDir, transcriptionResultFileName(source.TaskID))
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, http.StatusNotFound, fmt.Errorf("result file not found")
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	segments, err := subtitle.ParseBlueLM(data)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return segments, http.StatusOK, nil
+}
+
+// runBilingualJob 逐句翻译并写出双语结果，ctx 被取消时停止
+// 部分句子翻译失败时任务仍然完成，失败原因记录在对应句子的 error 中；全部失败时任务失败
+func runBilingualJob(ctx context.Context, cfg *config.Config, taskID string, source *TaskInfo, req BilingualRequest, chain []Translator, segments []subtitle.Segment) {
+	defer finishTaskPoller(taskID)
+
+	inputs := make([]batchTranslationInput, len(segments))
+	for i, seg := range segments {
+		inputs[i] = batchTranslationInput{text: seg.Text}
+	}
+	results := translateBatch(ctx, chain, req.From, req.To, inputs, cfg.Translation.BatchConcurrency, cfg.Translation.BatchMaxAttempts, func(done int) {
+		updateRunningTask(taskID, func(task *TaskInfo) {
+			task.Progress = done * 100 / len(inputs)
+			task.Message = fmt.Sprintf("Translated %d/%d segments", done, len(inputs))
+		})
+	})
+	if ctx.Err() != nil {
+		utils.Log.Infof("Bilingual task %s stopped: %v", taskID, ctx.Err())
+		return
+	}
+
+	result := BilingualResult{
+		TaskID:       taskID,
+		SourceTaskID: source.TaskID,
+		SourceEngine: taskEngine(source),
+		From:         req.From,
+		To:           req.To,
+		CreatedAt:    time.Now(),
+		Total:        len(segments),
+		Segments:     make([]BilingualSegment, len(segments)),
+	}
+	for i, seg := range segments {
+		result.Segments[i] = BilingualSegment{
+			Index:       i,
+			StartMs:     seg.Start.Milliseconds(),
+			EndMs:       seg.End.Milliseconds(),
+			Speaker:     seg.Speaker,
+			Text:        seg.Text,
+			Translation: results[i].Translation,
+			Backend:     results[i].Backend,
+		}
+		if !results[i].Success {
+			result.Segments[i].Error = results[i].Message
+			result.Failed++
+		}
+	}
+	if result.Failed == result.Total {
+		utils.Log.Errorf("Bilingual task %s failed: no segment was translated", taskID)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, "Translation failed for all segments: "+results[0].Message)
+		return
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		utils.Log.Errorf("Failed to marshal result for bilingual task %s: %v", taskID, err)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error serializing result: %v", err))
+		return
+	}
+	downloadFilePath := filepath.Join(cfg.FilePaths.DownloadDir, bilingualResultFileName(taskID))
+	if err := os.WriteFile(downloadFilePath, jsonData, 0644); err != nil {
+		utils.Log.Errorf("Failed to write result to file for bilingual task %s: %v", taskID, err)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error writing file: %v", err))
+		return
+	}
+
+	message := "Bilingual subtitles completed successfully"
+	if result.Failed > 0 {
+		message = fmt.Sprintf("Bilingual subtitles completed, %d/%d segments failed to translate", result.Failed, result.Total)
+	}
+	GlobalTaskManager.SetTaskFilePath(taskID, downloadFilePath)
+	updateRunningTask(taskID, func(task *TaskInfo) {
+		task.Status = TaskStatusCompleted
+		task.Message = message
+		task.Progress = 100
+	})
+	utils.Log.Infof("Bilingual task %s completed. Result saved to %s", taskID, downloadFilePath)
+}
+
+// BilingualDownloadHandler 下载双语结果
+// format=json（默认）返回结果文件；format=srt/vtt 返回双行字幕，第一行原文、第二行译文
+func BilingualDownloadHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filePath, ok := completedTaskResult(c, cfg, TaskEngineBilingual)
+		if !ok {
+			return
+		}
+
+		formatName := c.DefaultQuery("format", "json")
+		if formatName == "json" {
+			sendResultFile(c, filePath, "application/json")
+			return
+		}
+
+		format, err := subtitle.ParseFormat(formatName)
+		if err != nil || (format != subtitle.FormatSRT && format != subtitle.FormatVTT) {
+			utils.AbortWithBadRequest(c, err, fmt.Sprintf("unsupported bilingual format %q (supported: json, srt, vtt)", formatName))
+			return
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		var result BilingualResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := subtitle.Render(&buf, format, bilingualSubtitles(result.Segments)); err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=bilingual_"+c.Param("task_id")+format.Extension())
+		c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+	}
+}
+
+// bilingualSubtitles 将双语结果转换为双行字幕，翻译失败的句子只保留原文
+// 双行字幕不做长句切分，以免原文和译文错位
+func bilingualSubtitles(segments []BilingualSegment) []subtitle.Segment {
+	subtitles := make([]subtitle.Segment, len(segments))
+	for i, seg := range segments {
+		text := seg.Text
+		if translation := strings.TrimSpace(seg.Translation); translation != "" {
+			text += "\n" + translation
+		}
+		subtitles[i] = subtitle.Segment{
+			Start:   time.Duration(seg.StartMs) * time.Millisecond,
+			End:     time.Duration(seg.EndMs) * time.Millisecond,
+			Text:    text,
+			Speaker: seg.Speaker,
+		}
+	}
+	return subtitles
+}
+
+// BilingualTasksHandler 列出所有双语字幕任务
+func BilingualTasksHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listTasks(c, TaskEngineBilingual)
+	}
+}
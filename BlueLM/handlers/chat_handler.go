@@ -4,23 +4,37 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 )
 
+// chatRequestBody 文本对话请求体
+type chatRequestBody struct {
+	Message         string                `json:"message"`
+	SessionID       string                `json:"session_id,omitempty"`
+	HistoryMessages []ConversationMessage `json:"history_messages,omitempty"` // 为空时使用服务端保存的会话历史
+	AppID           string                `json:"app_id,omitempty"`           // 前端传递的AppID
+	AppKey          string                `json:"app_key,omitempty"`          // 前端传递的AppKey
+	Profile         string                `json:"profile,omitempty"`          // 使用 vivo_ai.profiles 中的凭据档案
+	Stream          bool                  `json:"stream,omitempty"`           // 以 SSE 流式返回
+}
+
 // ChatHandler handles the AI chat requests.
-func ChatHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+// 请求体 stream=true 或查询参数 stream=true 时以 SSE 流式返回
+func ChatHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
+	return chatHandler(resolver, cfg, false)
+}
+
+// ChatStreamHandler 流式对话，始终以 SSE 返回
+func ChatStreamHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
+	return chatHandler(resolver, cfg, true)
+}
+
+func chatHandler(resolver *ai.Resolver, cfg *config.Config, forceStream bool) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// 定义请求体结构
-		var requestBody struct {
-			Message         string             `json:"message"`
-			SessionID       string             `json:"session_id,omitempty"`
-			HistoryMessages []vivo.ChatMessage `json:"history_messages,omitempty"`
-			AppID           string             `json:"app_id,omitempty"`  // 前端传递的AppID
-			AppKey          string             `json:"app_key,omitempty"` // 前端传递的AppKey
-		}
+		var requestBody chatRequestBody
 
 		// 解析JSON请求
 		if err := ctx.ShouldBindJSON(&requestBody); err != nil {
@@ -35,43 +49,54 @@ func ChatHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 创建蓝心大模型应用实例，考虑配置优先级
-		chatApp := createBlueLMApp(requestBody.AppID, requestBody.AppKey, cfg)
-
-		// 生成或使用现有的会话ID
-		sessionID := requestBody.SessionID
-		if sessionID == "" {
-			sessionID = vivo.GenerateSessionID()
+		chatApp, ok := resolveProvider(ctx, resolver, ai.Credentials{
+			AppID:   requestBody.AppID,
+			AppKey:  requestBody.AppKey,
+			Profile: requestBody.Profile,
+		})
+		if !ok {
+			return
 		}
 
-		// 构建消息历史
-		var historyMessages []vivo.ChatMessage
+		// 客户端未提供历史消息时使用服务端保存的会话历史
+		var clientHistory []ConversationMessage
 		if len(requestBody.HistoryMessages) > 0 {
-			// 使用提供的历史消息
-			historyMessages = requestBody.HistoryMessages
+			if errs := validateHistoryMessages(requestBody.HistoryMessages); len(errs) > 0 {
+				utils.AbortWithValidationErrors(ctx, "Invalid history_messages", errs)
+				return
+			}
+			clientHistory = requestBody.HistoryMessages
 		}
-
-		// 添加当前用户消息
-		historyMessages = append(historyMessages, vivo.ChatMessage{
-			Role:    vivo.CHAT_ROLE_USER,
+		session, err := openChatSession(requestBody.SessionID, clientHistory, ConversationMessage{
+			Role:    ai.RoleUser,
 			Content: requestBody.Message,
 		})
+		if err != nil {
+			utils.AbortWithInternalServerError(ctx, err)
+			return
+		}
+
+		if forceStream || requestBody.Stream || ctx.Query("stream") == "true" {
+			streamChat(ctx, chatApp, session)
+			return
+		}
 
 		// 调用蓝心大模型
-		res, err := chatApp.Chat(vivo.GenerateSessionID(), sessionID, historyMessages, nil)
+		res, err := chatApp.Chat(session.ID, session.chatMessages())
 		if err != nil {
 			utils.AbortWithInternalServerError(ctx, err)
 			return
 		}
 
-		// 将AI回复添加到消息历史
-		historyMessages = append(historyMessages, res)
+		// 将AI回复添加到消息历史并保存会话
+		historyMessages := session.complete(ConversationMessage{Role: res.Role, Content: res.Content})
 
 		// 返回响应
 		ctx.JSON(http.StatusOK, gin.H{
 			"success":    true,
 			"message":    "Chat completed successfully",
 			"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
-			"session_id": sessionID,
+			"session_id": session.ID,
 			"data": gin.H{
 				"reply":    res.Content,
 				"role":     res.Role,
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// validChatRoles 允许出现在历史消息中的角色
+var validChatRoles = map[string]bool{
+	ai.RoleUser:      true,
+	ai.RoleAssistant: true,
+	ai.RoleSystem:    true,
+	ai.RoleFunction:  true,
+}
+
+// inlineImagePattern 旧版接口返回的历史消息中内嵌的图片，见 formatImageMessage
+var inlineImagePattern = regexp.MustCompile(`(?s)\n?\[图片:data:([^;\]]+);base64,([A-Za-z0-9+/=]+)\]$`)
+
+// parseHistoryMessages 解析多模态对话表单中的 history_messages JSON
+// 图片以 image_id 引用已保存的图片；旧版客户端回传的内嵌 base64 图片会被保存并转换为图片ID
+func parseHistoryMessages(raw string) ([]ConversationMessage, []utils.FieldError) {
+	var messages []ConversationMessage
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&messages); err != nil {
+		return nil, []utils.FieldError{{
+			Field: "history_messages",
+			Error: "must be a JSON array of {role, content, image_id} objects: " + err.Error(),
+		}}
+	}
+
+	// 先校验再保存内嵌图片，避免请求被拒绝时留下无人引用的图片
+	if errs := validateHistoryMessages(messages); len(errs) > 0 {
+		return nil, errs
+	}
+
+	for i := range messages {
+		if match := inlineImagePattern.FindStringSubmatch(messages[i].Content); match != nil && messages[i].ImageID == "" {
+			imageID, err := saveInlineImage(match[1], match[2])
+			if err != nil {
+				return nil, []utils.FieldError{{
+					Field: fmt.Sprintf("history_messages[%d].content", i),
+					Error: "invalid inline image: " + err.Error(),
+				}}
+			}
+			messages[i].Content = strings.TrimSuffix(messages[i].Content, match[0])
+			messages[i].ImageID = imageID
+		}
+	}
+
+	return messages, nil
+}
+
+// validateHistoryMessages 校验历史消息的角色、内容和图片引用
+func validateHistoryMessages(messages []ConversationMessage) []utils.FieldError {
+	var errs []utils.FieldError
+	for i, msg := range messages {
+		field := fmt.Sprintf("history_messages[%d]", i)
+		switch {
+		case msg.Role == "":
+			errs = append(errs, utils.FieldError{Field: field + ".role", Error: "is required"})
+		case !validChatRoles[msg.Role]:
+			errs = append(errs, utils.FieldError{
+				Field: field + ".role",
+				Error: fmt.Sprintf("unsupported role %q (allowed: user, assistant, system, function)", msg.Role),
+			})
+		case msg.Role == ai.RoleSystem && i != 0:
+			errs = append(errs, utils.FieldError{Field: field + ".role", Error: "system message must be the first message"})
+		}
+
+		if strings.TrimSpace(msg.Content) == "" && msg.ImageID == "" {
+			errs = append(errs, utils.FieldError{Field: field + ".content", Error: "content or image_id is required"})
+		}
+
+		if msg.ImageID != "" {
+			switch {
+			case msg.Role != ai.RoleUser:
+				errs = append(errs, utils.FieldError{Field: field + ".image_id", Error: "only user messages can reference images"})
+			case GlobalConversationStore == nil:
+				errs = append(errs, utils.FieldError{Field: field + ".image_id", Error: "image references are not available"})
+			default:
+				if _, _, exists := GlobalConversationStore.GetImage(msg.ImageID); !exists {
+					errs = append(errs, utils.FieldError{Field: field + ".image_id", Error: "image not found: " + msg.ImageID})
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// saveInlineImage 保存内嵌的 base64 图片并返回图片ID
+func saveInlineImage(contentType, encoded string) (string, error) {
+	if GlobalConversationStore == nil {
+		return "", fmt.Errorf("image storage is not available")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(strings.ToLower(contentType), "image/") {
+		return "", fmt.Errorf("unsupported content type %s", contentType)
+	}
+	return GlobalConversationStore.SaveImage(contentType, data)
+}
@@ -8,14 +8,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 )
 
 // MultimodalChatHandler handles the AI chat requests with image support.
-func MultimodalChatHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+func MultimodalChatHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// 获取表单数据
 		message := ctx.PostForm("message")
@@ -31,88 +31,100 @@ func MultimodalChatHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 处理图片上传
-		var imageBase64 string
+		var imageData []byte
+		var imageContentType string
 		file, header, err := ctx.Request.FormFile("image")
 		if err == nil {
 			defer file.Close()
 
 			// 读取图片数据
-			imageData, err := io.ReadAll(file)
+			imageData, err = io.ReadAll(file)
 			if err != nil {
 				utils.AbortWithBadRequest(ctx, err, "Failed to read image file")
 				return
 			}
 
-			// 将图片转换为base64
-			imageBase64 = base64.StdEncoding.EncodeToString(imageData)
-
-			// 添加MIME类型前缀
-			contentType := header.Header.Get("Content-Type")
-			if contentType == "" {
+			// 确定图片的MIME类型
+			imageContentType = header.Header.Get("Content-Type")
+			if imageContentType == "" {
 				// 根据文件扩展名推断MIME类型
 				ext := strings.ToLower(header.Filename[strings.LastIndex(header.Filename, ".")+1:])
 				switch ext {
 				case "jpg", "jpeg":
-					contentType = "image/jpeg"
+					imageContentType = "image/jpeg"
 				case "png":
-					contentType = "image/png"
+					imageContentType = "image/png"
 				case "gif":
-					contentType = "image/gif"
+					imageContentType = "image/gif"
 				case "webp":
-					contentType = "image/webp"
+					imageContentType = "image/webp"
 				default:
-					contentType = "image/jpeg"
+					imageContentType = "image/jpeg"
 				}
 			}
-			imageBase64 = fmt.Sprintf("data:%s;base64,%s", contentType, imageBase64)
 		}
 
 		// 创建蓝心大模型应用实例
-		chatApp := createBlueLMApp(appID, appKey, cfg)
+		chatApp, ok := resolveProvider(ctx, resolver, ai.Credentials{
+			AppID:   appID,
+			AppKey:  appKey,
+			Profile: ctx.PostForm("profile"),
+		})
+		if !ok {
+			return
+		}
 
-		// 生成或使用现有的会话ID
-		if sessionID == "" {
-			sessionID = vivo.GenerateSessionID()
+		// 解析历史消息，未提供时使用服务端保存的会话历史
+		// 先校验历史消息再保存图片，避免请求被拒绝时留下无人引用的图片
+		var clientHistory []ConversationMessage
+		if strings.TrimSpace(historyMessagesStr) != "" {
+			var errs []utils.FieldError
+			clientHistory, errs = parseHistoryMessages(historyMessagesStr)
+			if len(errs) > 0 {
+				utils.AbortWithValidationErrors(ctx, "Invalid history_messages", errs)
+				return
+			}
 		}
 
-		// 解析历史消息
-		var historyMessages []vivo.ChatMessage
-		if historyMessagesStr != "" {
-			// TODO: 解析历史消息JSON字符串
-			// 这里暂时跳过历史消息的解析
+		session, err := openChatSession(sessionID, clientHistory, ConversationMessage{Role: ai.RoleUser, Content: message})
+		if err != nil {
+			utils.AbortWithInternalServerError(ctx, err)
+			return
 		}
 
-		// 构建当前消息内容
-		var messageContent string
-		if imageBase64 != "" {
-			// 如果有图片，使用多模态格式
-			messageContent = fmt.Sprintf("%s\n[图片:%s]", message, imageBase64)
-		} else {
-			messageContent = message
+		// 图片保存到会话存储中，历史消息里只保留图片ID
+		userMessage := &session.History[len(session.History)-1]
+		if imageData != nil && GlobalConversationStore != nil {
+			userMessage.ImageID, err = GlobalConversationStore.SaveImage(imageContentType, imageData)
+			if err != nil {
+				utils.AbortWithInternalServerError(ctx, err)
+				return
+			}
 		}
 
-		// 添加当前用户消息
-		historyMessages = append(historyMessages, vivo.ChatMessage{
-			Role:    vivo.CHAT_ROLE_USER,
-			Content: messageContent,
-		})
+		// 构建发送给模型的消息，图片以 data URL 内嵌到当前消息中
+		messages := session.chatMessages()
+		if imageData != nil && userMessage.ImageID == "" {
+			messages[len(messages)-1].Content = formatImageMessage(message, imageDataURL(imageContentType, imageData))
+		}
 
 		// 调用蓝心大模型的多模态接口
-		res, err := chatApp.Chat(vivo.GenerateSessionID(), sessionID, historyMessages, nil)
+		res, err := chatApp.Chat(session.ID, messages)
 		if err != nil {
+			session.discard()
 			utils.AbortWithInternalServerError(ctx, err)
 			return
 		}
 
-		// 将AI回复添加到消息历史
-		historyMessages = append(historyMessages, res)
+		// 将AI回复添加到消息历史并保存会话
+		historyMessages := session.complete(ConversationMessage{Role: res.Role, Content: res.Content})
 
 		// 返回响应
 		ctx.JSON(http.StatusOK, gin.H{
 			"success":    true,
 			"message":    "Chat completed successfully",
 			"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
-			"session_id": sessionID,
+			"session_id": session.ID,
 			"data": gin.H{
 				"reply":    res.Content,
 				"role":     res.Role,
@@ -125,3 +137,13 @@ func MultimodalChatHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 		})
 	}
 }
+
+// imageDataURL 将图片编码为 data URL
+func imageDataURL(contentType string, data []byte) string {
+	return fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(data))
+}
+
+// formatImageMessage 将图片内嵌到消息文本中
+func formatImageMessage(message, imageURL string) string {
+	return fmt.Sprintf("%s\n[图片:%s]", message, imageURL)
+}
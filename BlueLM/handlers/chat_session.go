@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// chatSession 一次对话请求所在的会话
+// 客户端未提供历史时从会话存储中读取，客户端提供的历史优先并在保存时覆盖服务端记录
+type chatSession struct {
+	ID            string
+	History       []ConversationMessage // 包含本轮的用户消息
+	clientHistory bool
+}
+
+// openChatSession 打开会话并追加本轮用户消息，sessionID 为空时创建新会话
+func openChatSession(sessionID string, clientHistory []ConversationMessage, userMessage ConversationMessage) (*chatSession, error) {
+	session := &chatSession{ID: sessionID}
+	if session.ID == "" {
+		session.ID = ai.NewSessionID()
+	}
+
+	switch {
+	case clientHistory != nil:
+		session.History = clientHistory
+		session.clientHistory = true
+	case GlobalConversationStore != nil && sessionID != "":
+		conv, err := GlobalConversationStore.Get(sessionID)
+		if err != nil && !errors.Is(err, ErrConversationNotFound) {
+			return nil, fmt.Errorf("failed to load conversation: %v", err)
+		}
+		if conv != nil {
+			session.History = conv.Messages
+		}
+	}
+
+	session.History = append(session.History, userMessage)
+	return session, nil
+}
+
+// chatMessages 返回发送给模型的消息
+func (s *chatSession) chatMessages() []ai.ChatMessage {
+	return GlobalConversationStore.toChatMessages(s.History)
+}
+
+// complete 记录模型回复并保存会话，返回完整的消息历史
+// 保存失败只记录日志，不影响本次回复
+func (s *chatSession) complete(reply ConversationMessage) []ConversationMessage {
+	s.History = append(s.History, reply)
+	if GlobalConversationStore == nil {
+		return s.History
+	}
+
+	var err error
+	if s.clientHistory {
+		_, err = GlobalConversationStore.Replace(s.ID, s.History)
+	} else {
+		_, err = GlobalConversationStore.Append(s.ID, s.History[len(s.History)-2:]...)
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to save conversation %s: %v", s.ID, err)
+	}
+	return s.History
+}
+
+// discard 对话失败、会话未保存时调用，删除本次请求保存但没有被任何会话引用的图片
+func (s *chatSession) discard() {
+	if GlobalConversationStore == nil {
+		return
+	}
+	var imageIDs []string
+	for _, msg := range s.History {
+		imageIDs = append(imageIDs, msg.ImageID)
+	}
+	if err := GlobalConversationStore.DeleteUnreferencedImages(imageIDs...); err != nil {
+		utils.Log.Errorf("Failed to clean up images of conversation %s: %v", s.ID, err)
+	}
+}
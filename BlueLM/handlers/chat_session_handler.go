@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// requireConversationStore 会话存储未初始化时返回 503
+func requireConversationStore(c *gin.Context) bool {
+	if GlobalConversationStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Conversation store is not available"})
+		return false
+	}
+	return true
+}
+
+// respondConversationError 将会话存储的错误转换为响应
+func respondConversationError(c *gin.Context, sessionID string, err error) {
+	if errors.Is(err, ErrConversationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "Session not found",
+			"session_id": sessionID,
+		})
+		return
+	}
+	utils.AbortWithInternalServerError(c, err)
+}
+
+// ChatSessionsHandler 列出所有会话
+func ChatSessionsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireConversationStore(c) {
+			return
+		}
+		sessions, err := GlobalConversationStore.List()
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"sessions": sessions,
+			"total":    len(sessions),
+		})
+	}
+}
+
+// ChatSessionHandler 获取会话及其完整消息历史
+func ChatSessionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireConversationStore(c) {
+			return
+		}
+		sessionID := c.Param("session_id")
+		conv, err := GlobalConversationStore.Get(sessionID)
+		if err != nil {
+			respondConversationError(c, sessionID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"session": conv,
+		})
+	}
+}
+
+// ChatSessionRenameHandler 修改会话标题
+func ChatSessionRenameHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireConversationStore(c) {
+			return
+		}
+		var requestBody struct {
+			Title string `json:"title"`
+		}
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid request format")
+			return
+		}
+		title := strings.TrimSpace(requestBody.Title)
+		if title == "" {
+			utils.AbortWithBadRequest(c, nil, "Title cannot be empty")
+			return
+		}
+
+		sessionID := c.Param("session_id")
+		conv, err := GlobalConversationStore.Rename(sessionID, title)
+		if err != nil {
+			respondConversationError(c, sessionID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"session": conv.summary(),
+		})
+	}
+}
+
+// ChatSessionForkHandler 从会话的某条消息处分叉出新会话
+// message_count 指定保留的消息条数，缺省时复制全部消息
+func ChatSessionForkHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireConversationStore(c) {
+			return
+		}
+		var requestBody struct {
+			MessageCount int    `json:"message_count,omitempty"`
+			Title        string `json:"title,omitempty"`
+		}
+		// 请求体可以为空
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&requestBody); err != nil {
+				utils.AbortWithBadRequest(c, err, "Invalid request format")
+				return
+			}
+		}
+		if requestBody.MessageCount < 0 {
+			utils.AbortWithBadRequest(c, nil, "message_count cannot be negative")
+			return
+		}
+
+		sessionID := c.Param("session_id")
+		conv, err := GlobalConversationStore.Fork(sessionID, requestBody.MessageCount, strings.TrimSpace(requestBody.Title))
+		if err != nil {
+			respondConversationError(c, sessionID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"session": conv,
+		})
+	}
+}
+
+// ChatSessionDeleteHandler 删除会话
+func ChatSessionDeleteHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireConversationStore(c) {
+			return
+		}
+		sessionID := c.Param("session_id")
+		if err := GlobalConversationStore.Delete(sessionID); err != nil {
+			respondConversationError(c, sessionID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":    true,
+			"session_id": sessionID,
+		})
+	}
+}
+
+// ChatImageHandler 返回会话中引用的图片
+func ChatImageHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireConversationStore(c) {
+			return
+		}
+		imageID := c.Param("image_id")
+		contentType, data, exists := GlobalConversationStore.GetImage(imageID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":    "Image not found",
+				"image_id": imageID,
+			})
+			return
+		}
+		// 图片以内容哈希为ID，内容不会变化
+		c.Header("Cache-Control", "private, max-age=31536000, immutable")
+		c.Data(http.StatusOK, contentType, data)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 流式对话的 SSE 事件类型
+const (
+	ChatEventStart = "start" // 开始生成，携带会话ID
+	ChatEventDelta = "delta" // 增量内容
+	ChatEventDone  = "done"  // 生成结束，携带与 ChatHandler 相同结构的完整结果
+	ChatEventError = "error"
+)
+
+// chatStreamFunc 执行一次流式对话，during 在收到每段增量内容时被调用
+type chatStreamFunc func(during func(delta string)) error
+
+// streamChat 以 SSE 推送对话的增量内容，最后推送完整的消息历史
+// 上游请求绑定客户端请求的 context，客户端断开时立即取消上游生成
+func streamChat(ctx *gin.Context, chatApp ai.Provider, session *chatSession) {
+	messages := session.chatMessages()
+	stream := func(during func(delta string)) error {
+		return chatApp.ChatStream(ctx.Request.Context(), session.ID, messages, during)
+	}
+	streamChatEvents(ctx, stream, session)
+}
+
+// streamChatEvents 将流式对话的结果写为 SSE 事件
+// 只有完整生成的回复才会保存到会话中
+func streamChatEvents(ctx *gin.Context, stream chatStreamFunc, session *chatSession) {
+	sessionID := session.ID
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no") // 禁用反向代理缓冲
+
+	ctx.SSEvent(ChatEventStart, gin.H{"session_id": sessionID})
+	ctx.Writer.Flush()
+
+	requestCtx := ctx.Request.Context()
+	var reply strings.Builder
+	err := stream(func(delta string) {
+		if delta == "" {
+			return
+		}
+		reply.WriteString(delta)
+		ctx.SSEvent(ChatEventDelta, gin.H{"content": delta})
+		ctx.Writer.Flush()
+	})
+
+	if requestCtx.Err() != nil {
+		utils.Log.Infof("Chat stream for session %s cancelled by client", sessionID)
+		return
+	}
+	if err != nil {
+		utils.Log.Errorf("Chat stream for session %s failed: %v", sessionID, err)
+		ctx.SSEvent(ChatEventError, gin.H{
+			"success": false,
+			"message": err.Error(),
+			"reply":   reply.String(), // 出错前已生成的部分
+		})
+		return
+	}
+
+	res := ConversationMessage{Role: ai.RoleAssistant, Content: reply.String()}
+	historyMessages := session.complete(res)
+	ctx.SSEvent(ChatEventDone, gin.H{
+		"success":    true,
+		"message":    "Chat completed successfully",
+		"timestamp":  time.Now().Format("2006-01-02 15:04:05"),
+		"session_id": sessionID,
+		"data": gin.H{
+			"reply":    res.Content,
+			"role":     res.Role,
+			"messages": historyMessages,
+		},
+	})
+}
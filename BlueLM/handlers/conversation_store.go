@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+var (
+	// conversationsBucket 会话记录，键为会话ID
+	conversationsBucket = []byte("conversations")
+	// chatImagesBucket 对话中上传的图片，键为图片内容的哈希
+	chatImagesBucket = []byte("chat_images")
+)
+
+// ErrConversationNotFound 会话不存在
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationMessage 会话中的一条消息，图片以 ID 引用而不内嵌 base64
+type ConversationMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	ImageID string `json:"image_id,omitempty"`
+}
+
+// Conversation 按会话ID保存的对话
+type Conversation struct {
+	SessionID  string                `json:"session_id"`
+	Title      string                `json:"title"`
+	ForkedFrom string                `json:"forked_from,omitempty"` // 分叉来源会话
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Messages   []ConversationMessage `json:"messages"`
+}
+
+// ConversationSummary 会话列表中的摘要信息
+type ConversationSummary struct {
+	SessionID    string    `json:"session_id"`
+	Title        string    `json:"title"`
+	ForkedFrom   string    `json:"forked_from,omitempty"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// chatImage 保存的图片
+type chatImage struct {
+	ContentType string
+	Data        []byte
+}
+
+// ConversationStore 基于 BoltDB 的会话存储，文本对话和多模态对话共用
+type ConversationStore struct {
+	db *bolt.DB
+}
+
+// GlobalConversationStore 全局会话存储，未初始化时对话不做持久化
+var GlobalConversationStore *ConversationStore
+
+// InitConversationStore 初始化会话存储
+func InitConversationStore(db *bolt.DB) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(conversationsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(chatImagesBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init conversation store: %v", err)
+	}
+
+	GlobalConversationStore = &ConversationStore{db: db}
+	return nil
+}
+
+// Get 获取会话
+func (s *ConversationStore) Get(sessionID string) (*Conversation, error) {
+	var conv *Conversation
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		conv, err = loadConversation(tx, sessionID)
+		return err
+	})
+	return conv, err
+}
+
+// List 列出所有会话，最近更新的在前
+func (s *ConversationStore) List() ([]ConversationSummary, error) {
+	summaries := []ConversationSummary{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(conversationsBucket).ForEach(func(k, v []byte) error {
+			var conv Conversation
+			if err := json.Unmarshal(v, &conv); err != nil {
+				utils.Log.Warnf("Skipping corrupted conversation %s: %v", string(k), err)
+				return nil
+			}
+			summaries = append(summaries, conv.summary())
+			return nil
+		})
+	})
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, err
+}
+
+// Append 向会话追加消息，会话不存在时创建
+func (s *ConversationStore) Append(sessionID string, messages ...ConversationMessage) (*Conversation, error) {
+	return s.update(sessionID, true, func(conv *Conversation) error {
+		conv.Messages = append(conv.Messages, messages...)
+		return nil
+	})
+}
+
+// Replace 用客户端提供的完整历史覆盖会话消息，会话不存在时创建
+func (s *ConversationStore) Replace(sessionID string, messages []ConversationMessage) (*Conversation, error) {
+	return s.update(sessionID, true, func(conv *Conversation) error {
+		conv.Messages = messages
+		return nil
+	})
+}
+
+// Rename 修改会话标题
+func (s *ConversationStore) Rename(sessionID, title string) (*Conversation, error) {
+	return s.update(sessionID, false, func(conv *Conversation) error {
+		conv.Title = title
+		return nil
+	})
+}
+
+// Fork 复制会话的前 keep 条消息为新会话，keep <= 0 表示复制全部消息
+func (s *ConversationStore) Fork(sessionID string, keep int, title string) (*Conversation, error) {
+	var forked *Conversation
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		source, err := loadConversation(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		if keep <= 0 || keep > len(source.Messages) {
+			keep = len(source.Messages)
+		}
+		if title == "" {
+			title = source.Title + " (fork)"
+		}
+
+		now := time.Now()
+		forked = &Conversation{
+			SessionID:  ai.NewSessionID(),
+			Title:      title,
+			ForkedFrom: sessionID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			Messages:   append([]ConversationMessage(nil), source.Messages[:keep]...),
+		}
+		return saveConversation(tx, forked)
+	})
+	return forked, err
+}
+
+// Delete 删除会话，并清理不再被任何会话引用的图片
+func (s *ConversationStore) Delete(sessionID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		conv, err := loadConversation(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		bucket := tx.Bucket(conversationsBucket)
+		if err := bucket.Delete([]byte(sessionID)); err != nil {
+			return err
+		}
+
+		orphans := make(map[string]bool)
+		for _, msg := range conv.Messages {
+			if msg.ImageID != "" {
+				orphans[msg.ImageID] = true
+			}
+		}
+		return deleteOrphanImages(tx, orphans)
+	})
+}
+
+// DeleteUnreferencedImages 删除没有被任何会话引用的图片，用于清理对话失败时本次请求保存的图片
+func (s *ConversationStore) DeleteUnreferencedImages(imageIDs ...string) error {
+	orphans := make(map[string]bool)
+	for _, imageID := range imageIDs {
+		if imageID != "" {
+			orphans[imageID] = true
+		}
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteOrphanImages(tx, orphans)
+	})
+}
+
+// deleteOrphanImages 删除 orphans 中不再被任何会话引用的图片
+// 分叉出的会话可能仍引用同一张图片，相同内容的图片也只保存一份
+func deleteOrphanImages(tx *bolt.Tx, orphans map[string]bool) error {
+	if len(orphans) == 0 {
+		return nil
+	}
+	err := tx.Bucket(conversationsBucket).ForEach(func(_, v []byte) error {
+		var other Conversation
+		if json.Unmarshal(v, &other) == nil {
+			for _, msg := range other.Messages {
+				delete(orphans, msg.ImageID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	images := tx.Bucket(chatImagesBucket)
+	for imageID := range orphans {
+		if err := images.Delete([]byte(imageID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveImage 保存图片并返回其ID，相同内容的图片只保存一份
+func (s *ConversationStore) SaveImage(contentType string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	imageID := "img_" + hex.EncodeToString(sum[:16])
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(chatImage{ContentType: contentType, Data: data}); err != nil {
+		return "", err
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(chatImagesBucket).Put([]byte(imageID), buf.Bytes())
+	})
+	return imageID, err
+}
+
+// GetImage 读取图片
+func (s *ConversationStore) GetImage(imageID string) (contentType string, data []byte, exists bool) {
+	s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(chatImagesBucket).Get([]byte(imageID))
+		if v == nil {
+			return nil
+		}
+		var img chatImage
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&img); err != nil {
+			utils.Log.Warnf("Corrupted chat image %s: %v", imageID, err)
+			return nil
+		}
+		contentType, data, exists = img.ContentType, img.Data, true
+		return nil
+	})
+	return contentType, data, exists
+}
+
+// update 在事务内读取、修改并保存会话
+func (s *ConversationStore) update(sessionID string, create bool, mutate func(conv *Conversation) error) (*Conversation, error) {
+	var conv *Conversation
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		conv, err = loadConversation(tx, sessionID)
+		if errors.Is(err, ErrConversationNotFound) && create {
+			conv = &Conversation{SessionID: sessionID, CreatedAt: time.Now()}
+		} else if err != nil {
+			return err
+		}
+
+		if err := mutate(conv); err != nil {
+			return err
+		}
+		if conv.Title == "" {
+			conv.Title = defaultConversationTitle(conv.Messages)
+		}
+		conv.UpdatedAt = time.Now()
+		return saveConversation(tx, conv)
+	})
+	return conv, err
+}
+
+func loadConversation(tx *bolt.Tx, sessionID string) (*Conversation, error) {
+	v := tx.Bucket(conversationsBucket).Get([]byte(sessionID))
+	if v == nil {
+		return nil, ErrConversationNotFound
+	}
+	var conv Conversation
+	if err := json.Unmarshal(v, &conv); err != nil {
+		return nil, fmt.Errorf("corrupted conversation %s: %v", sessionID, err)
+	}
+	return &conv, nil
+}
+
+func saveConversation(tx *bolt.Tx, conv *Conversation) error {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(conversationsBucket).Put([]byte(conv.SessionID), data)
+}
+
+// summary 生成会话摘要
+func (conv *Conversation) summary() ConversationSummary {
+	return ConversationSummary{
+		SessionID:    conv.SessionID,
+		Title:        conv.Title,
+		ForkedFrom:   conv.ForkedFrom,
+		MessageCount: len(conv.Messages),
+		CreatedAt:    conv.CreatedAt,
+		UpdatedAt:    conv.UpdatedAt,
+	}
+}
+
+// defaultConversationTitle 以第一条用户消息的开头作为默认标题
+func defaultConversationTitle(messages []ConversationMessage) string {
+	for _, msg := range messages {
+		if msg.Role != ai.RoleUser {
+			continue
+		}
+		title := strings.Join(strings.Fields(msg.Content), " ")
+		if utf8.RuneCountInString(title) > 30 {
+			title = string([]rune(title)[:30]) + "…"
+		}
+		if title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+// toChatMessages 将会话消息转换为模型的消息格式，引用的图片以 data URL 内嵌到文本中
+func (s *ConversationStore) toChatMessages(messages []ConversationMessage) []ai.ChatMessage {
+	result := make([]ai.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		content := msg.Content
+		if msg.ImageID != "" && s != nil {
+			if contentType, data, exists := s.GetImage(msg.ImageID); exists {
+				content = formatImageMessage(msg.Content, imageDataURL(contentType, data))
+			}
+		}
+		result = append(result, ai.ChatMessage{Role: msg.Role, Content: content})
+	}
+	return result
+}
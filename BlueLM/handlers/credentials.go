@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// resolveProvider 按凭据查找顺序获取本次请求使用的 AI 提供方
+// 请求指定的档案不存在或没有可用凭据时返回 400
+func resolveProvider(c *gin.Context, resolver *ai.Resolver, creds ai.Credentials) (ai.Provider, bool) {
+	provider, err := resolver.Resolve(creds)
+	if err != nil {
+		utils.AbortWithBadRequest(c, err, "Invalid vivo AI credentials: "+err.Error())
+		return nil, false
+	}
+	return provider, true
+}
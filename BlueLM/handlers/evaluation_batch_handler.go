@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// batchEvaluationWeakestItems 报告中列出的得分最低的条目数
+const batchEvaluationWeakestItems = 5
+
+// BatchEvaluationRequest 批量评估请求，例如一整份练习的全部句子
+// 除 items 外的字段作为各条目的默认值，条目中已填写的字段优先；凭据对所有条目生效
+type BatchEvaluationRequest struct {
+	Title          string                         `json:"title,omitempty"` // 练习名称，作为任务的文件名显示
+	Items          []TranslationEvaluationRequest `json:"items"`
+	SourceLanguage string                         `json:"source_language,omitempty"`
+	TargetLanguage string                         `json:"target_language,omitempty"`
+	Context        string                         `json:"context,omitempty"`
+	GlossaryID     string                         `json:"glossary_id,omitempty"`
+	Rubric         string                         `json:"rubric,omitempty"`
+	LearnerID      string                         `json:"learner_id,omitempty"`
+	AppID          string                         `json:"app_id,omitempty"`
+	AppKey         string                         `json:"app_key,omitempty"`
+	Profile        string                         `json:"profile,omitempty"`
+	CallbackURL    string                         `json:"callback_url,omitempty"`
+	CallbackSecret string                         `json:"callback_secret,omitempty"`
+}
+
+// BatchEvaluationItem 批量评估中一条的结果
+type BatchEvaluationItem struct {
+	Index        int                 `json:"index"`
+	Success      bool                `json:"success"`
+	Error        string              `json:"error,omitempty"`
+	Result       *EvaluationResult   `json:"result,omitempty"`
+	Similarity   *SimilarityResult   `json:"similarity,omitempty"`
+	AIEvaluation *AIEvaluationResult `json:"ai_evaluation,omitempty"`
+	EvaluationID string              `json:"evaluation_id,omitempty"` // 条目带 learner_id 时保存的评估记录ID
+}
+
+// BatchEvaluationWeakItem 报告中得分较低的条目
+type BatchEvaluationWeakItem struct {
+	Index            int     `json:"index"`
+	Score            float64 `json:"score"`
+	Level            string  `json:"level"`
+	OriginalText     string  `json:"original_text"`
+	UserTranslation  string  `json:"user_translation"`
+	WeakestDimension string  `json:"weakest_dimension,omitempty"` // AI 评分最低的维度，AI 评估失败时为空
+}
+
+// BatchEvaluationReport 批量评估结果文件 evaluation_<id>.json 的内容
+type BatchEvaluationReport struct {
+	TaskID            string                    `json:"task_id"`
+	Title             string                    `json:"title,omitempty"`
+	CreatedAt         time.Time                 `json:"created_at"`
+	Total             int                       `json:"total"`
+	Succeeded         int                       `json:"succeeded"`
+	Failed            int                       `json:"failed"`
+	Degraded          int                       `json:"degraded"` // AI 评估失败、评分仅基于相似度的条目数
+	AverageScore      float64                   `json:"average_score"`
+	AverageSimilarity float64                   `json:"average_similarity"`
+	Dimensions        map[string]float64        `json:"dimensions"` // 各维度的平均 AI 评分，不含 AI 评估失败的条目
+	Levels            map[string]int            `json:"levels"`     // 各评级的条目数
+	WeakestItems      []BatchEvaluationWeakItem `json:"weakest_items"`
+	Items             []BatchEvaluationItem     `json:"items"`
+}
+
+// batchEvaluationInput 一条待评估的请求及其评分标准和术语表
+type batchEvaluationInput struct {
+	req      TranslationEvaluationRequest
+	rubric   *Rubric
+	glossary *Glossary
+}
+
+// evaluationReportFileName 返回批量评估结果文件名
+func evaluationReportFileName(taskID string) string {
+	return "evaluation_" + taskID + ".json"
+}
+
+// prepareBatchEvaluation 填充各条目的默认值并加载评分标准和术语表
+// 失败时返回对应的 HTTP 状态码，错误信息中带有条目序号
+func prepareBatchEvaluation(batch BatchEvaluationRequest) ([]batchEvaluationInput, int, error) {
+	rubrics := map[string]*Rubric{}
+	glossaries := map[string]*Glossary{}
+	inputs := make([]batchEvaluationInput, len(batch.Items))
+	for i, req := range batch.Items {
+		if strings.TrimSpace(req.OriginalText) == "" || strings.TrimSpace(req.UserTranslation) == "" || strings.TrimSpace(req.StandardAnswer) == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("items[%d]: original_text, user_translation and standard_answer are required", i)
+		}
+		req.SourceLanguage = cmp.Or(req.SourceLanguage, batch.SourceLanguage)
+		req.TargetLanguage = cmp.Or(req.TargetLanguage, batch.TargetLanguage)
+		req.Context = cmp.Or(req.Context, batch.Context)
+		req.GlossaryID = cmp.Or(req.GlossaryID, batch.GlossaryID)
+		req.Rubric = cmp.Or(req.Rubric, batch.Rubric)
+		req.LearnerID = cmp.Or(req.LearnerID, batch.LearnerID)
+		req.AppID, req.AppKey, req.Profile = "", "", ""
+		if req.LearnerID != "" {
+			if err := validateLearnerID(req.LearnerID); err != nil {
+				return nil, http.StatusBadRequest, fmt.Errorf("items[%d]: %v", i, err)
+			}
+		}
+
+		rubric, ok := rubrics[req.Rubric]
+		if !ok {
+			var status int
+			var err error
+			if rubric, status, err = loadRequestRubric(req.Rubric); err != nil {
+				return nil, status, fmt.Errorf("items[%d]: %v", i, err)
+			}
+			rubrics[req.Rubric] = rubric
+		}
+
+		// 同一术语表在不同条目中可能对应不同的语言对，每条都要检查
+		glossary, ok := glossaries[req.GlossaryID]
+		if !ok {
+			var status int
+			var err error
+			if glossary, status, err = loadRequestGlossary(req.GlossaryID, req.SourceLanguage, req.TargetLanguage); err != nil {
+				return nil, status, fmt.Errorf("items[%d]: %v", i, err)
+			}
+			glossaries[req.GlossaryID] = glossary
+		} else if glossary != nil && !glossary.supports(req.SourceLanguage, req.TargetLanguage) {
+			return nil, http.StatusBadRequest, fmt.Errorf("items[%d]: glossary %s is for %s -> %s", i, req.GlossaryID, glossary.From, glossary.To)
+		}
+
+		inputs[i] = batchEvaluationInput{req: req, rubric: rubric, glossary: glossary}
+	}
+	return inputs, http.StatusOK, nil
+}
+
+// BatchEvaluationHandler 创建批量评估任务
+// 条目并发评估，进度通过任务状态和 /tasks/:task_id/events 查看，完成后下载汇总报告
+func BatchEvaluationHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var batch BatchEvaluationRequest
+		if err := c.ShouldBindJSON(&batch); err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid request format: "+err.Error())
+			return
+		}
+		if len(batch.Items) == 0 {
+			utils.AbortWithBadRequest(c, nil, "Items cannot be empty")
+			return
+		}
+		if len(batch.Items) > cfg.Evaluation.BatchMaxItems {
+			utils.AbortWithBadRequest(c, nil, fmt.Sprintf("Too many items: %d (max %d)", len(batch.Items), cfg.Evaluation.BatchMaxItems))
+			return
+		}
+		callback, err := newCallbackTarget(batch.CallbackURL, batch.CallbackSecret)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid callback parameters")
+			return
+		}
+
+		inputs, status, err := prepareBatchEvaluation(batch)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		evalApp, ok := resolveProvider(c, resolver, ai.Credentials{
+			AppID:   batch.AppID,
+			AppKey:  batch.AppKey,
+			Profile: batch.Profile,
+		})
+		if !ok {
+			return
+		}
+
+		title := batch.Title
+		if title == "" {
+			title = "evaluation_batch"
+		}
+		taskID := ai.NewSessionID()
+		GlobalTaskManager.CreateTask(taskID, title, TaskEngineEvaluation)
+		GlobalTaskManager.UpdateTask(taskID, func(task *TaskInfo) {
+			task.Status = TaskStatusProcessing
+			task.Message = fmt.Sprintf("Evaluating %d items", len(inputs))
+			callback.apply(task)
+		})
+
+		go runBatchEvaluationJob(startTaskPoller(taskID), cfg, taskID, batch.Title, evalApp, inputs)
+
+		c.JSON(http.StatusOK, gin.H{
+			"task_id": taskID,
+			"items":   len(inputs),
+		})
+	}
+}
+
+// evaluateBatch 以有限的并发评估所有条目，单条失败不影响其他条目，ctx 被取消后不再开始新的条目
+func evaluateBatch(ctx context.Context, app ai.Provider, inputs []batchEvaluationInput, concurrency, maxAttempts int, progress func(done int)) []BatchEvaluationItem {
+	results := make([]BatchEvaluationItem, len(inputs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i, input := range inputs {
+		results[i] = BatchEvaluationItem{Index: i}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Error = "评估已取消"
+			continue
+		}
+
+		wg.Add(1)
+		go func(item *BatchEvaluationItem, input batchEvaluationInput) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				mu.Lock()
+				defer mu.Unlock()
+				done++
+				progress(done)
+			}()
+
+			outcome, err := evaluateTranslation(app, input.req, input.glossary, input.rubric, maxAttempts)
+			if err != nil {
+				utils.Log.Warnf("Batch evaluation item %d failed: %v", item.Index, err)
+				item.Error = err.Error()
+				return
+			}
+			item.Success = true
+			item.Result = &outcome.Result
+			item.Similarity = &outcome.Similarity
+			item.AIEvaluation = &outcome.AIEvaluation
+			item.EvaluationID = saveEvaluation(input.req, input.rubric, outcome)
+		}(&results[i], input)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// runBatchEvaluationJob 评估全部条目并写出汇总报告，ctx 被取消时停止
+// 部分条目失败时任务仍然完成，失败原因记录在对应条目的 error 中；全部失败时任务失败
+func runBatchEvaluationJob(ctx context.Context, cfg *config.Config, taskID, title string, app ai.Provider, inputs []batchEvaluationInput) {
+	defer finishTaskPoller(taskID)
+
+	items := evaluateBatch(ctx, app, inputs, cfg.Evaluation.BatchConcurrency, cfg.Evaluation.MaxAttempts, func(done int) {
+		updateRunningTask(taskID, func(task *TaskInfo) {
+			task.Progress = done * 100 / len(inputs)
+			task.Message = fmt.Sprintf("Evaluated %d/%d items", done, len(inputs))
+		})
+	})
+	if ctx.Err() != nil {
+		utils.Log.Infof("Batch evaluation task %s stopped: %v", taskID, ctx.Err())
+		return
+	}
+
+	report := buildBatchEvaluationReport(taskID, title, inputs, items)
+	if report.Succeeded == 0 {
+		utils.Log.Errorf("Batch evaluation task %s failed: no item was evaluated", taskID)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, "Evaluation failed for all items: "+items[0].Error)
+		return
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		utils.Log.Errorf("Failed to marshal report for batch evaluation task %s: %v", taskID, err)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error serializing result: %v", err))
+		return
+	}
+	downloadFilePath := filepath.Join(cfg.FilePaths.DownloadDir, evaluationReportFileName(taskID))
+	if err := os.WriteFile(downloadFilePath, jsonData, 0644); err != nil {
+		utils.Log.Errorf("Failed to write report to file for batch evaluation task %s: %v", taskID, err)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error writing file: %v", err))
+		return
+	}
+
+	message := "Batch evaluation completed successfully"
+	if report.Failed > 0 {
+		message = fmt.Sprintf("Batch evaluation completed, %d/%d items failed", report.Failed, report.Total)
+	}
+	GlobalTaskManager.SetTaskFilePath(taskID, downloadFilePath)
+	updateRunningTask(taskID, func(task *TaskInfo) {
+		task.Status = TaskStatusCompleted
+		task.Message = message
+		task.Progress = 100
+	})
+	utils.Log.Infof("Batch evaluation task %s completed. Report saved to %s", taskID, downloadFilePath)
+}
+
+// buildBatchEvaluationReport 汇总各条目的评分，并按综合评分找出得分最低的条目
+func buildBatchEvaluationReport(taskID, title string, inputs []batchEvaluationInput, items []BatchEvaluationItem) BatchEvaluationReport {
+	report := BatchEvaluationReport{
+		TaskID:       taskID,
+		Title:        title,
+		CreatedAt:    time.Now(),
+		Total:        len(items),
+		Dimensions:   map[string]float64{},
+		Levels:       map[string]int{},
+		WeakestItems: []BatchEvaluationWeakItem{},
+		Items:        items,
+	}
+
+	var score, similarity mean
+	dimensions := map[string]*mean{}
+	var weak []BatchEvaluationWeakItem
+	for i, item := range items {
+		if !item.Success {
+			report.Failed++
+			continue
+		}
+		report.Succeeded++
+		score.add(item.Result.Score)
+		similarity.add(item.Similarity.Score)
+		report.Levels[item.Result.Level]++
+
+		weakItem := BatchEvaluationWeakItem{
+			Index:           i,
+			Score:           item.Result.Score,
+			Level:           item.Result.Level,
+			OriginalText:    inputs[i].req.OriginalText,
+			UserTranslation: inputs[i].req.UserTranslation,
+		}
+		if item.AIEvaluation.Degraded {
+			report.Degraded++
+		} else {
+			lowest := 0.0
+			for _, dimension := range inputs[i].rubric.Dimensions {
+				value := item.AIEvaluation.Scores[dimension.Key]
+				if dimensions[dimension.Key] == nil {
+					dimensions[dimension.Key] = &mean{}
+				}
+				dimensions[dimension.Key].add(value)
+				if weakItem.WeakestDimension == "" || value < lowest {
+					weakItem.WeakestDimension, lowest = dimension.Key, value
+				}
+			}
+		}
+		weak = append(weak, weakItem)
+	}
+
+	report.AverageScore = score.value()
+	report.AverageSimilarity = similarity.value()
+	for key, m := range dimensions {
+		report.Dimensions[key] = m.value()
+	}
+	sort.SliceStable(weak, func(i, j int) bool {
+		return weak[i].Score < weak[j].Score
+	})
+	if len(weak) > batchEvaluationWeakestItems {
+		weak = weak[:batchEvaluationWeakestItems]
+	}
+	if weak != nil {
+		report.WeakestItems = weak
+	}
+	return report
+}
+
+// BatchEvaluationDownloadHandler 下载批量评估报告
+func BatchEvaluationDownloadHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filePath, ok := completedTaskResult(c, cfg, TaskEngineEvaluation)
+		if !ok {
+			return
+		}
+		sendResultFile(c, filePath, "application/json")
+	}
+}
+
+// BatchEvaluationTasksHandler 列出所有批量评估任务
+func BatchEvaluationTasksHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listTasks(c, TaskEngineEvaluation)
+	}
+}
@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// evaluationHistoryBucket 学习者的评估记录，每个学习者一个子桶，子桶内的键为自增序号（按时间先后）
+var evaluationHistoryBucket = []byte("evaluation_history")
+
+// maxLearnerIDLength 学习者ID的最大长度
+const maxLearnerIDLength = 128
+
+// ErrLearnerNotFound 学习者没有评估记录
+var ErrLearnerNotFound = errors.New("learner not found")
+
+// EvaluationRecord 一次保存的翻译评估
+type EvaluationRecord struct {
+	EvaluationID string                       `json:"evaluation_id"`
+	LearnerID    string                       `json:"learner_id"`
+	CreatedAt    time.Time                    `json:"created_at"`
+	Request      TranslationEvaluationRequest `json:"request"` // 不含凭据
+	Result       EvaluationResult             `json:"result"`
+	Similarity   SimilarityResult             `json:"similarity"`
+	AIEvaluation AIEvaluationResult           `json:"ai_evaluation"`
+
+	// Categories 本次评估暴露的问题类别，用于统计最常见的改进方向
+	Categories []string `json:"categories"`
+}
+
+// EvaluationHistoryStore 基于 BoltDB 的评估记录存储
+type EvaluationHistoryStore struct {
+	db         *bolt.DB
+	maxRecords int // 每个学习者保留的记录数，超出时删除最早的记录
+}
+
+// GlobalEvaluationHistory 全局评估记录存储，未初始化时不保存评估记录
+var GlobalEvaluationHistory *EvaluationHistoryStore
+
+// InitEvaluationHistory 初始化评估记录存储
+func InitEvaluationHistory(db *bolt.DB, cfg *config.Config) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(evaluationHistoryBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init evaluation history: %v", err)
+	}
+
+	GlobalEvaluationHistory = &EvaluationHistoryStore{db: db, maxRecords: cfg.Evaluation.HistoryMaxRecords}
+	return nil
+}
+
+// validateLearnerID 学习者ID不能为空、过长或包含控制字符
+func validateLearnerID(learnerID string) error {
+	if strings.TrimSpace(learnerID) == "" {
+		return fmt.Errorf("learner_id cannot be empty")
+	}
+	if len(learnerID) > maxLearnerIDLength {
+		return fmt.Errorf("learner_id must not exceed %d bytes", maxLearnerIDLength)
+	}
+	if strings.ContainsFunc(learnerID, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return fmt.Errorf("learner_id must not contain control characters")
+	}
+	return nil
+}
+
+// Record 保存一次评估，请求中的凭据不会保存
+func (s *EvaluationHistoryStore) Record(record *EvaluationRecord) error {
+	record.EvaluationID = ai.NewSessionID()
+	record.CreatedAt = time.Now()
+	record.Request.AppID = ""
+	record.Request.AppKey = ""
+	record.Request.Profile = ""
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		learner, err := tx.Bucket(evaluationHistoryBucket).CreateBucketIfNotExists([]byte(record.LearnerID))
+		if err != nil {
+			return err
+		}
+		seq, err := learner.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := learner.Put(historyKey(seq), data); err != nil {
+			return err
+		}
+
+		// 序号连续递增，最早一条到最新一条之间的条数即为记录数
+		first, _ := learner.Cursor().First()
+		for oldest := binary.BigEndian.Uint64(first); seq-oldest+1 > uint64(s.maxRecords); oldest++ {
+			if err := learner.Delete(historyKey(oldest)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List 按时间先后返回学习者满足过滤条件的评估记录
+func (s *EvaluationHistoryStore) List(learnerID string, filter historyFilter) ([]EvaluationRecord, error) {
+	records := []EvaluationRecord{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		learner := tx.Bucket(evaluationHistoryBucket).Bucket([]byte(learnerID))
+		if learner == nil {
+			return ErrLearnerNotFound
+		}
+		return learner.ForEach(func(k, v []byte) error {
+			var record EvaluationRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				utils.Log.Warnf("Skipping corrupted evaluation record %s/%d: %v", learnerID, binary.BigEndian.Uint64(k), err)
+				return nil
+			}
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
+
+// DeleteLearner 删除学习者的全部评估记录，返回删除的条数
+func (s *EvaluationHistoryStore) DeleteLearner(learnerID string) (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(evaluationHistoryBucket)
+		learner := root.Bucket([]byte(learnerID))
+		if learner == nil {
+			return ErrLearnerNotFound
+		}
+		deleted = learner.Stats().KeyN
+		return root.DeleteBucket([]byte(learnerID))
+	})
+	return deleted, err
+}
+
+func historyKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// historyFilter 查询评估记录的过滤条件，零值表示不过滤
+type historyFilter struct {
+	From           time.Time
+	To             time.Time
+	SourceLanguage string
+	TargetLanguage string
+	Rubric         string
+}
+
+func (f historyFilter) matches(record EvaluationRecord) bool {
+	if !f.From.IsZero() && record.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !record.CreatedAt.Before(f.To) {
+		return false
+	}
+	if f.SourceLanguage != "" && !strings.EqualFold(f.SourceLanguage, record.Request.SourceLanguage) {
+		return false
+	}
+	if f.TargetLanguage != "" && !strings.EqualFold(f.TargetLanguage, record.Request.TargetLanguage) {
+		return false
+	}
+	return f.Rubric == "" || f.Rubric == record.Result.Rubric
+}
+
+// 改进建议的类别；评分标准中自定义的维度以维度 key 作为类别
+const (
+	CategoryTerminology = "terminology"
+	CategoryReference   = "reference"
+	CategoryWordChoice  = "word_choice"
+	CategoryPunctuation = "punctuation"
+	CategoryOther       = "other"
+)
+
+// improvementCategoryLabels 已知类别的中文名称
+var improvementCategoryLabels = map[string]string{
+	"grammar":           "语法",
+	"accuracy":          "意思准确性",
+	"fluency":           "表达流畅性",
+	CategoryTerminology: "术语",
+	CategoryReference:   "与标准答案差异",
+	CategoryWordChoice:  "用词",
+	CategoryPunctuation: "标点",
+	CategoryOther:       "其他",
+}
+
+// adviceCategoryKeywords 按关键词为模型给出的建议归类，按顺序匹配第一个命中的类别
+var adviceCategoryKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{CategoryTerminology, []string{"术语", "terminology", "glossary"}},
+	{CategoryPunctuation, []string{"标点", "punctuation"}},
+	{"grammar", []string{"语法", "时态", "主谓", "单复数", "冠词", "介词", "语序", "grammar", "tense", "article", "preposition"}},
+	{CategoryWordChoice, []string{"用词", "词汇", "措辞", "选词", "搭配", "word", "vocabulary", "collocation"}},
+	{"accuracy", []string{"漏译", "误译", "错译", "原意", "意思", "含义", "准确", "accura", "meaning", "mistranslat", "omission", "omit"}},
+	{"fluency", []string{"流畅", "通顺", "自然", "生硬", "地道", "fluen", "natural", "idiomatic"}},
+}
+
+// classifyAdvice 返回建议所属的类别
+func classifyAdvice(advice string) string {
+	lower := strings.ToLower(advice)
+	for _, group := range adviceCategoryKeywords {
+		for _, keyword := range group.keywords {
+			if strings.Contains(lower, keyword) {
+				return group.category
+			}
+		}
+	}
+	return CategoryOther
+}
+
+// evaluationCategories 汇总一次评估暴露的问题类别：得分偏低的维度、术语违例、与标准答案差异较大，以及模型建议的归类
+func evaluationCategories(similarity SimilarityResult, aiEval AIEvaluationResult, rubric *Rubric, glossaryViolated bool) []string {
+	categories := []string{}
+	seen := make(map[string]bool)
+	add := func(category string) {
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+
+	if !aiEval.Degraded {
+		for _, dimension := range rubric.Dimensions {
+			if aiEval.Scores[dimension.Key] < rubric.ImproveBelow {
+				add(dimension.Key)
+			}
+		}
+	}
+	if glossaryViolated {
+		add(CategoryTerminology)
+	}
+	if similarity.Score < 0.6 {
+		add(CategoryReference)
+	}
+	for _, advice := range aiEval.DetailedAdvice {
+		add(classifyAdvice(advice))
+	}
+	return categories
+}
+
+// ProgressPoint 时间序列上的一个点，Value 为该时间段内的平均分
+type ProgressPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	Count int       `json:"count"`
+}
+
+// ProgressReport 学习者各项得分的时间序列
+type ProgressReport struct {
+	Interval   string                     `json:"interval"`
+	Score      []ProgressPoint            `json:"score"`      // 综合评分
+	Similarity []ProgressPoint            `json:"similarity"` // 与标准答案的相似度（0-1）
+	Dimensions map[string][]ProgressPoint `json:"dimensions"` // 各维度的 AI 评分，不含 AI 评估失败的记录
+}
+
+// 时间序列的聚合粒度
+const (
+	ProgressIntervalEvaluation = "evaluation" // 每次评估一个点
+	ProgressIntervalDay        = "day"
+	ProgressIntervalWeek       = "week"
+)
+
+// mean 平均值累加器
+type mean struct {
+	sum   float64
+	count int
+}
+
+func (m *mean) add(v float64) {
+	m.sum += v
+	m.count++
+}
+
+func (m mean) value() float64 {
+	if m.count == 0 {
+		return 0
+	}
+	return m.sum / float64(m.count)
+}
+
+// periodStart 返回时间所在聚合周期的起点，周以周一为起点
+func periodStart(t time.Time, interval string) time.Time {
+	switch interval {
+	case ProgressIntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case ProgressIntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return t
+	}
+}
+
+// buildProgressReport 按聚合粒度生成时间序列，records 需按时间先后排列
+func buildProgressReport(records []EvaluationRecord, interval string) ProgressReport {
+	report := ProgressReport{
+		Interval:   interval,
+		Score:      []ProgressPoint{},
+		Similarity: []ProgressPoint{},
+		Dimensions: map[string][]ProgressPoint{},
+	}
+
+	// series 一条时间序列，按周期累加后再输出
+	type series struct {
+		points []ProgressPoint
+		acc    mean
+	}
+	flush := func(s *series, period time.Time) {
+		if s.acc.count > 0 {
+			s.points = append(s.points, ProgressPoint{Time: period, Value: s.acc.value(), Count: s.acc.count})
+			s.acc = mean{}
+		}
+	}
+
+	var score, similarity series
+	dimensions := map[string]*series{}
+	var period time.Time
+	for i, record := range records {
+		start := periodStart(record.CreatedAt.Local(), interval)
+		if i > 0 && !start.Equal(period) {
+			flush(&score, period)
+			flush(&similarity, period)
+			for _, s := range dimensions {
+				flush(s, period)
+			}
+		}
+		period = start
+
+		score.acc.add(record.Result.Score)
+		similarity.acc.add(record.Similarity.Score)
+		if record.AIEvaluation.Degraded {
+			continue
+		}
+		for key, value := range record.AIEvaluation.Scores {
+			if dimensions[key] == nil {
+				dimensions[key] = &series{}
+			}
+			dimensions[key].acc.add(value)
+		}
+	}
+	flush(&score, period)
+	flush(&similarity, period)
+	for key, s := range dimensions {
+		flush(s, period)
+		report.Dimensions[key] = s.points
+	}
+	if score.points != nil {
+		report.Score = score.points
+		report.Similarity = similarity.points
+	}
+	return report
+}
+
+// ImprovementCategoryStat 一个改进类别的出现情况
+type ImprovementCategoryStat struct {
+	Category string   `json:"category"`
+	Label    string   `json:"label"`
+	Count    int      `json:"count"`    // 出现该类问题的评估次数
+	Share    float64  `json:"share"`    // 占全部评估的比例
+	Examples []string `json:"examples"` // 最近几次评估中该类别的建议
+}
+
+// maxCategoryExamples 每个类别返回的示例建议数
+const maxCategoryExamples = 3
+
+// buildImprovementStats 统计各类问题出现的次数，按次数从多到少排列
+func buildImprovementStats(records []EvaluationRecord, limit int) []ImprovementCategoryStat {
+	stats := map[string]*ImprovementCategoryStat{}
+	// 从最新的记录开始，示例建议优先取最近的
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		for _, category := range record.Categories {
+			stat := stats[category]
+			if stat == nil {
+				label, ok := improvementCategoryLabels[category]
+				if !ok {
+					label = category
+				}
+				stat = &ImprovementCategoryStat{Category: category, Label: label, Examples: []string{}}
+				stats[category] = stat
+			}
+			stat.Count++
+		}
+		for _, advice := range record.AIEvaluation.DetailedAdvice {
+			stat := stats[classifyAdvice(advice)]
+			if stat != nil && len(stat.Examples) < maxCategoryExamples && !slices.Contains(stat.Examples, advice) {
+				stat.Examples = append(stat.Examples, advice)
+			}
+		}
+	}
+
+	result := make([]ImprovementCategoryStat, 0, len(stats))
+	for _, stat := range stats {
+		stat.Share = float64(stat.Count) / float64(len(records))
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Category < result[j].Category
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// LanguagePairSummary 一个语言对上的评估汇总
+type LanguagePairSummary struct {
+	SourceLanguage string             `json:"source_language"`
+	TargetLanguage string             `json:"target_language"`
+	Count          int                `json:"count"`
+	AverageScore   float64            `json:"average_score"`
+	BestScore      float64            `json:"best_score"`
+	LatestScore    float64            `json:"latest_score"`
+	ScoreChange    float64            `json:"score_change"` // 最近几次与最早几次的平均分之差，正数表示进步
+	Levels         map[string]int     `json:"levels"`       // 各评级的次数
+	Dimensions     map[string]float64 `json:"dimensions"`   // 各维度的平均 AI 评分
+	Degraded       int                `json:"degraded"`     // AI 评估失败的次数
+	FirstAt        time.Time          `json:"first_at"`
+	LastAt         time.Time          `json:"last_at"`
+}
+
+// scoreChangeWindow 计算进步幅度时比较的最早和最近评估次数
+const scoreChangeWindow = 5
+
+// buildLanguagePairSummaries 按语言对汇总评估记录，评估次数多的语言对在前
+func buildLanguagePairSummaries(records []EvaluationRecord) []LanguagePairSummary {
+	type pairKey struct{ from, to string }
+	groups := map[pairKey][]EvaluationRecord{}
+	var order []pairKey
+	for _, record := range records {
+		key := pairKey{strings.ToLower(record.Request.SourceLanguage), strings.ToLower(record.Request.TargetLanguage)}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], record)
+	}
+
+	summaries := make([]LanguagePairSummary, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+		summary := LanguagePairSummary{
+			SourceLanguage: group[0].Request.SourceLanguage,
+			TargetLanguage: group[0].Request.TargetLanguage,
+			Count:          len(group),
+			LatestScore:    group[len(group)-1].Result.Score,
+			Levels:         map[string]int{},
+			Dimensions:     map[string]float64{},
+			FirstAt:        group[0].CreatedAt,
+			LastAt:         group[len(group)-1].CreatedAt,
+		}
+
+		var score mean
+		dimensions := map[string]*mean{}
+		for _, record := range group {
+			score.add(record.Result.Score)
+			summary.BestScore = max(summary.BestScore, record.Result.Score)
+			summary.Levels[record.Result.Level]++
+			if record.AIEvaluation.Degraded {
+				summary.Degraded++
+				continue
+			}
+			for dimension, value := range record.AIEvaluation.Scores {
+				if dimensions[dimension] == nil {
+					dimensions[dimension] = &mean{}
+				}
+				dimensions[dimension].add(value)
+			}
+		}
+		summary.AverageScore = score.value()
+		for dimension, m := range dimensions {
+			summary.Dimensions[dimension] = m.value()
+		}
+
+		if window := min(scoreChangeWindow, len(group)/2); window > 0 {
+			var first, last mean
+			for i := 0; i < window; i++ {
+				first.add(group[i].Result.Score)
+				last.add(group[len(group)-1-i].Result.Score)
+			}
+			summary.ScoreChange = last.value() - first.value()
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Count > summaries[j].Count
+	})
+	return summaries
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+const (
+	// defaultHistoryLimit 评估记录列表默认返回的条数
+	defaultHistoryLimit = 50
+	// defaultImprovementLimit 改进类别默认返回的个数
+	defaultImprovementLimit = 10
+)
+
+// parseHistoryTime 解析 RFC3339 时间或 2006-01-02 格式的日期（服务器本地时区）
+// endOfDay 为 true 时日期表示当天结束，用于包含整天的 to 参数
+func parseHistoryTime(name, value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 time or a date such as 2006-01-02", name)
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// bindHistoryFilter 读取 from / to / source_language / target_language / rubric 查询参数
+func bindHistoryFilter(c *gin.Context) (historyFilter, error) {
+	filter := historyFilter{
+		SourceLanguage: c.Query("source_language"),
+		TargetLanguage: c.Query("target_language"),
+		Rubric:         c.Query("rubric"),
+	}
+	var err error
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = parseHistoryTime("from", v, false); err != nil {
+			return filter, err
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = parseHistoryTime("to", v, true); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// queryLimit 读取正整数的 limit 参数
+func queryLimit(c *gin.Context, defaultLimit int) (int, error) {
+	v := c.Query("limit")
+	if v == "" {
+		return defaultLimit, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	return n, nil
+}
+
+// loadLearnerRecords 读取路径中学习者满足查询条件的评估记录，失败时已写入响应
+func loadLearnerRecords(c *gin.Context) ([]EvaluationRecord, bool) {
+	if GlobalEvaluationHistory == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Evaluation history is not available"})
+		return nil, false
+	}
+	learnerID := c.Param("learner_id")
+	filter, err := bindHistoryFilter(c)
+	if err != nil {
+		utils.AbortWithBadRequest(c, err, err.Error())
+		return nil, false
+	}
+
+	records, err := GlobalEvaluationHistory.List(learnerID, filter)
+	if errors.Is(err, ErrLearnerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "Learner not found",
+			"learner_id": learnerID,
+		})
+		return nil, false
+	}
+	if err != nil {
+		utils.AbortWithInternalServerError(c, err)
+		return nil, false
+	}
+	return records, true
+}
+
+// LearnerEvaluationsHandler 列出学习者的评估记录，最新的在前
+func LearnerEvaluationsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := queryLimit(c, defaultHistoryLimit)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+		records, ok := loadLearnerRecords(c)
+		if !ok {
+			return
+		}
+
+		total := len(records)
+		slices.Reverse(records)
+		if len(records) > limit {
+			records = records[:limit]
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":     true,
+			"learner_id":  c.Param("learner_id"),
+			"evaluations": records,
+			"total":       total,
+		})
+	}
+}
+
+// LearnerProgressHandler 返回学习者综合评分、相似度和各维度评分的时间序列
+// interval 为 evaluation（每次评估一个点，默认）、day 或 week
+func LearnerProgressHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		interval := c.DefaultQuery("interval", ProgressIntervalEvaluation)
+		switch interval {
+		case ProgressIntervalEvaluation, ProgressIntervalDay, ProgressIntervalWeek:
+		default:
+			utils.AbortWithBadRequest(c, nil, "interval must be evaluation, day or week")
+			return
+		}
+		records, ok := loadLearnerRecords(c)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":    true,
+			"learner_id": c.Param("learner_id"),
+			"total":      len(records),
+			"progress":   buildProgressReport(records, interval),
+		})
+	}
+}
+
+// LearnerImprovementsHandler 返回学习者最常出现的问题类别
+func LearnerImprovementsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := queryLimit(c, defaultImprovementLimit)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+		records, ok := loadLearnerRecords(c)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"learner_id":   c.Param("learner_id"),
+			"total":        len(records),
+			"improvements": buildImprovementStats(records, limit),
+		})
+	}
+}
+
+// LearnerSummaryHandler 按语言对汇总学习者的评估
+func LearnerSummaryHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		records, ok := loadLearnerRecords(c)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"learner_id":     c.Param("learner_id"),
+			"total":          len(records),
+			"language_pairs": buildLanguagePairSummaries(records),
+		})
+	}
+}
+
+// LearnerHistoryDeleteHandler 删除学习者的全部评估记录
+func LearnerHistoryDeleteHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GlobalEvaluationHistory == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Evaluation history is not available"})
+			return
+		}
+		learnerID := c.Param("learner_id")
+		deleted, err := GlobalEvaluationHistory.DeleteLearner(learnerID)
+		if errors.Is(err, ErrLearnerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":      "Learner not found",
+				"learner_id": learnerID,
+			})
+			return
+		}
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":    true,
+			"message":    "Evaluation history deleted",
+			"learner_id": learnerID,
+			"deleted":    deleted,
+		})
+	}
+}
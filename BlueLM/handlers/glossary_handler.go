@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// glossaryRequestBody 创建或替换术语表的请求体
+// 也可以用 multipart 表单上传：name / from / to 字段加 file（CSV 或 TSV，每行 原文术语,目标术语）
+type glossaryRequestBody struct {
+	Name    string          `json:"name"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Entries []GlossaryEntry `json:"entries"`
+}
+
+// requireGlossaryStore 术语表存储未初始化时返回 503
+func requireGlossaryStore(c *gin.Context) bool {
+	if GlobalGlossaryStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Glossary store is not available"})
+		return false
+	}
+	return true
+}
+
+// respondGlossaryError 将术语表存储的错误转换为响应
+func respondGlossaryError(c *gin.Context, glossaryID string, err error) {
+	if errors.Is(err, ErrGlossaryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":       "Glossary not found",
+			"glossary_id": glossaryID,
+		})
+		return
+	}
+	utils.AbortWithInternalServerError(c, err)
+}
+
+// bindGlossaryRequest 读取 JSON 或 multipart 表单形式的术语表
+func bindGlossaryRequest(c *gin.Context) (glossaryRequestBody, error) {
+	var body glossaryRequestBody
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			return body, fmt.Errorf("invalid request format: %v", err)
+		}
+	} else {
+		body.Name = c.PostForm("name")
+		body.From = c.PostForm("from")
+		body.To = c.PostForm("to")
+		file, err := c.FormFile("file")
+		if err != nil {
+			return body, fmt.Errorf("file is required for multipart upload")
+		}
+		f, err := file.Open()
+		if err != nil {
+			return body, err
+		}
+		defer f.Close()
+		if body.Entries, err = parseGlossaryFile(f, file.Filename); err != nil {
+			return body, err
+		}
+	}
+
+	entries, err := normalizeGlossaryEntries(body.Entries)
+	if err != nil {
+		return body, err
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	body.Entries = entries
+	return body, nil
+}
+
+// parseGlossaryFile 解析 CSV/TSV 术语文件，.tsv 文件或首行含制表符时按 TSV 解析
+// 首行为 source,target 表头时跳过
+func parseGlossaryFile(r io.Reader, filename string) ([]GlossaryEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff") // Excel 导出的 UTF-8 BOM
+
+	reader := csv.NewReader(strings.NewReader(content))
+	firstLine, _, _ := strings.Cut(content, "\n")
+	if strings.EqualFold(filepath.Ext(filename), ".tsv") || strings.Contains(firstLine, "\t") {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid glossary file: %v", err)
+	}
+	var entries []GlossaryEntry
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid glossary file: line %d needs a source and a target term", i+1)
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "source") && strings.EqualFold(strings.TrimSpace(record[1]), "target") {
+			continue
+		}
+		entries = append(entries, GlossaryEntry{Source: record[0], Target: record[1]})
+	}
+	return entries, nil
+}
+
+// validateGlossaryLanguages 术语表必须属于一个明确的语言对
+func validateGlossaryLanguages(from, to string) error {
+	if _, ok := supportedLanguages[from]; !ok || from == TRANSLATE_LANGUAGE_AUTO {
+		return fmt.Errorf("unsupported glossary source language: %q", from)
+	}
+	if _, ok := supportedLanguages[to]; !ok || to == TRANSLATE_LANGUAGE_AUTO {
+		return fmt.Errorf("unsupported glossary target language: %q", to)
+	}
+	if mapLanguage(from) == mapLanguage(to) {
+		return fmt.Errorf("glossary source and target languages must differ")
+	}
+	return nil
+}
+
+// loadRequestGlossary 读取请求指定的术语表并检查语言对，glossaryID 为空时返回 nil
+// 失败时返回对应的 HTTP 状态码
+func loadRequestGlossary(glossaryID, from, to string) (*Glossary, int, error) {
+	if glossaryID == "" {
+		return nil, http.StatusOK, nil
+	}
+	if GlobalGlossaryStore == nil {
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("glossary store is not available")
+	}
+	glossary, err := GlobalGlossaryStore.Get(glossaryID)
+	if errors.Is(err, ErrGlossaryNotFound) {
+		return nil, http.StatusNotFound, fmt.Errorf("glossary not found: %s", glossaryID)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if !glossary.supports(from, to) {
+		return nil, http.StatusBadRequest, fmt.Errorf("glossary %s is for %s -> %s", glossaryID, glossary.From, glossary.To)
+	}
+	return glossary, http.StatusOK, nil
+}
+
+// GlossaryCreateHandler 上传新术语表
+func GlossaryCreateHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireGlossaryStore(c) {
+			return
+		}
+		body, err := bindGlossaryRequest(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+		if body.Name == "" {
+			utils.AbortWithBadRequest(c, nil, "Name cannot be empty")
+			return
+		}
+		if err := validateGlossaryLanguages(body.From, body.To); err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+
+		glossary, err := GlobalGlossaryStore.Create(body.Name, body.From, body.To, body.Entries)
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"glossary": glossary,
+		})
+	}
+}
+
+// GlossaryListHandler 列出术语表，可按 from / to 过滤
+func GlossaryListHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireGlossaryStore(c) {
+			return
+		}
+		summaries, err := GlobalGlossaryStore.List()
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+
+		from, to := c.Query("from"), c.Query("to")
+		filtered := make([]GlossarySummary, 0, len(summaries))
+		for _, summary := range summaries {
+			if (from == "" || mapLanguage(summary.From) == mapLanguage(from)) && (to == "" || mapLanguage(summary.To) == mapLanguage(to)) {
+				filtered = append(filtered, summary)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":    true,
+			"glossaries": filtered,
+			"total":      len(filtered),
+		})
+	}
+}
+
+// GlossaryHandler 获取术语表及其全部词条
+func GlossaryHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireGlossaryStore(c) {
+			return
+		}
+		glossaryID := c.Param("glossary_id")
+		glossary, err := GlobalGlossaryStore.Get(glossaryID)
+		if err != nil {
+			respondGlossaryError(c, glossaryID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"glossary": glossary,
+		})
+	}
+}
+
+// GlossaryReplaceHandler 替换术语表的词条（name 可选），语言对创建后不可修改
+func GlossaryReplaceHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireGlossaryStore(c) {
+			return
+		}
+		body, err := bindGlossaryRequest(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+
+		glossaryID := c.Param("glossary_id")
+		glossary, err := GlobalGlossaryStore.Replace(glossaryID, body.Name, body.Entries)
+		if err != nil {
+			respondGlossaryError(c, glossaryID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"glossary": glossary,
+		})
+	}
+}
+
+// GlossaryDeleteHandler 删除术语表
+func GlossaryDeleteHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireGlossaryStore(c) {
+			return
+		}
+		glossaryID := c.Param("glossary_id")
+		if err := GlobalGlossaryStore.Delete(glossaryID); err != nil {
+			respondGlossaryError(c, glossaryID, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":     true,
+			"message":     "Glossary deleted",
+			"glossary_id": glossaryID,
+		})
+	}
+}
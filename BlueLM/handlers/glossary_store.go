@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// glossariesBucket 用户术语表，键为术语表ID
+var glossariesBucket = []byte("glossaries")
+
+// maxGlossaryEntries 单个术语表的最大词条数
+const maxGlossaryEntries = 2000
+
+// ErrGlossaryNotFound 术语表不存在
+var ErrGlossaryNotFound = errors.New("glossary not found")
+
+// GlossaryEntry 一条术语：原文中出现 Source 时译文必须使用 Target
+type GlossaryEntry struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Glossary 一个语言对的命名术语表
+type Glossary struct {
+	GlossaryID string          `json:"glossary_id"`
+	Name       string          `json:"name"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Entries    []GlossaryEntry `json:"entries"`
+
+	matcher *regexp.Regexp // 按最长优先匹配所有原文术语，由 compile 生成
+}
+
+// GlossarySummary 术语表列表中的摘要信息
+type GlossarySummary struct {
+	GlossaryID string    `json:"glossary_id"`
+	Name       string    `json:"name"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	EntryCount int       `json:"entry_count"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GlossaryTermUsage 一次翻译中命中的术语及其是否出现在译文中
+type GlossaryTermUsage struct {
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Applied bool   `json:"applied"`
+}
+
+// GlossaryStore 基于 BoltDB 的术语表存储
+type GlossaryStore struct {
+	db *bolt.DB
+}
+
+// GlobalGlossaryStore 全局术语表存储，未初始化时不支持 glossary_id
+var GlobalGlossaryStore *GlossaryStore
+
+// InitGlossaryStore 初始化术语表存储
+func InitGlossaryStore(db *bolt.DB) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(glossariesBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init glossary store: %v", err)
+	}
+
+	GlobalGlossaryStore = &GlossaryStore{db: db}
+	return nil
+}
+
+// Create 保存新术语表
+func (s *GlossaryStore) Create(name, from, to string, entries []GlossaryEntry) (*Glossary, error) {
+	now := time.Now()
+	glossary := &Glossary{
+		GlossaryID: ai.NewSessionID(),
+		Name:       name,
+		From:       from,
+		To:         to,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Entries:    entries,
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return saveGlossary(tx, glossary)
+	})
+	return glossary, err
+}
+
+// Replace 替换术语表的名称和词条，语言对不变
+func (s *GlossaryStore) Replace(glossaryID, name string, entries []GlossaryEntry) (*Glossary, error) {
+	var glossary *Glossary
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		glossary, err = loadGlossary(tx, glossaryID)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			glossary.Name = name
+		}
+		glossary.Entries = entries
+		glossary.UpdatedAt = time.Now()
+		return saveGlossary(tx, glossary)
+	})
+	return glossary, err
+}
+
+// Get 获取术语表
+func (s *GlossaryStore) Get(glossaryID string) (*Glossary, error) {
+	var glossary *Glossary
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		glossary, err = loadGlossary(tx, glossaryID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := glossary.compile(); err != nil {
+		return nil, err
+	}
+	return glossary, nil
+}
+
+// List 列出术语表，最近更新的在前
+func (s *GlossaryStore) List() ([]GlossarySummary, error) {
+	summaries := []GlossarySummary{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(glossariesBucket).ForEach(func(k, v []byte) error {
+			var glossary Glossary
+			if err := json.Unmarshal(v, &glossary); err != nil {
+				utils.Log.Warnf("Skipping corrupted glossary %s: %v", string(k), err)
+				return nil
+			}
+			summaries = append(summaries, glossary.summary())
+			return nil
+		})
+	})
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, err
+}
+
+// Delete 删除术语表
+func (s *GlossaryStore) Delete(glossaryID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(glossariesBucket)
+		if bucket.Get([]byte(glossaryID)) == nil {
+			return ErrGlossaryNotFound
+		}
+		return bucket.Delete([]byte(glossaryID))
+	})
+}
+
+func loadGlossary(tx *bolt.Tx, glossaryID string) (*Glossary, error) {
+	v := tx.Bucket(glossariesBucket).Get([]byte(glossaryID))
+	if v == nil {
+		return nil, ErrGlossaryNotFound
+	}
+	var glossary Glossary
+	if err := json.Unmarshal(v, &glossary); err != nil {
+		return nil, fmt.Errorf("corrupted glossary %s: %v", glossaryID, err)
+	}
+	return &glossary, nil
+}
+
+func saveGlossary(tx *bolt.Tx, glossary *Glossary) error {
+	data, err := json.Marshal(glossary)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(glossariesBucket).Put([]byte(glossary.GlossaryID), data)
+}
+
+// summary 生成术语表摘要
+func (g *Glossary) summary() GlossarySummary {
+	return GlossarySummary{
+		GlossaryID: g.GlossaryID,
+		Name:       g.Name,
+		From:       g.From,
+		To:         g.To,
+		EntryCount: len(g.Entries),
+		UpdatedAt:  g.UpdatedAt,
+	}
+}
+
+// normalizeGlossaryEntries 去除空白并校验词条，同一原文术语（不区分大小写）只能出现一次
+func normalizeGlossaryEntries(entries []GlossaryEntry) ([]GlossaryEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("glossary entries cannot be empty")
+	}
+	if len(entries) > maxGlossaryEntries {
+		return nil, fmt.Errorf("too many glossary entries: %d (max %d)", len(entries), maxGlossaryEntries)
+	}
+
+	normalized := make([]GlossaryEntry, 0, len(entries))
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		source := strings.Join(strings.Fields(entry.Source), " ")
+		target := strings.TrimSpace(entry.Target)
+		if source == "" || target == "" {
+			return nil, fmt.Errorf("entries[%d]: source and target are required", i)
+		}
+		key := strings.ToLower(source)
+		if seen[key] {
+			return nil, fmt.Errorf("entries[%d]: duplicate source term %q", i, source)
+		}
+		seen[key] = true
+		normalized = append(normalized, GlossaryEntry{Source: source, Target: target})
+	}
+	return normalized, nil
+}
+
+// compile 生成原文术语的匹配表达式
+// 长术语优先匹配；以字母或数字开头/结尾的术语按单词边界匹配，避免 "art" 命中 "start"；不区分大小写
+func (g *Glossary) compile() error {
+	sources := make([]string, len(g.Entries))
+	for i, entry := range g.Entries {
+		sources[i] = entry.Source
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return utf8.RuneCountInString(sources[i]) > utf8.RuneCountInString(sources[j])
+	})
+
+	patterns := make([]string, len(sources))
+	for i, source := range sources {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(source), " ", `\s+`)
+		if first, _ := utf8.DecodeRuneInString(source); isASCIIWordRune(first) {
+			pattern = `\b` + pattern
+		}
+		if last, _ := utf8.DecodeLastRuneInString(source); isASCIIWordRune(last) {
+			pattern += `\b`
+		}
+		patterns[i] = pattern
+	}
+
+	matcher, err := regexp.Compile(`(?i)(?:` + strings.Join(patterns, "|") + `)`)
+	if err != nil {
+		return fmt.Errorf("invalid glossary %s: %v", g.GlossaryID, err)
+	}
+	g.matcher = matcher
+	return nil
+}
+
+func isASCIIWordRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// entryFor 返回匹配文本对应的词条
+func (g *Glossary) entryFor(match string) (GlossaryEntry, bool) {
+	match = strings.Join(strings.Fields(match), " ")
+	for _, entry := range g.Entries {
+		if strings.EqualFold(entry.Source, match) {
+			return entry, true
+		}
+	}
+	return GlossaryEntry{}, false
+}
+
+// Matches 返回文本中出现的术语，每个词条只返回一次，按首次出现的顺序排列
+func (g *Glossary) Matches(text string) []GlossaryEntry {
+	var matches []GlossaryEntry
+	seen := make(map[string]bool)
+	for _, match := range g.matcher.FindAllString(text, -1) {
+		entry, ok := g.entryFor(match)
+		if !ok || seen[entry.Source] {
+			continue
+		}
+		seen[entry.Source] = true
+		matches = append(matches, entry)
+	}
+	return matches
+}
+
+// glossaryPlaceholder 翻译前替换术语的占位符，翻译后端需原样保留
+var glossaryPlaceholder = regexp.MustCompile(`\{\{\s*G\s*(\d+)\s*\}\}`)
+
+// protect 将原文中的术语替换为占位符 {{G<n>}}，返回替换后的文本和命中的术语
+func (g *Glossary) protect(text string) (string, []GlossaryTermUsage) {
+	var terms []GlossaryTermUsage
+	index := make(map[string]int)
+	protected := g.matcher.ReplaceAllStringFunc(text, func(match string) string {
+		entry, ok := g.entryFor(match)
+		if !ok {
+			return match
+		}
+		n, exists := index[entry.Source]
+		if !exists {
+			n = len(terms)
+			index[entry.Source] = n
+			terms = append(terms, GlossaryTermUsage{Source: entry.Source, Target: entry.Target})
+		}
+		return "{{G" + strconv.Itoa(n) + "}}"
+	})
+	return protected, terms
+}
+
+// restoreGlossaryTerms 将译文中的占位符替换为目标术语
+// 后端丢失占位符时检查译文中是否已包含目标术语，据此设置 Applied
+func restoreGlossaryTerms(translation string, terms []GlossaryTermUsage) string {
+	restored := glossaryPlaceholder.ReplaceAllStringFunc(translation, func(placeholder string) string {
+		n, err := strconv.Atoi(glossaryPlaceholder.FindStringSubmatch(placeholder)[1])
+		if err != nil || n >= len(terms) {
+			return placeholder
+		}
+		terms[n].Applied = true
+		return terms[n].Target
+	})
+	for i := range terms {
+		if !terms[i].Applied {
+			terms[i].Applied = containsFold(restored, terms[i].Target)
+		}
+	}
+	return restored
+}
+
+// glossaryViolations 返回原文中出现、但译文未使用规定译法的术语
+func (g *Glossary) glossaryViolations(originalText, translation string) []GlossaryEntry {
+	var violations []GlossaryEntry
+	for _, entry := range g.Matches(originalText) {
+		if !containsFold(translation, entry.Target) {
+			violations = append(violations, entry)
+		}
+	}
+	return violations
+}
+
+// supports 判断术语表是否适用于请求的语言对，未指定或自动检测的语言不做比较
+func (g *Glossary) supports(from, to string) bool {
+	if to != "" && mapLanguage(g.To) != mapLanguage(to) {
+		return false
+	}
+	return from == "" || from == TRANSLATE_LANGUAGE_AUTO || mapLanguage(g.From) == mapLanguage(from)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+)
+
+func newTestGlossary(t *testing.T, entries ...GlossaryEntry) *Glossary {
+	t.Helper()
+	g := &Glossary{GlossaryID: "test", From: "en", To: "zh", Entries: entries}
+	if err := g.compile(); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGlossaryProtect(t *testing.T) {
+	g := newTestGlossary(t,
+		GlossaryEntry{Source: "learning", Target: "学习"},
+		GlossaryEntry{Source: "machine learning", Target: "机器学习"},
+		GlossaryEntry{Source: "AI", Target: "人工智能"},
+		GlossaryEntry{Source: "art", Target: "艺术"},
+		GlossaryEntry{Source: "C++", Target: "C++语言"},
+	)
+	tests := []struct {
+		name      string
+		text      string
+		want      string
+		wantTerms []string
+	}{
+		{"no terms", "hello world", "hello world", nil},
+		{"longest match first", "Machine  learning and learning", "{{G0}} and {{G1}}", []string{"machine learning", "learning"}},
+		{"word boundary", "AI art, start AI", "{{G0}} {{G1}}, start {{G0}}", []string{"AI", "art"}},
+		{"case insensitive", "ai ART", "{{G0}} {{G1}}", []string{"AI", "art"}},
+		{"symbol term", "I like C++.", "I like {{G0}}.", []string{"C++"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, terms := g.protect(tt.text)
+			if got != tt.want {
+				t.Errorf("protect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			var sources []string
+			for _, term := range terms {
+				sources = append(sources, term.Source)
+				if term.Applied {
+					t.Errorf("term %q should not be applied before restore", term.Source)
+				}
+			}
+			if !slices.Equal(sources, tt.wantTerms) {
+				t.Errorf("protect(%q) terms = %q, want %q", tt.text, sources, tt.wantTerms)
+			}
+		})
+	}
+}
+
+func TestRestoreGlossaryTerms(t *testing.T) {
+	tests := []struct {
+		name        string
+		translation string
+		want        string
+		wantApplied []bool
+	}{
+		{"placeholders", "{{G0}}和{{G1}}", "机器学习和人工智能", []bool{true, true}},
+		{"spaced placeholder", "{{ G1 }}很好", "人工智能很好", []bool{false, true}},
+		{"out of range kept", "{{G5}}{{G0}}", "{{G5}}机器学习", []bool{true, false}},
+		{"placeholder lost but target present", "人工智能与机器学习", "人工智能与机器学习", []bool{true, true}},
+		{"placeholder lost", "其他内容", "其他内容", []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms := []GlossaryTermUsage{
+				{Source: "machine learning", Target: "机器学习"},
+				{Source: "AI", Target: "人工智能"},
+			}
+			got := restoreGlossaryTerms(tt.translation, terms)
+			if got != tt.want {
+				t.Errorf("restoreGlossaryTerms(%q) = %q, want %q", tt.translation, got, tt.want)
+			}
+			for i, term := range terms {
+				if term.Applied != tt.wantApplied[i] {
+					t.Errorf("terms[%d].Applied = %v, want %v", i, term.Applied, tt.wantApplied[i])
+				}
+			}
+		})
+	}
+}
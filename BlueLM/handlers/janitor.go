@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/audio"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 清理的文件类别
+const (
+	ArtifactUploads       = "uploads"
+	ArtifactTTSTemp       = "tts_temp"
+	ArtifactTranscription = "transcription"
+	ArtifactWhisperX      = "whisperx"
+	ArtifactBilingual     = "bilingual"
+	ArtifactEvaluation    = "evaluation"
+	ArtifactTTS           = "tts"
+)
+
+// artifact 上传或下载目录中的一个可清理文件（或 WhisperX 输出目录）
+type artifact struct {
+	Kind    string
+	Path    string
+	TaskID  string // 关联的任务，为空表示不属于任何任务
+	Size    int64
+	ModTime time.Time
+}
+
+// JanitorKindReport 单个文件类别的清理统计
+type JanitorKindReport struct {
+	Files   int   `json:"files"`
+	Bytes   int64 `json:"bytes"`
+	Skipped int   `json:"skipped"` // 因关联任务未结束（容量淘汰时还包括过新的文件）而跳过的文件数
+}
+
+// JanitorReport 一次清理的结果
+type JanitorReport struct {
+	Trigger        string                        `json:"trigger"` // schedule 或 manual
+	StartedAt      time.Time                     `json:"started_at"`
+	FinishedAt     time.Time                     `json:"finished_at"`
+	Retention      map[string]*JanitorKindReport `json:"retention"`
+	QuotaEvicted   JanitorKindReport             `json:"quota_evicted"`
+	TasksRemoved   []string                      `json:"tasks_removed"`
+	ReclaimedBytes int64                         `json:"reclaimed_bytes"`
+	DiskUsageBytes int64                         `json:"disk_usage_bytes"` // 清理后的占用
+	Errors         []string                      `json:"errors,omitempty"`
+}
+
+// janitor 周期性清理上传和下载目录
+// 与任务注册表协同：未结束任务的文件不会被删除；删除任务结果前先删除任务记录，
+// 保证客户端不会看到一个结果文件已经丢失的已完成任务
+type janitor struct {
+	mu         sync.Mutex // 保证同一时间只有一次清理在运行
+	cfg        *config.Config
+	lastReport *JanitorReport
+}
+
+var globalJanitor *janitor
+
+// StartJanitor 启动后台清理协程
+func StartJanitor(cfg *config.Config) {
+	globalJanitor = &janitor{cfg: cfg}
+	if !cfg.Retention.Enabled {
+		utils.Log.Infof("Janitor disabled by config")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Retention.Interval)
+		defer ticker.Stop()
+		for {
+			globalJanitor.run("schedule")
+			<-ticker.C
+		}
+	}()
+	utils.Log.Infof("Janitor started, interval %s", cfg.Retention.Interval)
+}
+
+// retentionFor 返回文件类别的保留时长
+func (j *janitor) retentionFor(kind string) time.Duration {
+	switch kind {
+	case ArtifactUploads:
+		return j.cfg.Retention.Uploads
+	case ArtifactTTSTemp:
+		return j.cfg.Retention.TTSTemp
+	case ArtifactTranscription:
+		return j.cfg.Retention.Transcription
+	case ArtifactWhisperX:
+		return j.cfg.Retention.WhisperX
+	case ArtifactBilingual:
+		return j.cfg.Retention.Bilingual
+	case ArtifactEvaluation:
+		return j.cfg.Retention.Evaluation
+	case ArtifactTTS:
+		return j.cfg.Retention.TTS
+	}
+	return 0
+}
+
+// run 执行一次清理：先按保留时长清理，再按容量上限从最旧的文件开始淘汰
+func (j *janitor) run(trigger string) *JanitorReport {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report := &JanitorReport{
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+		Retention: map[string]*JanitorKindReport{
+			ArtifactUploads:       {},
+			ArtifactTTSTemp:       {},
+			ArtifactTranscription: {},
+			ArtifactWhisperX:      {},
+			ArtifactBilingual:     {},
+			ArtifactEvaluation:    {},
+			ArtifactTTS:           {},
+		},
+		TasksRemoved: []string{},
+	}
+
+	artifacts := j.scan(report)
+	activeUploads := make(map[string]bool)
+	for _, task := range GlobalTaskManager.GetAllTasks() {
+		if task.UploadPath != "" && !task.Status.IsFinished() {
+			activeUploads[filepath.Clean(task.UploadPath)] = true
+		}
+	}
+
+	// 1. 按保留时长清理
+	remaining := make([]artifact, 0, len(artifacts))
+	for _, a := range artifacts {
+		kindReport := report.Retention[a.Kind]
+		if j.isProtected(a, activeUploads) {
+			kindReport.Skipped++
+			remaining = append(remaining, a)
+			continue
+		}
+
+		retention := j.retentionFor(a.Kind)
+		if retention <= 0 || time.Since(j.artifactTime(a)) < retention {
+			remaining = append(remaining, a)
+			continue
+		}
+
+		if j.remove(a, report) {
+			kindReport.Files++
+			kindReport.Bytes += a.Size
+		}
+	}
+
+	// 2. 按容量上限淘汰最旧的文件
+	// 不淘汰修改时间在一个清理周期内的文件：上传文件在任务登记 UploadPath 之前就已写入，
+	// 此时无法通过任务判断其是否仍在使用
+	minAge := j.cfg.Retention.Interval
+	var usage int64
+	for _, a := range remaining {
+		usage += a.Size
+	}
+	quota := j.cfg.Retention.MaxDiskMB * 1024 * 1024
+	if quota > 0 && usage > quota {
+		sort.Slice(remaining, func(i, k int) bool {
+			return j.artifactTime(remaining[i]).Before(j.artifactTime(remaining[k]))
+		})
+		for _, a := range remaining {
+			if usage <= quota {
+				break
+			}
+			if j.isProtected(a, activeUploads) || time.Since(a.ModTime) < minAge {
+				report.QuotaEvicted.Skipped++
+				continue
+			}
+			if j.remove(a, report) {
+				report.QuotaEvicted.Files++
+				report.QuotaEvicted.Bytes += a.Size
+				usage -= a.Size
+			}
+		}
+		if usage > quota {
+			utils.Log.Warnf("Janitor: disk usage %d bytes still exceeds quota %d bytes, remaining files belong to running tasks or are newer than %s", usage, quota, minAge)
+		}
+	}
+
+	for _, kindReport := range report.Retention {
+		report.ReclaimedBytes += kindReport.Bytes
+	}
+	report.ReclaimedBytes += report.QuotaEvicted.Bytes
+	report.DiskUsageBytes = usage
+	report.FinishedAt = time.Now()
+	j.lastReport = report
+
+	utils.Log.Infof("Janitor (%s) reclaimed %d bytes, removed %d tasks, disk usage %d bytes",
+		trigger, report.ReclaimedBytes, len(report.TasksRemoved), report.DiskUsageBytes)
+	return report
+}
+
+// scan 列出上传和下载目录中所有可清理的文件
+func (j *janitor) scan(report *JanitorReport) []artifact {
+	var artifacts []artifact
+
+	uploadDir := j.cfg.FilePaths.UploadDir
+	entries, err := os.ReadDir(uploadDir)
+	if err != nil {
+		report.Errors = append(report.Errors, "read upload dir: "+err.Error())
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if a, ok := newArtifact(ArtifactUploads, filepath.Join(uploadDir, entry.Name()), ""); ok {
+			artifacts = append(artifacts, a)
+		}
+	}
+
+	downloadDir := j.cfg.FilePaths.DownloadDir
+	entries, err = os.ReadDir(downloadDir)
+	if err != nil {
+		report.Errors = append(report.Errors, "read download dir: "+err.Error())
+	}
+	for _, entry := range entries {
+		kind, taskID := classifyDownload(entry)
+		if kind == "" {
+			continue
+		}
+		if a, ok := newArtifact(kind, filepath.Join(downloadDir, entry.Name()), taskID); ok {
+			artifacts = append(artifacts, a)
+		}
+	}
+
+	return artifacts
+}
+
+// classifyDownload 根据文件名识别下载目录中的文件类别及其关联任务，无法识别的文件不做处理
+func classifyDownload(entry os.DirEntry) (string, string) {
+	name := entry.Name()
+	if entry.IsDir() {
+		// WhisperX 服务为每个任务创建的输出目录
+		if strings.HasPrefix(name, "whisperx_") {
+			return ArtifactWhisperX, strings.TrimPrefix(name, "whisperx_")
+		}
+		return "", ""
+	}
+
+	switch {
+	case strings.HasPrefix(name, "temp_") && strings.HasSuffix(name, ".wav"):
+		return ArtifactTTSTemp, ""
+	case strings.HasPrefix(name, "transcription_") && strings.HasSuffix(name, ".json"):
+		return ArtifactTranscription, strings.TrimSuffix(strings.TrimPrefix(name, "transcription_"), ".json")
+	case strings.HasPrefix(name, "whisperx_result_") && strings.HasSuffix(name, ".json"):
+		return ArtifactWhisperX, strings.TrimSuffix(strings.TrimPrefix(name, "whisperx_result_"), ".json")
+	case strings.HasPrefix(name, "bilingual_") && strings.HasSuffix(name, ".json"):
+		return ArtifactBilingual, strings.TrimSuffix(strings.TrimPrefix(name, "bilingual_"), ".json")
+	case strings.HasPrefix(name, "evaluation_") && strings.HasSuffix(name, ".json"):
+		return ArtifactEvaluation, strings.TrimSuffix(strings.TrimPrefix(name, "evaluation_"), ".json")
+	case strings.HasPrefix(name, "tts_") && audio.ContentType(strings.TrimPrefix(filepath.Ext(name), ".")) != "":
+		return ArtifactTTS, strings.TrimSuffix(strings.TrimPrefix(name, "tts_"), filepath.Ext(name))
+	}
+	return "", ""
+}
+
+// newArtifact 读取文件（或目录）的大小和修改时间
+func newArtifact(kind, path, taskID string) (artifact, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return artifact{}, false
+	}
+
+	a := artifact{Kind: kind, Path: path, TaskID: taskID, Size: info.Size(), ModTime: info.ModTime()}
+	if info.IsDir() {
+		a.Size = 0
+		filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+			if err == nil && !fi.IsDir() {
+				a.Size += fi.Size()
+			}
+			return nil
+		})
+	}
+	return a, true
+}
+
+// isProtected 判断文件是否属于仍在进行中的任务
+func (j *janitor) isProtected(a artifact, activeUploads map[string]bool) bool {
+	if a.Kind == ArtifactUploads {
+		return activeUploads[filepath.Clean(a.Path)]
+	}
+	if a.TaskID == "" {
+		return false
+	}
+	task, exists := GlobalTaskManager.GetTask(a.TaskID)
+	return exists && !task.Status.IsFinished()
+}
+
+// artifactTime 计算文件的年龄起点：关联任务仍可见时以任务最后一次变更为准
+func (j *janitor) artifactTime(a artifact) time.Time {
+	if a.TaskID != "" {
+		if task, exists := GlobalTaskManager.GetTask(a.TaskID); exists {
+			return task.lastActivity()
+		}
+	}
+	return a.ModTime
+}
+
+// remove 删除文件，关联任务仍可见时先删除任务记录
+func (j *janitor) remove(a artifact, report *JanitorReport) bool {
+	if a.TaskID != "" && GlobalTaskManager.DeleteTask(a.TaskID) {
+		report.TasksRemoved = append(report.TasksRemoved, a.TaskID)
+	}
+
+	if err := os.RemoveAll(a.Path); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		utils.Log.Warnf("Janitor failed to remove %s: %v", a.Path, err)
+		return false
+	}
+	return true
+}
+
+// JanitorStatusHandler 返回清理配置和最近一次清理的结果
+func JanitorStatusHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var lastReport *JanitorReport
+		if globalJanitor != nil {
+			globalJanitor.mu.Lock()
+			lastReport = globalJanitor.lastReport
+			globalJanitor.mu.Unlock()
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"config": gin.H{
+				"enabled":       cfg.Retention.Enabled,
+				"interval":      cfg.Retention.Interval.String(),
+				"uploads":       cfg.Retention.Uploads.String(),
+				"tts_temp":      cfg.Retention.TTSTemp.String(),
+				"transcription": cfg.Retention.Transcription.String(),
+				"whisperx":      cfg.Retention.WhisperX.String(),
+				"bilingual":     cfg.Retention.Bilingual.String(),
+				"evaluation":    cfg.Retention.Evaluation.String(),
+				"tts":           cfg.Retention.TTS.String(),
+				"max_disk_mb":   cfg.Retention.MaxDiskMB,
+			},
+			"last_report": lastReport,
+		})
+	}
+}
+
+// JanitorRunHandler 立即执行一次清理并返回结果
+func JanitorRunHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if globalJanitor == nil {
+			globalJanitor = &janitor{cfg: cfg}
+		}
+		report := globalJanitor.run("manual")
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"report":  report,
+		})
+	}
+}
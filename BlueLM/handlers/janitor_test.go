@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyDownload(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      bool
+		wantKind string
+		wantTask string
+	}{
+		{"whisperx_task1", true, ArtifactWhisperX, "task1"},
+		{"other_dir", true, "", ""},
+		{"transcription_task2.json", true, "", ""},
+		{"temp_123.wav", false, ArtifactTTSTemp, ""},
+		{"temp_123.mp3", false, "", ""},
+		{"transcription_task2.json", false, ArtifactTranscription, "task2"},
+		{"transcription_task2.txt", false, "", ""},
+		{"whisperx_result_task3.json", false, ArtifactWhisperX, "task3"},
+		{"bilingual_task4.json", false, ArtifactBilingual, "task4"},
+		{"evaluation_task5.json", false, ArtifactEvaluation, "task5"},
+		{"tts_task6.wav", false, ArtifactTTS, "task6"},
+		{"tts_task6.flac", false, ArtifactTTS, "task6"},
+		{"tts_task6.pcm", false, ArtifactTTS, "task6"},
+		{"tts_task6.mp3", false, "", ""},
+		{"notes.txt", false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, tt.name)
+			var err error
+			if tt.dir {
+				err = os.Mkdir(path, 0755)
+			} else {
+				err = os.WriteFile(path, nil, 0644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := os.ReadDir(root)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("ReadDir() = %v, %v", entries, err)
+			}
+
+			kind, taskID := classifyDownload(entries[0])
+			if kind != tt.wantKind || taskID != tt.wantTask {
+				t.Errorf("classifyDownload(%s) = %q, %q; want %q, %q", tt.name, kind, taskID, tt.wantKind, tt.wantTask)
+			}
+		})
+	}
+}
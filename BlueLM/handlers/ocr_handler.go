@@ -4,18 +4,19 @@ import (
 	"encoding/base64"
 	"net/http"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 )
 
 // OCR请求结构体
 type OCRRequest struct {
-	Image  string `json:"image" binding:"required"` // base64编码的图片
-	Mode   int    `json:"mode"`                     // OCR模式，默认为0（仅返回文字）
-	AppID  string `json:"app_id,omitempty"`         // vivo AI AppID
-	AppKey string `json:"app_key,omitempty"`        // vivo AI AppKey
+	Image   string `json:"image" binding:"required"` // base64编码的图片
+	Mode    int    `json:"mode"`                     // OCR模式，默认为0（仅返回文字）
+	AppID   string `json:"app_id,omitempty"`         // vivo AI AppID
+	AppKey  string `json:"app_key,omitempty"`        // vivo AI AppKey
+	Profile string `json:"profile,omitempty"`        // 使用 vivo_ai.profiles 中的凭据档案
 }
 
 // OCR响应结构体
@@ -26,7 +27,7 @@ type OCRResponse struct {
 }
 
 // OCRHandler 处理OCR识别请求
-func OCRHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+func OCRHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		utils.Log.Info("OCR Handler called")
 
@@ -49,36 +50,13 @@ func OCRHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// 获取AppID和AppKey，优先使用前端传来的参数
-		var appID, appKey string
-		var vivoApp *vivo.Vivo
-
-		if req.AppID != "" && req.AppKey != "" {
-			// 使用前端传来的AppID和AppKey创建新的vivo实例
-			appID = req.AppID
-			appKey = req.AppKey
-			utils.Log.Info("使用前端传来的vivo AI凭据")
-			vivoApp = vivo.NewVivoAIGC(vivo.Config{
-				AppID:  appID,
-				AppKey: appKey,
-			})
-		} else {
-			// 使用默认的vivo实例
-			appID = cfg.VivoAI.AppID
-			appKey = cfg.VivoAI.AppKey
-			utils.Log.Info("使用配置文件中的vivo AI凭据")
-			vivoApp = app
-		}
-
-		if appID == "" || appKey == "" {
-			utils.Log.WithFields(map[string]interface{}{
-				"has_app_id":    appID != "",
-				"has_app_key":   appKey != "",
-				"from_frontend": req.AppID != "",
-			}).Error("蓝心大模型配置缺失")
+		// 按凭据查找顺序获取提供方，优先使用前端传来的参数
+		ocrApp, err := resolver.Resolve(ai.Credentials{AppID: req.AppID, AppKey: req.AppKey, Profile: req.Profile})
+		if err != nil {
+			utils.Log.WithError(err).WithField("from_frontend", req.AppID != "").Error("蓝心大模型配置缺失")
 			c.JSON(http.StatusBadRequest, OCRResponse{
 				Success: false,
-				Message: "缺少vivo AI凭据，请在前端设置页面配置AppID和AppKey",
+				Message: "缺少vivo AI凭据，请在前端设置页面配置AppID和AppKey: " + err.Error(),
 			})
 			return
 		}
@@ -94,8 +72,7 @@ func OCRHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// 使用vivo包的OCR方法
-		result, err := vivoApp.OCR(imageData, req.Mode)
+		result, err := ocrApp.OCR(imageData, req.Mode)
 		if err != nil {
 			utils.Log.WithError(err).Error("OCR识别失败")
 			c.JSON(http.StatusInternalServerError, OCRResponse{
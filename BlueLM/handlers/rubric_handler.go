@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// rubricRequestBody 创建或替换评分标准的请求体，替换时以路径中的名称为准
+type rubricRequestBody struct {
+	Name string `json:"name"`
+	config.RubricConfig
+}
+
+// requireRubricStore 评分标准存储未初始化时返回 503
+func requireRubricStore(c *gin.Context) bool {
+	if GlobalRubricStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Rubric store is not available"})
+		return false
+	}
+	return true
+}
+
+// respondRubricError 将评分标准存储的错误转换为响应
+func respondRubricError(c *gin.Context, name string, err error) {
+	switch {
+	case errors.Is(err, ErrRubricNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":  "Rubric not found",
+			"rubric": name,
+		})
+	case errors.Is(err, ErrRubricExists), errors.Is(err, ErrRubricReadOnly):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  err.Error(),
+			"rubric": name,
+		})
+	default:
+		utils.AbortWithInternalServerError(c, err)
+	}
+}
+
+// bindRubricRequest 读取并校验评分标准
+func bindRubricRequest(c *gin.Context) (rubricRequestBody, error) {
+	var body rubricRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return body, fmt.Errorf("invalid request format: %v", err)
+	}
+	if err := normalizeRubric(&body.RubricConfig); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// loadRequestRubric 读取请求指定的评分标准，name 为空时使用默认评分标准
+// 失败时返回对应的 HTTP 状态码
+func loadRequestRubric(name string) (*Rubric, int, error) {
+	if GlobalRubricStore == nil {
+		if name == "" || name == StandardRubricName {
+			return &Rubric{Name: StandardRubricName, Source: RubricSourceBuiltin, RubricConfig: standardRubric()}, http.StatusOK, nil
+		}
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("rubric store is not available")
+	}
+	if name == "" {
+		return GlobalRubricStore.Default(), http.StatusOK, nil
+	}
+	rubric, err := GlobalRubricStore.Get(name)
+	if errors.Is(err, ErrRubricNotFound) {
+		return nil, http.StatusNotFound, fmt.Errorf("rubric not found: %s", name)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return rubric, http.StatusOK, nil
+}
+
+// RubricCreateHandler 创建评分标准
+func RubricCreateHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireRubricStore(c) {
+			return
+		}
+		body, err := bindRubricRequest(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+		if !rubricNamePattern.MatchString(body.Name) {
+			utils.AbortWithBadRequest(c, nil, "Name must be 1-64 lowercase letters, digits, '-' or '_'")
+			return
+		}
+
+		rubric, err := GlobalRubricStore.Create(body.Name, body.RubricConfig)
+		if err != nil {
+			respondRubricError(c, body.Name, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"rubric":  rubric,
+		})
+	}
+}
+
+// RubricListHandler 列出全部评分标准
+func RubricListHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireRubricStore(c) {
+			return
+		}
+		rubrics, err := GlobalRubricStore.List()
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"default": GlobalRubricStore.Default().Name,
+			"rubrics": rubrics,
+			"total":   len(rubrics),
+		})
+	}
+}
+
+// RubricHandler 获取评分标准
+func RubricHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireRubricStore(c) {
+			return
+		}
+		name := c.Param("name")
+		rubric, err := GlobalRubricStore.Get(name)
+		if err != nil {
+			respondRubricError(c, name, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"rubric":  rubric,
+		})
+	}
+}
+
+// RubricReplaceHandler 替换接口创建的评分标准
+func RubricReplaceHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireRubricStore(c) {
+			return
+		}
+		body, err := bindRubricRequest(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+
+		name := c.Param("name")
+		rubric, err := GlobalRubricStore.Replace(name, body.RubricConfig)
+		if err != nil {
+			respondRubricError(c, name, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"rubric":  rubric,
+		})
+	}
+}
+
+// RubricDeleteHandler 删除接口创建的评分标准
+func RubricDeleteHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireRubricStore(c) {
+			return
+		}
+		name := c.Param("name")
+		if err := GlobalRubricStore.Delete(name); err != nil {
+			respondRubricError(c, name, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Rubric deleted",
+			"rubric":  name,
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// rubricsBucket 通过接口创建的评分标准，键为名称
+var rubricsBucket = []byte("rubrics")
+
+// StandardRubricName 内置评分标准的名称，配置文件中同名的评分标准会覆盖它
+const StandardRubricName = "standard"
+
+// 评分标准的来源，builtin 和 config 的评分标准只读
+const (
+	RubricSourceBuiltin = "builtin"
+	RubricSourceConfig  = "config"
+	RubricSourceAPI     = "api"
+)
+
+const (
+	maxRubricDimensions   = 10
+	maxRubricLevels       = 10
+	maxRubricPromptLength = 2000
+)
+
+var (
+	rubricNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	rubricKeyPattern  = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+var (
+	// ErrRubricNotFound 评分标准不存在
+	ErrRubricNotFound = errors.New("rubric not found")
+	// ErrRubricExists 名称已被占用
+	ErrRubricExists = errors.New("rubric already exists")
+	// ErrRubricReadOnly 内置或配置文件中的评分标准不能通过接口修改
+	ErrRubricReadOnly = errors.New("rubric is defined by the server and cannot be modified")
+)
+
+// Rubric 命名的评分标准
+type Rubric struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"` // 只有接口创建的评分标准有更新时间
+	config.RubricConfig
+}
+
+// standardRubric 内置评分标准：相似度占30%，语法、准确性、流畅性等权
+func standardRubric() config.RubricConfig {
+	return config.RubricConfig{
+		Description:      "默认评分标准：与标准答案的相似度占30%，语法、准确性、流畅性平均分占70%",
+		SimilarityWeight: 0.3,
+		Dimensions: []config.RubricDimension{
+			{Key: "grammar", Label: "语法正确性", Weight: 1, Advice: "注意语法结构的准确性", Strength: "语法结构正确"},
+			{Key: "accuracy", Label: "意思准确性", Weight: 1, Advice: "确保翻译的意思准确传达", Strength: "意思表达准确"},
+			{Key: "fluency", Label: "表达流畅性", Weight: 1, Advice: "提高表达的自然流畅度", Strength: "表达自然流畅"},
+		},
+		Levels: []config.RubricLevel{
+			{Label: "优秀", MinScore: 90},
+			{Label: "良好", MinScore: 80},
+			{Label: "及格", MinScore: 60},
+			{Label: "不及格", MinScore: 0},
+		},
+		ImproveBelow: 70,
+		StrengthAt:   80,
+	}
+}
+
+// RubricStore 评分标准存储：内置和配置文件中的评分标准保存在内存中，接口创建的保存在 BoltDB 中
+type RubricStore struct {
+	db          *bolt.DB
+	predefined  map[string]*Rubric
+	defaultName string
+}
+
+// GlobalRubricStore 全局评分标准存储，未初始化时只能使用内置评分标准
+var GlobalRubricStore *RubricStore
+
+// InitRubricStore 校验配置文件中的评分标准并初始化存储
+func InitRubricStore(db *bolt.DB, cfg *config.Config) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(rubricsBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init rubric store: %v", err)
+	}
+
+	predefined := map[string]*Rubric{
+		StandardRubricName: {Name: StandardRubricName, Source: RubricSourceBuiltin, RubricConfig: standardRubric()},
+	}
+	for name, rubric := range cfg.Evaluation.Rubrics {
+		if !rubricNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid rubric name %q in config", name)
+		}
+		if err := normalizeRubric(&rubric); err != nil {
+			return fmt.Errorf("invalid rubric %q in config: %v", name, err)
+		}
+		predefined[name] = &Rubric{Name: name, Source: RubricSourceConfig, RubricConfig: rubric}
+	}
+
+	defaultName := cfg.Evaluation.DefaultRubric
+	if defaultName == "" {
+		defaultName = StandardRubricName
+	}
+	if _, ok := predefined[defaultName]; !ok {
+		return fmt.Errorf("default_rubric %q is not defined in evaluation.rubrics", defaultName)
+	}
+
+	GlobalRubricStore = &RubricStore{db: db, predefined: predefined, defaultName: defaultName}
+	utils.Log.Infof("Loaded %d evaluation rubrics, default: %s", len(predefined), defaultName)
+	return nil
+}
+
+// Default 返回默认评分标准
+func (s *RubricStore) Default() *Rubric {
+	return s.predefined[s.defaultName]
+}
+
+// Get 按名称获取评分标准，内置和配置文件中的优先
+func (s *RubricStore) Get(name string) (*Rubric, error) {
+	if rubric, ok := s.predefined[name]; ok {
+		return rubric, nil
+	}
+	var rubric *Rubric
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rubric, err = loadRubric(tx, name)
+		return err
+	})
+	return rubric, err
+}
+
+// List 列出全部评分标准：内置和配置文件中的在前，各自按名称排序
+func (s *RubricStore) List() ([]*Rubric, error) {
+	var rubrics []*Rubric
+	for _, rubric := range s.predefined {
+		rubrics = append(rubrics, rubric)
+	}
+	sort.Slice(rubrics, func(i, j int) bool {
+		return rubrics[i].Name < rubrics[j].Name
+	})
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(rubricsBucket).ForEach(func(k, v []byte) error {
+			if _, ok := s.predefined[string(k)]; ok {
+				return nil
+			}
+			var rubric Rubric
+			if err := json.Unmarshal(v, &rubric); err != nil {
+				utils.Log.Warnf("Skipping corrupted rubric %s: %v", string(k), err)
+				return nil
+			}
+			rubrics = append(rubrics, &rubric)
+			return nil
+		})
+	})
+	return rubrics, err
+}
+
+// Create 保存新的评分标准，名称不能与已有的重复
+func (s *RubricStore) Create(name string, rubricConfig config.RubricConfig) (*Rubric, error) {
+	if _, ok := s.predefined[name]; ok {
+		return nil, ErrRubricExists
+	}
+	rubric := &Rubric{Name: name, Source: RubricSourceAPI, UpdatedAt: time.Now(), RubricConfig: rubricConfig}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(rubricsBucket).Get([]byte(name)) != nil {
+			return ErrRubricExists
+		}
+		return saveRubric(tx, rubric)
+	})
+	return rubric, err
+}
+
+// Replace 替换接口创建的评分标准
+func (s *RubricStore) Replace(name string, rubricConfig config.RubricConfig) (*Rubric, error) {
+	if _, ok := s.predefined[name]; ok {
+		return nil, ErrRubricReadOnly
+	}
+	rubric := &Rubric{Name: name, Source: RubricSourceAPI, UpdatedAt: time.Now(), RubricConfig: rubricConfig}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(rubricsBucket).Get([]byte(name)) == nil {
+			return ErrRubricNotFound
+		}
+		return saveRubric(tx, rubric)
+	})
+	return rubric, err
+}
+
+// Delete 删除接口创建的评分标准
+func (s *RubricStore) Delete(name string) error {
+	if _, ok := s.predefined[name]; ok {
+		return ErrRubricReadOnly
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rubricsBucket)
+		if bucket.Get([]byte(name)) == nil {
+			return ErrRubricNotFound
+		}
+		return bucket.Delete([]byte(name))
+	})
+}
+
+func loadRubric(tx *bolt.Tx, name string) (*Rubric, error) {
+	v := tx.Bucket(rubricsBucket).Get([]byte(name))
+	if v == nil {
+		return nil, ErrRubricNotFound
+	}
+	var rubric Rubric
+	if err := json.Unmarshal(v, &rubric); err != nil {
+		return nil, fmt.Errorf("corrupted rubric %s: %v", name, err)
+	}
+	return &rubric, nil
+}
+
+func saveRubric(tx *bolt.Tx, rubric *Rubric) error {
+	data, err := json.Marshal(rubric)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(rubricsBucket).Put([]byte(rubric.Name), data)
+}
+
+// normalizeRubric 去除空白、补全默认阈值并校验评分标准，评级按 min_score 从高到低排序
+func normalizeRubric(r *config.RubricConfig) error {
+	r.Description = strings.TrimSpace(r.Description)
+	r.Prompt = strings.TrimSpace(r.Prompt)
+	if utf8.RuneCountInString(r.Prompt) > maxRubricPromptLength {
+		return fmt.Errorf("prompt must not exceed %d characters", maxRubricPromptLength)
+	}
+	if r.SimilarityWeight < 0 || r.SimilarityWeight > 1 {
+		return fmt.Errorf("similarity_weight must be between 0 and 1")
+	}
+	if r.ImproveBelow <= 0 {
+		r.ImproveBelow = 70
+	}
+	if r.StrengthAt <= 0 {
+		r.StrengthAt = 80
+	}
+	if r.ImproveBelow > 100 || r.StrengthAt > 100 {
+		return fmt.Errorf("improve_below and strength_at must not exceed 100")
+	}
+
+	if len(r.Dimensions) == 0 || len(r.Dimensions) > maxRubricDimensions {
+		return fmt.Errorf("a rubric needs 1 to %d dimensions", maxRubricDimensions)
+	}
+	r.Dimensions = slices.Clone(r.Dimensions)
+	keys := make(map[string]bool)
+	for i := range r.Dimensions {
+		dimension := &r.Dimensions[i]
+		dimension.Key = strings.TrimSpace(dimension.Key)
+		dimension.Label = strings.TrimSpace(dimension.Label)
+		dimension.Criteria = strings.TrimSpace(dimension.Criteria)
+		dimension.Advice = strings.TrimSpace(dimension.Advice)
+		dimension.Strength = strings.TrimSpace(dimension.Strength)
+		if !rubricKeyPattern.MatchString(dimension.Key) {
+			return fmt.Errorf("dimensions[%d]: key must be lowercase letters, digits or underscores", i)
+		}
+		if keys[dimension.Key] {
+			return fmt.Errorf("dimensions[%d]: duplicate key %q", i, dimension.Key)
+		}
+		keys[dimension.Key] = true
+		if dimension.Label == "" {
+			return fmt.Errorf("dimensions[%d]: label is required", i)
+		}
+		if dimension.Weight <= 0 {
+			return fmt.Errorf("dimensions[%d]: weight must be positive", i)
+		}
+	}
+
+	if len(r.Levels) == 0 || len(r.Levels) > maxRubricLevels {
+		return fmt.Errorf("a rubric needs 1 to %d levels", maxRubricLevels)
+	}
+	r.Levels = slices.Clone(r.Levels)
+	for i := range r.Levels {
+		level := &r.Levels[i]
+		level.Label = strings.TrimSpace(level.Label)
+		if level.Label == "" {
+			return fmt.Errorf("levels[%d]: label is required", i)
+		}
+		if level.MinScore < 0 || level.MinScore > 100 {
+			return fmt.Errorf("levels[%d]: min_score must be between 0 and 100", i)
+		}
+	}
+	sort.SliceStable(r.Levels, func(i, j int) bool {
+		return r.Levels[i].MinScore > r.Levels[j].MinScore
+	})
+	for i := 1; i < len(r.Levels); i++ {
+		if r.Levels[i].MinScore == r.Levels[i-1].MinScore {
+			return fmt.Errorf("levels %q and %q have the same min_score", r.Levels[i-1].Label, r.Levels[i].Label)
+		}
+	}
+	return nil
+}
+
+// level 返回综合得分对应的评级
+func (r *Rubric) level(score float64) string {
+	for _, level := range r.Levels {
+		if score >= level.MinScore {
+			return level.Label
+		}
+	}
+	return r.Levels[len(r.Levels)-1].Label
+}
+
+// hasDimension 判断评分标准是否包含该维度
+func (r *Rubric) hasDimension(key string) bool {
+	return slices.ContainsFunc(r.Dimensions, func(dimension config.RubricDimension) bool {
+		return dimension.Key == key
+	})
+}
+
+// dimensionKeys 返回各维度的 key，即模型输出 scores 中要求的字段
+func (r *Rubric) dimensionKeys() []string {
+	keys := make([]string, len(r.Dimensions))
+	for i, dimension := range r.Dimensions {
+		keys[i] = dimension.Key
+	}
+	return keys
+}
+
+// dimensionScore 各维度得分按权重的加权平均，得分限制在0-100之间
+func (r *Rubric) dimensionScore(scores map[string]float64) float64 {
+	var total, weights float64
+	for _, dimension := range r.Dimensions {
+		total += max(0, min(100, scores[dimension.Key])) * dimension.Weight
+		weights += dimension.Weight
+	}
+	return total / weights
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+)
+
+func TestRubricLevel(t *testing.T) {
+	rubric := &Rubric{Name: StandardRubricName, RubricConfig: standardRubric()}
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{100, "优秀"},
+		{90, "优秀"},
+		{89.9, "良好"},
+		{80, "良好"},
+		{60, "及格"},
+		{59.99, "不及格"},
+		{0, "不及格"},
+	}
+	for _, tt := range tests {
+		if got := rubric.level(tt.score); got != tt.want {
+			t.Errorf("level(%v) = %q, want %q", tt.score, got, tt.want)
+		}
+	}
+
+	// 低于所有阈值时为最低一档
+	strict := &Rubric{RubricConfig: config.RubricConfig{Levels: []config.RubricLevel{
+		{Label: "pass", MinScore: 60},
+		{Label: "fail", MinScore: 30},
+	}}}
+	if got := strict.level(10); got != "fail" {
+		t.Errorf("level(10) = %q, want the lowest level", got)
+	}
+}
+
+func TestRubricDimensionScore(t *testing.T) {
+	rubric := &Rubric{RubricConfig: config.RubricConfig{Dimensions: []config.RubricDimension{
+		{Key: "a", Weight: 3},
+		{Key: "b", Weight: 2},
+		{Key: "c", Weight: 1},
+	}}}
+	tests := []struct {
+		name   string
+		scores map[string]float64
+		want   float64
+	}{
+		{"weighted", map[string]float64{"a": 90, "b": 60, "c": 30}, 70},
+		{"equal scores", map[string]float64{"a": 80, "b": 80, "c": 80}, 80},
+		{"clamped", map[string]float64{"a": 150, "b": -20, "c": 100}, (100*3 + 0*2 + 100*1) / 6.0},
+		{"missing scores count as zero", map[string]float64{"a": 60}, 30},
+		{"extra scores ignored", map[string]float64{"a": 60, "b": 60, "c": 60, "d": 0}, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rubric.dimensionScore(tt.scores); got != tt.want {
+				t.Errorf("dimensionScore(%v) = %v, want %v", tt.scores, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRubric(t *testing.T) {
+	valid := func() config.RubricConfig {
+		return config.RubricConfig{
+			SimilarityWeight: 0.5,
+			Dimensions: []config.RubricDimension{
+				{Key: " clarity ", Label: " 清晰度 ", Weight: 1, Advice: " 表达更清楚 "},
+				{Key: "terms", Label: "术语", Weight: 2},
+			},
+			Levels: []config.RubricLevel{
+				{Label: "C", MinScore: 0},
+				{Label: " A ", MinScore: 90},
+				{Label: "B", MinScore: 70},
+			},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		r := valid()
+		if err := normalizeRubric(&r); err != nil {
+			t.Fatalf("normalizeRubric() error = %v", err)
+		}
+		if r.ImproveBelow != 70 || r.StrengthAt != 80 {
+			t.Errorf("default thresholds = %v/%v, want 70/80", r.ImproveBelow, r.StrengthAt)
+		}
+		if d := r.Dimensions[0]; d.Key != "clarity" || d.Label != "清晰度" || d.Advice != "表达更清楚" {
+			t.Errorf("dimension not trimmed: %+v", d)
+		}
+		var labels []string
+		for _, level := range r.Levels {
+			labels = append(labels, level.Label)
+		}
+		if got := strings.Join(labels, ","); got != "A,B,C" {
+			t.Errorf("levels = %s, want A,B,C sorted by min_score", got)
+		}
+	})
+
+	t.Run("input not modified", func(t *testing.T) {
+		r := valid()
+		dimensions, levels := r.Dimensions, r.Levels
+		if err := normalizeRubric(&r); err != nil {
+			t.Fatal(err)
+		}
+		if dimensions[0].Key != " clarity " || levels[0].Label != "C" {
+			t.Error("normalizeRubric should not modify the caller's slices")
+		}
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(r *config.RubricConfig)
+		wantErr string
+	}{
+		{"similarity weight too large", func(r *config.RubricConfig) { r.SimilarityWeight = 1.5 }, "similarity_weight"},
+		{"negative similarity weight", func(r *config.RubricConfig) { r.SimilarityWeight = -0.1 }, "similarity_weight"},
+		{"threshold above 100", func(r *config.RubricConfig) { r.StrengthAt = 120 }, "strength_at"},
+		{"prompt too long", func(r *config.RubricConfig) { r.Prompt = strings.Repeat("字", maxRubricPromptLength+1) }, "prompt"},
+		{"no dimensions", func(r *config.RubricConfig) { r.Dimensions = nil }, "dimensions"},
+		{"invalid key", func(r *config.RubricConfig) { r.Dimensions[0].Key = "Clarity" }, "dimensions[0]: key"},
+		{"duplicate key", func(r *config.RubricConfig) { r.Dimensions[1].Key = "clarity" }, "duplicate key"},
+		{"missing label", func(r *config.RubricConfig) { r.Dimensions[1].Label = " " }, "dimensions[1]: label"},
+		{"non-positive weight", func(r *config.RubricConfig) { r.Dimensions[1].Weight = 0 }, "weight must be positive"},
+		{"no levels", func(r *config.RubricConfig) { r.Levels = nil }, "levels"},
+		{"missing level label", func(r *config.RubricConfig) { r.Levels[2].Label = "" }, "levels[2]: label"},
+		{"min score out of range", func(r *config.RubricConfig) { r.Levels[0].MinScore = 101 }, "min_score must be between"},
+		{"duplicate min score", func(r *config.RubricConfig) { r.Levels[2].MinScore = 90 }, "same min_score"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			err := normalizeRubric(&r)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("normalizeRubric() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
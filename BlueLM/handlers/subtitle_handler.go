@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/subtitle"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// subtitleOptions 读取长句切分限制，请求参数 max_line_length / max_duration 优先于配置
+func subtitleOptions(c *gin.Context, cfg *config.Config) (subtitle.Options, error) {
+	opts := subtitle.Options{
+		MaxLineLength: cfg.Subtitle.MaxLineLength,
+		MaxDuration:   cfg.Subtitle.MaxDuration,
+	}
+	if v := c.Query("max_line_length"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("max_line_length must be a non-negative integer")
+		}
+		opts.MaxLineLength = n
+	}
+	if v := c.Query("max_duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			// 也接受以秒为单位的数字
+			secs, ferr := strconv.ParseFloat(v, 64)
+			if ferr != nil {
+				return opts, fmt.Errorf("max_duration must be a duration such as 7s or a number of seconds")
+			}
+			d = time.Duration(secs * float64(time.Second))
+		}
+		if d < 0 {
+			return opts, fmt.Errorf("max_duration must not be negative")
+		}
+		opts.MaxDuration = d
+	}
+	return opts, nil
+}
+
+// respondSubtitle 切分字幕并以附件形式返回
+func respondSubtitle(c *gin.Context, format subtitle.Format, opts subtitle.Options, segments []subtitle.Segment, baseName string) {
+	var buf bytes.Buffer
+	if err := subtitle.Render(&buf, format, subtitle.Split(segments, opts)); err != nil {
+		utils.AbortWithInternalServerError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+baseName+format.Extension())
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// renderBlueLMSubtitle 将蓝心转写结果导出为字幕
+func renderBlueLMSubtitle(c *gin.Context, cfg *config.Config, taskID, filePath, formatName string) {
+	format, err := subtitle.ParseFormat(formatName)
+	if err != nil {
+		utils.AbortWithBadRequest(c, err, err.Error())
+		return
+	}
+	opts, err := subtitleOptions(c, cfg)
+	if err != nil {
+		utils.AbortWithBadRequest(c, err, err.Error())
+		return
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		utils.AbortWithInternalServerError(c, err)
+		return
+	}
+	segments, err := subtitle.ParseBlueLM(data)
+	if err != nil {
+		utils.AbortWithInternalServerError(c, err)
+		return
+	}
+	respondSubtitle(c, format, opts, segments, "transcription_"+taskID)
+}
+
+// renderWhisperXSubtitle 将 WhisperX 结果导出为字幕
+// fileName 指定时间轴来源（transcription 或 wordstamps 等）；做过说话人分离时从 speaker_segments 补充说话人标签
+func renderWhisperXSubtitle(c *gin.Context, cfg *config.Config, taskID, fileName, formatName string) {
+	format, err := subtitle.ParseFormat(formatName)
+	if err != nil {
+		utils.AbortWithBadRequest(c, err, err.Error())
+		return
+	}
+	opts, err := subtitleOptions(c, cfg)
+	if err != nil {
+		utils.AbortWithBadRequest(c, err, err.Error())
+		return
+	}
+	if fileName == "diarization" {
+		utils.AbortWithBadRequest(c, nil, "diarization file cannot be exported as subtitles, use speaker_segments instead")
+		return
+	}
+
+	segments, status, err := loadWhisperXSegments(cfg, taskID, fileName)
+	if err != nil {
+		c.JSON(status, gin.H{
+			"error":   err.Error(),
+			"task_id": taskID,
+		})
+		return
+	}
+	respondSubtitle(c, format, opts, segments, fileName+"_"+taskID)
+}
+
+// loadWhisperXSegments 读取并解析 WhisperX 结果文件，做过说话人分离时从 speaker_segments 补充说话人标签
+func loadWhisperXSegments(cfg *config.Config, taskID, fileName string) ([]subtitle.Segment, int, error) {
+	data, status, err := loadWhisperXFile(cfg, taskID, fileName)
+	if err != nil {
+		return nil, status, err
+	}
+	segments, err := subtitle.ParseWhisperX(data)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if fileName != "speaker_segments" {
+		if speakerData, _, err := loadWhisperXFile(cfg, taskID, "speaker_segments"); err == nil {
+			if speakerSegments, err := subtitle.ParseWhisperX(speakerData); err == nil {
+				subtitle.AssignSpeakers(segments, speakerSegments)
+			}
+		}
+	}
+	return segments, http.StatusOK, nil
+}
+
+// loadWhisperXFile 读取 WhisperX 结果文件的内容
+// 优先使用本地保存的 whisperx_result_<id>.json（WhisperX 服务重启后任务会丢失），没有时再向 WhisperX 服务下载
+func loadWhisperXFile(cfg *config.Config, taskID, fileName string) ([]byte, int, error) {
+	task, exists := GlobalTaskManager.GetTask(taskID)
+	if exists && len(task.AvailableFiles) > 0 && !slices.Contains(task.AvailableFiles, fileName) {
+		return nil, http.StatusBadRequest, fmt.Errorf("file %s is not available for this task", fileName)
+	}
+
+	resultPath := filepath.Join(cfg.FilePaths.DownloadDir, whisperXResultFileName(taskID))
+	if exists && task.FilePath != "" {
+		resultPath = task.FilePath
+	}
+	if data, err := os.ReadFile(resultPath); err == nil {
+		var result map[string]json.RawMessage
+		if err := json.Unmarshal(data, &result); err == nil {
+			if raw, ok := result[fileName]; ok && string(raw) != "null" {
+				return raw, http.StatusOK, nil
+			}
+		}
+	}
+
+	downloadURL := fmt.Sprintf("%s/whisperx/download/%s/%s", cfg.WhisperX.URL, taskID, fileName)
+	resp, err := http.Get(downloadURL)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to download %s from WhisperX service: %v", fileName, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to read %s from WhisperX service: %v", fileName, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("WhisperX service returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, http.StatusOK, nil
+}
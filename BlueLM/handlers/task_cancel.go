@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// taskPollers 记录正在运行的状态轮询协程，用于取消任务时停止轮询
+var taskPollers = struct {
+	sync.Mutex
+	cancels map[string]context.CancelFunc
+}{cancels: make(map[string]context.CancelFunc)}
+
+// startTaskPoller 为任务登记轮询协程，返回的 ctx 在任务被取消时结束
+func startTaskPoller(taskID string) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	taskPollers.Lock()
+	defer taskPollers.Unlock()
+	if previous, exists := taskPollers.cancels[taskID]; exists {
+		previous()
+	}
+	taskPollers.cancels[taskID] = cancel
+	return ctx
+}
+
+// finishTaskPoller 轮询协程退出时注销
+func finishTaskPoller(taskID string) {
+	taskPollers.Lock()
+	defer taskPollers.Unlock()
+	if cancel, exists := taskPollers.cancels[taskID]; exists {
+		cancel()
+		delete(taskPollers.cancels, taskID)
+	}
+}
+
+// stopTaskPoller 停止任务的轮询协程，协程不存在时返回 false
+func stopTaskPoller(taskID string) bool {
+	taskPollers.Lock()
+	defer taskPollers.Unlock()
+	cancel, exists := taskPollers.cancels[taskID]
+	if !exists {
+		return false
+	}
+	cancel()
+	delete(taskPollers.cancels, taskID)
+	return true
+}
+
+// updateRunningTask 修改任务信息，已被取消的任务不再被轮询结果覆盖
+func updateRunningTask(taskID string, update func(task *TaskInfo)) {
+	GlobalTaskManager.UpdateTask(taskID, func(task *TaskInfo) {
+		if task.Status == TaskStatusCancelled {
+			return
+		}
+		update(task)
+	})
+}
+
+// updateRunningTaskStatus 更新未被取消任务的状态
+func updateRunningTaskStatus(taskID string, status TaskStatus, message string) {
+	updateRunningTask(taskID, func(task *TaskInfo) {
+		task.Status = status
+		task.Message = message
+	})
+}
+
+// cancelTask 取消任务：停止轮询、标记为已取消、清理上传文件，WhisperX 任务同时通知 WhisperX 服务
+// 返回取消后的任务信息，失败时返回对应的 HTTP 状态码
+func cancelTask(taskID string, cfg *config.Config) (*TaskInfo, int, error) {
+	task, exists := GlobalTaskManager.GetTask(taskID)
+	if !exists {
+		return nil, http.StatusNotFound, fmt.Errorf("task not found")
+	}
+
+	// 轮询协程在写入终止状态后才注销，是否已结束以任务状态为准；
+	// 检查和标记在同一次 UpdateTask 中完成，避免覆盖刚写入的 completed
+	stopTaskPoller(taskID)
+	cancelled := false
+	GlobalTaskManager.UpdateTask(taskID, func(t *TaskInfo) {
+		task.Status = t.Status
+		if t.Status.IsFinished() {
+			return
+		}
+		t.Status = TaskStatusCancelled
+		t.Message = "Task cancelled by user"
+		cancelled = true
+	})
+	if !cancelled {
+		return task, http.StatusConflict, fmt.Errorf("task already finished with status %s", task.Status)
+	}
+
+	if task.UploadPath != "" {
+		if err := os.Remove(task.UploadPath); err != nil && !os.IsNotExist(err) {
+			utils.Log.Warnf("Failed to remove upload file %s of cancelled task %s: %v", task.UploadPath, taskID, err)
+		}
+	}
+
+	if taskEngine(task) == TaskEngineWhisperX {
+		forwardWhisperXCancel(taskID, cfg)
+	}
+
+	utils.Log.Infof("Task %s (%s) cancelled", taskID, taskEngine(task))
+	task, _ = GlobalTaskManager.GetTask(taskID)
+	return task, http.StatusOK, nil
+}
+
+// forwardWhisperXCancel 通知 WhisperX 服务取消任务，旧版本 WhisperX 服务不支持取消时仅记录日志
+func forwardWhisperXCancel(taskID string, cfg *config.Config) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/whisperx/cancel/%s", cfg.WhisperX.URL, taskID), "application/json", nil)
+	if err != nil {
+		utils.Log.Warnf("Failed to forward cancellation of task %s to WhisperX: %v", taskID, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		utils.Log.Infof("WhisperX task %s cancelled on WhisperX service", taskID)
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		// 旧版本 WhisperX 服务没有取消接口，Flask 对不存在的路由返回 404
+		utils.Log.Warnf("WhisperX service does not support cancellation, task %s keeps running there", taskID)
+	default:
+		utils.Log.Warnf("WhisperX service returned status %d when cancelling task %s", resp.StatusCode, taskID)
+	}
+}
+
+// TaskCancelHandler 取消转录任务（蓝心和WhisperX通用）
+func TaskCancelHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskID := c.Param("task_id")
+		if taskID == "" {
+			utils.AbortWithBadRequest(c, nil, "Task ID is required")
+			return
+		}
+		respondTaskCancel(c, cfg, taskID, "")
+	}
+}
+
+// respondTaskCancel 取消任务并返回结果，engine 非空时要求任务属于该引擎
+// 取消会改变任务状态，只接受 POST（统一入口 /model 同时注册了 GET）
+func respondTaskCancel(c *gin.Context, cfg *config.Config, taskID, engine string) {
+	if c.Request.Method != http.MethodPost {
+		c.Header("Allow", http.MethodPost)
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error":   "Task cancellation requires POST",
+			"task_id": taskID,
+		})
+		return
+	}
+	if engine != "" {
+		if task, exists := GlobalTaskManager.GetTask(taskID); exists && taskEngine(task) != engine {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Task not found",
+				"task_id": taskID,
+			})
+			return
+		}
+	}
+
+	task, statusCode, err := cancelTask(taskID, cfg)
+	switch statusCode {
+	case http.StatusOK:
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Task cancelled",
+			"task":    task,
+		})
+	case http.StatusNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"task_id": taskID,
+		})
+	default:
+		c.JSON(statusCode, gin.H{
+			"error":   err.Error(),
+			"status":  task.Status,
+			"task_id": taskID,
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// SSE 事件类型
+const (
+	TaskEventSnapshot  = "snapshot"  // 订阅时回放的当前状态
+	TaskEventProgress  = "progress"  // 进度或提示信息变化
+	TaskEventStage     = "stage"     // WhisperX 处理阶段切换
+	TaskEventCompleted = "completed" // 任务完成，附带下载链接
+	TaskEventFailed    = "failed"
+	TaskEventCancelled = "cancelled"
+)
+
+// TaskEvent 推送给订阅者的任务事件
+type TaskEvent struct {
+	Type      string            `json:"type"`
+	TaskID    string            `json:"task_id"`
+	Status    TaskStatus        `json:"status"`
+	Progress  int               `json:"progress"`
+	Stage     string            `json:"stage,omitempty"`
+	Message   string            `json:"message"`
+	Downloads map[string]string `json:"downloads,omitempty"` // 文件类型 -> 下载地址，仅完成事件携带
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// TaskChangeListener 任务变更监听器，prev 为变更前的任务（新建任务时为 nil）
+type TaskChangeListener func(prev, cur *TaskInfo)
+
+// observedTaskManager 在任务管理器的每次变更后通知监听器
+// 监听器在锁内被同步调用，必须立即返回且不能再修改任务（需要时另起协程）
+type observedTaskManager struct {
+	TaskManager
+	mu        sync.Mutex // 保证同一任务的变更按顺序通知
+	listeners []TaskChangeListener
+}
+
+// newObservedTaskManager 包装任务管理器，使其变更可被监听
+func newObservedTaskManager(tm TaskManager, listeners ...TaskChangeListener) *observedTaskManager {
+	return &observedTaskManager{TaskManager: tm, listeners: listeners}
+}
+
+// observe 执行变更并通知监听器
+func (tm *observedTaskManager) observe(taskID string, mutate func()) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	prev, _ := tm.TaskManager.GetTask(taskID)
+	mutate()
+	cur, exists := tm.TaskManager.GetTask(taskID)
+	if !exists {
+		return
+	}
+	for _, listener := range tm.listeners {
+		listener(prev, cur)
+	}
+}
+
+func (tm *observedTaskManager) CreateTask(taskID, filename, engine string) {
+	tm.observe(taskID, func() { tm.TaskManager.CreateTask(taskID, filename, engine) })
+}
+
+func (tm *observedTaskManager) UpdateTaskStatus(taskID string, status TaskStatus, message string) {
+	tm.observe(taskID, func() { tm.TaskManager.UpdateTaskStatus(taskID, status, message) })
+}
+
+func (tm *observedTaskManager) UpdateTask(taskID string, update func(task *TaskInfo)) bool {
+	var updated bool
+	tm.observe(taskID, func() { updated = tm.TaskManager.UpdateTask(taskID, update) })
+	return updated
+}
+
+func (tm *observedTaskManager) SetTaskFilePath(taskID, filePath string) {
+	tm.observe(taskID, func() { tm.TaskManager.SetTaskFilePath(taskID, filePath) })
+}
+
+// taskEventBroker 按任务分发事件，每个任务支持多个订阅者
+type taskEventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan TaskEvent]struct{}
+}
+
+var taskEvents = &taskEventBroker{subscribers: make(map[string]map[chan TaskEvent]struct{})}
+
+// subscribe 订阅任务事件
+func (b *taskEventBroker) subscribe(taskID string) chan TaskEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan TaskEvent, 16)
+	if b.subscribers[taskID] == nil {
+		b.subscribers[taskID] = make(map[chan TaskEvent]struct{})
+	}
+	b.subscribers[taskID][ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe 取消订阅
+func (b *taskEventBroker) unsubscribe(taskID string, ch chan TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers[taskID], ch)
+	if len(b.subscribers[taskID]) == 0 {
+		delete(b.subscribers, taskID)
+	}
+}
+
+// publish 向任务的所有订阅者发送事件
+// 订阅者处理过慢时丢弃其最旧的事件，保证终止事件一定能送达且不阻塞任务轮询
+func (b *taskEventBroker) publish(event TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.TaskID] {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- event
+		}
+	}
+}
+
+// publishChange 将任务变更转换为事件，只推送订阅者关心的变化
+func (b *taskEventBroker) publishChange(prev, cur *TaskInfo) {
+	var eventType string
+	switch {
+	case cur.Status.IsFinished() && (prev == nil || prev.Status != cur.Status):
+		eventType = string(cur.Status)
+	case prev != nil && prev.Stage != cur.Stage:
+		eventType = TaskEventStage
+	case prev == nil || prev.Progress != cur.Progress || prev.Message != cur.Message || prev.Status != cur.Status:
+		eventType = TaskEventProgress
+	default:
+		return
+	}
+	b.publish(newTaskEvent(eventType, cur))
+}
+
+// newTaskEvent 根据任务当前状态构造事件
+func newTaskEvent(eventType string, task *TaskInfo) TaskEvent {
+	event := TaskEvent{
+		Type:      eventType,
+		TaskID:    task.TaskID,
+		Status:    task.Status,
+		Progress:  task.Progress,
+		Stage:     task.Stage,
+		Message:   task.Message,
+		Timestamp: time.Now(),
+	}
+	if task.Status == TaskStatusCompleted {
+		event.Downloads = taskDownloadLinks(task)
+	}
+	return event
+}
+
+// taskDownloadLinks 返回已完成任务的结果下载地址
+func taskDownloadLinks(task *TaskInfo) map[string]string {
+	taskID := url.QueryEscape(task.TaskID)
+	switch taskEngine(task) {
+	case TaskEngineWhisperX:
+		links := make(map[string]string, len(task.AvailableFiles))
+		for _, file := range task.AvailableFiles {
+			links[file] = "/model?model=whisperx&action=download&task_id=" + taskID + "&file_name=" + url.QueryEscape(file)
+		}
+		return links
+	case TaskEngineBilingual:
+		return map[string]string{
+			"json": "/bluelm/bilingual/download/" + taskID,
+			"srt":  "/bluelm/bilingual/download/" + taskID + "?format=srt",
+			"vtt":  "/bluelm/bilingual/download/" + taskID + "?format=vtt",
+		}
+	case TaskEngineEvaluation:
+		return map[string]string{
+			"report": "/translate/evaluate/batch/download/" + taskID,
+		}
+	case TaskEngineTTS:
+		return map[string]string{
+			"audio": "/bluelm/tts/download/" + taskID,
+		}
+	default:
+		return map[string]string{
+			"transcription": "/bluelm/transcription/download/" + taskID,
+		}
+	}
+}
+
+// TaskEventsHandler 以 Server-Sent Events 推送任务进度
+// 订阅后先回放任务当前状态；任务结束后推送终止事件并关闭连接
+func TaskEventsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskID := c.Param("task_id")
+		if taskID == "" {
+			utils.AbortWithBadRequest(c, nil, "Task ID is required")
+			return
+		}
+
+		// 先订阅再读取当前状态，避免两者之间的事件丢失
+		events := taskEvents.subscribe(taskID)
+		defer taskEvents.unsubscribe(taskID, events)
+
+		task, exists := GlobalTaskManager.GetTask(taskID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Task not found",
+				"task_id": taskID,
+			})
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // 禁用反向代理缓冲
+
+		snapshot := newTaskEvent(TaskEventSnapshot, task)
+		c.SSEvent(snapshot.Type, snapshot)
+		c.Writer.Flush()
+		if task.Status.IsFinished() {
+			final := newTaskEvent(string(task.Status), task)
+			c.SSEvent(final.Type, final)
+			return
+		}
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				// SSE 注释行，用于保持连接
+				io.WriteString(w, ": keepalive\n\n")
+				return true
+			case event := <-events:
+				c.SSEvent(event.Type, event)
+				return !event.Status.IsFinished()
+			}
+		})
+	}
+}
@@ -1,8 +1,16 @@
 package handlers
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
 )
 
 // TaskStatus 定义任务状态
@@ -13,95 +21,265 @@ const (
 	TaskStatusProcessing TaskStatus = "processing"
 	TaskStatusCompleted  TaskStatus = "completed"
 	TaskStatusFailed     TaskStatus = "failed"
+	TaskStatusCancelled  TaskStatus = "cancelled"
+)
+
+// IsFinished 判断任务是否已处于终止状态
+func (s TaskStatus) IsFinished() bool {
+	return s == TaskStatusCompleted || s == TaskStatusFailed || s == TaskStatusCancelled
+}
+
+// 任务所属的处理引擎
+const (
+	TaskEngineBlueLM     = "bluelm"
+	TaskEngineWhisperX   = "whisperx"
+	TaskEngineBilingual  = "bilingual"  // 对已完成转录的逐句翻译
+	TaskEngineEvaluation = "evaluation" // 批量翻译评估
+	TaskEngineTTS        = "tts"        // 长文本语音合成
+)
+
+// WhisperX 的处理阶段
+const (
+	WhisperXStageTranscription = "transcription"
+	WhisperXStageAlignment     = "alignment"
+	WhisperXStageDiarization   = "diarization"
 )
 
 // TaskInfo 存储任务信息
 type TaskInfo struct {
-	TaskID    string     `json:"task_id"`
-	Status    TaskStatus `json:"status"`
-	Message   string     `json:"message"`
-	CreatedAt time.Time  `json:"created_at"`
-	Filename  string     `json:"filename"`
-	FilePath  string     `json:"-"` // 不在 JSON 中暴露文件路径
+	TaskID         string          `json:"task_id"`
+	Engine         string          `json:"engine"`
+	Status         TaskStatus      `json:"status"`
+	Message        string          `json:"message"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Filename       string          `json:"filename"`
+	FilePath       string          `json:"-"`                         // 不在 JSON 中暴露文件路径
+	UploadPath     string          `json:"-"`                         // 上传文件的本地路径，取消任务时清理
+	Options        *WhisperXParams `json:"options,omitempty"`         // WhisperX 处理参数（不含 HuggingFace Token）
+	Progress       int             `json:"progress"`                  // 进度百分比 (0-100)
+	Stage          string          `json:"stage,omitempty"`           // WhisperX 当前处理阶段
+	AvailableFiles []string        `json:"available_files,omitempty"` // WhisperX 已生成的结果文件
+
+	CallbackURL      string            `json:"callback_url,omitempty"`      // 任务结束时回调的地址
+	CallbackSecret   string            `json:"-"`                           // 回调签名密钥
+	CallbackStatus   string            `json:"callback_status,omitempty"`   // pending / delivered / failed
+	CallbackAttempts []CallbackAttempt `json:"callback_attempts,omitempty"` // 回调投递记录
+}
+
+// clone 深拷贝任务信息，避免调用方与管理器共享切片和指针
+func (t *TaskInfo) clone() *TaskInfo {
+	taskCopy := *t
+	if t.Options != nil {
+		options := *t.Options
+		taskCopy.Options = &options
+	}
+	taskCopy.AvailableFiles = append([]string(nil), t.AvailableFiles...)
+	taskCopy.CallbackAttempts = append([]CallbackAttempt(nil), t.CallbackAttempts...)
+	return &taskCopy
 }
 
 // TaskManager 管理转录任务
-type TaskManager struct {
+type TaskManager interface {
+	// CreateTask 创建新任务
+	CreateTask(taskID, filename, engine string)
+	// UpdateTaskStatus 更新任务状态
+	UpdateTaskStatus(taskID string, status TaskStatus, message string)
+	// UpdateTask 在锁内对任务执行任意修改，任务不存在时返回 false
+	UpdateTask(taskID string, update func(task *TaskInfo)) bool
+	// SetTaskFilePath 设置任务的文件路径
+	SetTaskFilePath(taskID, filePath string)
+	// DeleteTask 删除任务记录，任务不存在时返回 false
+	DeleteTask(taskID string) bool
+	// GetTask 获取任务信息（返回副本）
+	GetTask(taskID string) (*TaskInfo, bool)
+	// GetAllTasks 获取所有任务（返回副本）
+	GetAllTasks() []*TaskInfo
+}
+
+// MemoryTaskManager 基于内存的任务管理器，进程重启后任务丢失
+type MemoryTaskManager struct {
 	mu    sync.RWMutex
 	tasks map[string]*TaskInfo
 }
 
-// NewTaskManager 创建新的任务管理器
-func NewTaskManager() *TaskManager {
-	return &TaskManager{
+// NewMemoryTaskManager 创建新的内存任务管理器
+func NewMemoryTaskManager() *MemoryTaskManager {
+	return &MemoryTaskManager{
 		tasks: make(map[string]*TaskInfo),
 	}
 }
 
 // CreateTask 创建新任务
-func (tm *TaskManager) CreateTask(taskID, filename string) {
+func (tm *MemoryTaskManager) CreateTask(taskID, filename, engine string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	
-	tm.tasks[taskID] = &TaskInfo{
-		TaskID:    taskID,
-		Status:    TaskStatusPending,
-		Message:   "Task created, waiting to start",
-		CreatedAt: time.Now(),
-		Filename:  filename,
-	}
+
+	tm.tasks[taskID] = newTaskInfo(taskID, filename, engine)
 }
 
 // UpdateTaskStatus 更新任务状态
-func (tm *TaskManager) UpdateTaskStatus(taskID string, status TaskStatus, message string) {
+func (tm *MemoryTaskManager) UpdateTaskStatus(taskID string, status TaskStatus, message string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	
+
 	if task, exists := tm.tasks[taskID]; exists {
 		task.Status = status
 		task.Message = message
+		task.UpdatedAt = time.Now()
 	}
 }
 
+// UpdateTask 修改任务信息
+func (tm *MemoryTaskManager) UpdateTask(taskID string, update func(task *TaskInfo)) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return false
+	}
+	update(task)
+	task.UpdatedAt = time.Now()
+	return true
+}
+
 // SetTaskFilePath 设置任务的文件路径
-func (tm *TaskManager) SetTaskFilePath(taskID, filePath string) {
+func (tm *MemoryTaskManager) SetTaskFilePath(taskID, filePath string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	
+
 	if task, exists := tm.tasks[taskID]; exists {
 		task.FilePath = filePath
+		task.UpdatedAt = time.Now()
 	}
 }
 
+// DeleteTask 删除任务
+func (tm *MemoryTaskManager) DeleteTask(taskID string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if _, exists := tm.tasks[taskID]; !exists {
+		return false
+	}
+	delete(tm.tasks, taskID)
+	return true
+}
+
 // GetTask 获取任务信息
-func (tm *TaskManager) GetTask(taskID string) (*TaskInfo, bool) {
+func (tm *MemoryTaskManager) GetTask(taskID string) (*TaskInfo, bool) {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
-	
+
 	task, exists := tm.tasks[taskID]
 	if !exists {
 		return nil, false
 	}
-	
+
 	// 返回副本以避免并发问题
-	taskCopy := *task
-	return &taskCopy, true
+	return task.clone(), true
 }
 
 // GetAllTasks 获取所有任务
-func (tm *TaskManager) GetAllTasks() []*TaskInfo {
+func (tm *MemoryTaskManager) GetAllTasks() []*TaskInfo {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
-	
+
 	tasks := make([]*TaskInfo, 0, len(tm.tasks))
 	for _, task := range tm.tasks {
 		// 返回副本以避免并发问题
-		taskCopy := *task
-		tasks = append(tasks, &taskCopy)
+		tasks = append(tasks, task.clone())
 	}
-	
+
 	return tasks
 }
 
-// 全局任务管理器实例
-var GlobalTaskManager = NewTaskManager()
\ No newline at end of file
+// newTaskInfo 构造处于等待状态的新任务
+func newTaskInfo(taskID, filename, engine string) *TaskInfo {
+	now := time.Now()
+	return &TaskInfo{
+		TaskID:    taskID,
+		Engine:    engine,
+		Status:    TaskStatusPending,
+		Message:   "Task created, waiting to start",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Filename:  filename,
+	}
+}
+
+// lastActivity 返回任务最近一次变更的时间，早期版本的任务没有记录更新时间
+func (t *TaskInfo) lastActivity() time.Time {
+	if t.UpdatedAt.IsZero() {
+		return t.CreatedAt
+	}
+	return t.UpdatedAt
+}
+
+// 全局任务管理器实例，启动时由 InitTaskManager 替换为持久化实现
+// 任务变更会通过 taskEvents 推送给订阅者
+var GlobalTaskManager TaskManager = newObservedTaskManager(NewMemoryTaskManager(), taskEvents.publishChange)
+
+// InitTaskManager 使用嵌入式数据库初始化全局任务管理器，并恢复重启前的任务状态
+func InitTaskManager(db *bolt.DB, cfg *config.Config) error {
+	tm, err := NewBoltTaskManager(db)
+	if err != nil {
+		return err
+	}
+	webhooks := newWebhookDispatcher(cfg)
+	GlobalTaskManager = newObservedTaskManager(tm, taskEvents.publishChange, webhooks.onTaskChange)
+
+	recoverTasks(cfg)
+	webhooks.resumePending()
+	return nil
+}
+
+// transcriptionResultFileName 返回蓝心转录结果文件名
+func transcriptionResultFileName(taskID string) string {
+	return "transcription_" + taskID + ".json"
+}
+
+// recoverTasks 在启动时整理任务状态：
+// 1. 重启前仍在进行中的 WhisperX 任务：重新启动轮询，由 WhisperX 服务决定其最终状态
+// 2. 重启前仍在进行中的蓝心、双语字幕、批量评估和长文本语音合成任务：若结果文件已经写出则标记完成，否则标记失败并说明原因（见 taskResults）
+// 3. 下载目录中存在结果文件但没有任务记录的转录，重新登记为已完成任务
+func recoverTasks(cfg *config.Config) {
+	for _, task := range GlobalTaskManager.GetAllTasks() {
+		if task.Status != TaskStatusPending && task.Status != TaskStatusProcessing {
+			continue
+		}
+
+		if taskEngine(task) == TaskEngineWhisperX {
+			utils.Log.Infof("Resuming status polling for WhisperX task %s", task.TaskID)
+			go pollWhisperXStatus(startTaskPoller(task.TaskID), task.TaskID, cfg)
+			continue
+		}
+
+		if result, ok := taskResults[taskEngine(task)]; ok {
+			result.recover(cfg, task)
+		}
+	}
+
+	entries, err := os.ReadDir(cfg.FilePaths.DownloadDir)
+	if err != nil {
+		utils.Log.Warnf("Failed to scan download dir %s: %v", cfg.FilePaths.DownloadDir, err)
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "transcription_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		taskID := strings.TrimSuffix(strings.TrimPrefix(name, "transcription_"), ".json")
+		if _, exists := GlobalTaskManager.GetTask(taskID); exists {
+			continue
+		}
+
+		resultPath := filepath.Join(cfg.FilePaths.DownloadDir, name)
+		GlobalTaskManager.CreateTask(taskID, "unknown", TaskEngineBlueLM)
+		GlobalTaskManager.SetTaskFilePath(taskID, resultPath)
+		GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusCompleted, "Transcription completed successfully (recovered from result file)")
+		utils.Log.Infof("Registered orphan transcription result %s as task %s", resultPath, taskID)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// tasksBucket 任务记录所在的 bucket，键为任务ID
+var tasksBucket = []byte("tasks")
+
+// BoltTaskManager 基于 BoltDB 的持久化任务管理器
+// 读操作走内存索引，写操作同步落盘，进程重启后从数据库重建全部任务
+type BoltTaskManager struct {
+	mu    sync.RWMutex
+	db    *bolt.DB
+	tasks map[string]*TaskInfo
+}
+
+// NewBoltTaskManager 创建持久化任务管理器并加载已有任务
+func NewBoltTaskManager(db *bolt.DB) (*BoltTaskManager, error) {
+	tm := &BoltTaskManager{
+		db:    db,
+		tasks: make(map[string]*TaskInfo),
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(tasksBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var task TaskInfo
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&task); err != nil {
+				// 单条记录损坏不影响其他任务的恢复
+				utils.Log.Warnf("Skipping corrupted task record %s: %v", string(k), err)
+				return nil
+			}
+			tm.tasks[task.TaskID] = &task
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %v", err)
+	}
+
+	utils.Log.Infof("Loaded %d tasks from task store", len(tm.tasks))
+	return tm, nil
+}
+
+// CreateTask 创建新任务
+func (tm *BoltTaskManager) CreateTask(taskID, filename, engine string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task := newTaskInfo(taskID, filename, engine)
+	tm.tasks[taskID] = task
+	tm.persist(task)
+}
+
+// UpdateTaskStatus 更新任务状态
+func (tm *BoltTaskManager) UpdateTaskStatus(taskID string, status TaskStatus, message string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if task, exists := tm.tasks[taskID]; exists {
+		task.Status = status
+		task.Message = message
+		task.UpdatedAt = time.Now()
+		tm.persist(task)
+	}
+}
+
+// UpdateTask 修改任务信息并落盘
+func (tm *BoltTaskManager) UpdateTask(taskID string, update func(task *TaskInfo)) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return false
+	}
+	update(task)
+	task.UpdatedAt = time.Now()
+	tm.persist(task)
+	return true
+}
+
+// SetTaskFilePath 设置任务的文件路径
+func (tm *BoltTaskManager) SetTaskFilePath(taskID, filePath string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if task, exists := tm.tasks[taskID]; exists {
+		task.FilePath = filePath
+		task.UpdatedAt = time.Now()
+		tm.persist(task)
+	}
+}
+
+// DeleteTask 删除任务及其持久化记录
+func (tm *BoltTaskManager) DeleteTask(taskID string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if _, exists := tm.tasks[taskID]; !exists {
+		return false
+	}
+	delete(tm.tasks, taskID)
+
+	err := tm.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Delete([]byte(taskID))
+	})
+	if err != nil {
+		utils.Log.Errorf("Failed to delete task %s from store: %v", taskID, err)
+	}
+	return true
+}
+
+// GetTask 获取任务信息
+func (tm *BoltTaskManager) GetTask(taskID string) (*TaskInfo, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, false
+	}
+
+	return task.clone(), true
+}
+
+// GetAllTasks 获取所有任务
+func (tm *BoltTaskManager) GetAllTasks() []*TaskInfo {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	tasks := make([]*TaskInfo, 0, len(tm.tasks))
+	for _, task := range tm.tasks {
+		tasks = append(tasks, task.clone())
+	}
+
+	return tasks
+}
+
+// persist 将任务写入数据库，调用方需持有写锁
+// 使用 gob 编码以保留 JSON 中隐藏的字段（如 FilePath）
+func (tm *BoltTaskManager) persist(task *TaskInfo) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(task); err != nil {
+		utils.Log.Errorf("Failed to encode task %s: %v", task.TaskID, err)
+		return
+	}
+
+	err := tm.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put([]byte(task.TaskID), buf.Bytes())
+	})
+	if err != nil {
+		utils.Log.Errorf("Failed to persist task %s: %v", task.TaskID, err)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// resultPathFunc 在下载目录中查找任务的结果文件，不存在时返回 false
+type resultPathFunc func(downloadDir, taskID string) (string, bool)
+
+// fixedResultPath 结果文件名只由任务ID决定的引擎使用
+func fixedResultPath(fileName func(taskID string) string) resultPathFunc {
+	return func(downloadDir, taskID string) (string, bool) {
+		path := filepath.Join(downloadDir, fileName(taskID))
+		if _, err := os.Stat(path); err != nil {
+			return "", false
+		}
+		return path, true
+	}
+}
+
+// taskResult 在本进程内生成结果文件的引擎：结果文件的位置，以及重启后恢复任务时的说明
+type taskResult struct {
+	name        string // 日志中的任务类别
+	path        resultPathFunc
+	recovered   string // 结果文件已写出，恢复为完成时的消息
+	interrupted string // 结果文件未写出，标记为失败时的消息
+}
+
+// taskResults 各引擎的结果文件；WhisperX 的结果由 WhisperX 服务生成，不在此列
+var taskResults = map[string]taskResult{
+	// vivo 长语音转写的会话信息只保存在进程内，重启后无法继续轮询
+	TaskEngineBlueLM: {
+		name:        "Transcription",
+		path:        fixedResultPath(transcriptionResultFileName),
+		recovered:   "Transcription completed successfully (recovered after restart)",
+		interrupted: "Task interrupted by server restart: the transcription session cannot be resumed, please submit the file again",
+	},
+	TaskEngineBilingual: {
+		name:        "Bilingual",
+		path:        fixedResultPath(bilingualResultFileName),
+		recovered:   "Bilingual subtitles completed successfully (recovered after restart)",
+		interrupted: "Task interrupted by server restart, please submit the bilingual job again",
+	},
+	TaskEngineEvaluation: {
+		name:        "Batch evaluation",
+		path:        fixedResultPath(evaluationReportFileName),
+		recovered:   "Batch evaluation completed successfully (recovered after restart)",
+		interrupted: "Task interrupted by server restart, please submit the batch evaluation again",
+	},
+	TaskEngineTTS: {
+		name:        "TTS",
+		path:        findTTSResult,
+		recovered:   "Speech synthesis completed successfully (recovered after restart)",
+		interrupted: "Task interrupted by server restart, please submit the text again",
+	},
+}
+
+// recover 处理重启前未结束的任务：结果文件已经写出则标记完成，否则标记失败
+func (r taskResult) recover(cfg *config.Config, task *TaskInfo) {
+	if resultPath, ok := r.path(cfg.FilePaths.DownloadDir, task.TaskID); ok {
+		GlobalTaskManager.SetTaskFilePath(task.TaskID, resultPath)
+		GlobalTaskManager.UpdateTaskStatus(task.TaskID, TaskStatusCompleted, r.recovered)
+		utils.Log.Infof("Recovered completed %s task %s from %s", r.name, task.TaskID, resultPath)
+		return
+	}
+	GlobalTaskManager.UpdateTaskStatus(task.TaskID, TaskStatusFailed, r.interrupted)
+	utils.Log.Warnf("%s task %s was %s before restart, marked as failed", r.name, task.TaskID, task.Status)
+}
+
+// completedTaskResult 返回 engine 的已完成任务的结果文件路径
+// 任务不存在、不属于该引擎、尚未完成或结果文件缺失时写出错误响应并返回 false
+func completedTaskResult(c *gin.Context, cfg *config.Config, engine string) (string, bool) {
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		utils.AbortWithBadRequest(c, nil, "Task ID is required")
+		return "", false
+	}
+
+	task, exists := GlobalTaskManager.GetTask(taskID)
+	if !exists || taskEngine(task) != engine {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"task_id": taskID,
+		})
+		return "", false
+	}
+	if task.Status != TaskStatusCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Task is not completed yet",
+			"status":  task.Status,
+			"task_id": taskID,
+		})
+		return "", false
+	}
+
+	filePath, ok := taskResults[engine].path(cfg.FilePaths.DownloadDir, taskID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Result file not found",
+			"task_id": taskID,
+		})
+		return "", false
+	}
+	return filePath, true
+}
+
+// sendResultFile 以附件形式返回结果文件
+func sendResultFile(c *gin.Context, filePath, contentType string) {
+	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(filePath))
+	c.Header("Content-Type", contentType)
+	c.File(filePath)
+}
@@ -1,63 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
-	"reflect"
 	"time"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 )
 
-// createBlueLMApp 创建蓝心大模型应用实例，考虑配置优先级
-// 优先级：前端传递的配置 > 系统环境变量 > config.yaml文件
-func createBlueLMApp(frontendAppID, frontendAppKey string, cfg *config.Config) *vivo.Vivo {
-	// 1. 优先使用前端传递的配置
-	if frontendAppID != "" && frontendAppKey != "" {
-		utils.Log.Infof("Using frontend provided BlueLM config")
-		return vivo.NewVivoAIGC(vivo.Config{
-			AppID:  frontendAppID,
-			AppKey: frontendAppKey,
-		})
-	}
-
-	// 2. 使用系统环境变量
-	envAppID := os.Getenv("BLUELM_APP_ID")
-	envAppKey := os.Getenv("BLUELM_APP_KEY")
-	if envAppID != "" && envAppKey != "" {
-		utils.Log.Infof("Using environment variables for BlueLM config")
-		return vivo.NewVivoAIGC(vivo.Config{
-			AppID:  envAppID,
-			AppKey: envAppKey,
-		})
-	}
-
-	// 3. 使用config.yaml文件中的配置
-	if cfg.VivoAI.AppID != "" && cfg.VivoAI.AppKey != "" {
-		utils.Log.Infof("Using config.yaml for BlueLM config")
-		return vivo.NewVivoAIGC(vivo.Config{
-			AppID:  cfg.VivoAI.AppID,
-			AppKey: cfg.VivoAI.AppKey,
-		})
-	}
-
-	// 如果都没有配置，返回空配置的应用实例（可能会失败）
-	utils.Log.Warnf("No BlueLM configuration found, using empty config")
-	return vivo.NewVivoAIGC(vivo.Config{
-		AppID:  "",
-		AppKey: "",
-	})
-}
-
 // TranscriptionHandler 处理长语音转写请求
-func TranscriptionHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+func TranscriptionHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		callback, err := parseCallbackTarget(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid callback parameters")
+			return
+		}
+
 		// 保存上传的文件
 		uploadFilePath, err := utils.SaveUploadedFile(c, cfg.FilePaths.UploadDir)
 		if err != nil {
@@ -66,11 +32,14 @@ func TranscriptionHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 获取蓝心大模型配置（前端传递的优先级最高）
-		appID := c.PostForm("app_id")
-		appKey := c.PostForm("app_key")
-
-		// 创建蓝心大模型应用实例，考虑配置优先级
-		transcriptionApp := createBlueLMApp(appID, appKey, cfg)
+		transcriptionApp, ok := resolveProvider(c, resolver, ai.Credentials{
+			AppID:   c.PostForm("app_id"),
+			AppKey:  c.PostForm("app_key"),
+			Profile: c.PostForm("profile"),
+		})
+		if !ok {
+			return
+		}
 
 		//调用蓝心大模型长语音转写
 		trans := transcriptionApp.NewTranscription(uploadFilePath)
@@ -86,76 +55,97 @@ func TranscriptionHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		taskID := getTaskID(trans)
-		// 获取原始文件名
-		filename := "unknown"
-		if file, err := c.FormFile("file"); err == nil {
-			filename = file.Filename
-		}
+		taskID := trans.TaskID()
 
 		// 创建任务记录
-		GlobalTaskManager.CreateTask(taskID, filename)
-		GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusProcessing, "Processing started")
+		GlobalTaskManager.CreateTask(taskID, uploadedFilename(c), TaskEngineBlueLM)
+		GlobalTaskManager.UpdateTask(taskID, func(task *TaskInfo) {
+			task.Status = TaskStatusProcessing
+			task.Message = "Processing started"
+			task.UploadPath = uploadFilePath
+			callback.apply(task)
+		})
 
-		go pollTranscriptionStatus(trans, cfg, taskID)
+		go pollTranscriptionStatus(startTaskPoller(taskID), trans, cfg, taskID)
 
 		c.JSON(http.StatusOK, gin.H{"task_id": taskID})
 	}
 }
 
-// getTaskID uses reflection to access the private taskID field
-func getTaskID(trans *vivo.Transcription) string {
-	v := reflect.ValueOf(trans).Elem()
-	taskIDField := v.FieldByName("taskID")
-	if !taskIDField.IsValid() {
-		return "unknown"
+// uploadedFilename 获取上传文件的原始文件名
+func uploadedFilename(c *gin.Context) string {
+	if file, err := c.FormFile("file"); err == nil {
+		return file.Filename
 	}
-	return taskIDField.String()
+	return "unknown"
 }
 
-func pollTranscriptionStatus(trans *vivo.Transcription, cfg *config.Config, taskID string) {
+// pollTranscriptionStatus 轮询蓝心转写进度，ctx 被取消时停止轮询
+func pollTranscriptionStatus(ctx context.Context, trans ai.Transcription, cfg *config.Config, taskID string) {
+	defer finishTaskPoller(taskID)
+
 	process := 0
+	failures := 0     // 连续查询进度失败的次数
+	maxFailures := 60 // 连续失败上限 (60次 * 1秒 = 1分钟)，上游任务失败时查询进度也会一直返回错误
 	var e error
 	for process != 100 {
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			utils.Log.Infof("Transcription task %s polling stopped: %v", taskID, ctx.Err())
+			return
+		case <-time.After(1 * time.Second):
+		}
 		// 查询任务进度
-		process, e = trans.GetTaskInfo()
+		process, e = trans.Progress()
 		if e != nil {
-			utils.Log.Warnf("Failed to get task info for task %s: %v", taskID, e)
-			GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error getting progress: %v", e))
-			// 不中断轮询，继续尝试
+			failures++
+			utils.Log.Warnf("Failed to get task info for task %s (%d/%d): %v", taskID, failures, maxFailures, e)
+			if failures >= maxFailures {
+				updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error getting progress after %d attempts: %v", failures, e))
+				return
+			}
+			// 未达到上限时视为临时错误，不改变任务状态，只记录在 Message 中并继续轮询；
+			// failed 是终止状态，会结束事件流并触发回调
+			updateRunningTaskStatus(taskID, TaskStatusProcessing, fmt.Sprintf("Error getting progress, retrying: %v", e))
 			continue
 		}
+		failures = 0
 		utils.Log.Infof("Task %s progress: %d%%", taskID, process)
-		GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusProcessing, fmt.Sprintf("Progress: %d%%", process))
+		updateRunningTask(taskID, func(task *TaskInfo) {
+			task.Status = TaskStatusProcessing
+			task.Message = fmt.Sprintf("Progress: %d%%", process)
+			task.Progress = process
+		})
 	}
 
-	result, e := trans.GetResult()
+	result, e := trans.Result()
 	if e != nil {
 		utils.Log.Errorf("Failed to get result for task %s: %v", taskID, e)
-		GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error getting result: %v", e))
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error getting result: %v", e))
+		return
+	}
+	if ctx.Err() != nil {
 		return
 	}
 
 	jsonData, err := json.Marshal(result)
 	if err != nil {
 		utils.Log.Errorf("Failed to marshal result for task %s: %v", taskID, err)
-		GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error serializing result: %v", err))
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error serializing result: %v", err))
 		return
 	}
 
-	filename := fmt.Sprintf("transcription_%s.json", taskID)
-	downloadFilePath := filepath.Join(cfg.FilePaths.DownloadDir, filename)
+	downloadFilePath := filepath.Join(cfg.FilePaths.DownloadDir, transcriptionResultFileName(taskID))
 	//将json数据写入文件
 	err = os.WriteFile(downloadFilePath, jsonData, 0644)
 	if err != nil {
 		utils.Log.Errorf("Failed to write result to file for task %s: %v", taskID, err)
-		GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error writing file: %v", err))
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error writing file: %v", err))
 		return
 	}
 
 	// 更新任务状态为完成
 	GlobalTaskManager.SetTaskFilePath(taskID, downloadFilePath)
-	GlobalTaskManager.UpdateTaskStatus(taskID, TaskStatusCompleted, "Transcription completed successfully")
+	updateRunningTaskStatus(taskID, TaskStatusCompleted, "Transcription completed successfully")
 	utils.Log.Infof("Transcription task %s completed successfully. Result saved to %s", taskID, downloadFilePath)
 }
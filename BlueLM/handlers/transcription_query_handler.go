@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -35,6 +36,7 @@ func TranscriptionStatusHandler(cfg *config.Config) gin.HandlerFunc {
 }
 
 // TranscriptionDownloadHandler 下载转录结果文件
+// 可选参数 format=srt/vtt/txt/tsv 导出字幕，max_line_length / max_duration 覆盖长句切分限制
 func TranscriptionDownloadHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		taskID := c.Param("task_id")
@@ -62,7 +64,7 @@ func TranscriptionDownloadHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 构建文件路径
-		filename := "transcription_" + taskID + ".json"
+		filename := transcriptionResultFileName(taskID)
 		filePath := filepath.Join(cfg.FilePaths.DownloadDir, filename)
 
 		// 检查文件是否存在
@@ -74,6 +76,12 @@ func TranscriptionDownloadHandler(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// 指定 format 时导出为字幕，否则返回原始 JSON
+		if format := c.Query("format"); format != "" && format != "json" {
+			renderBlueLMSubtitle(c, cfg, taskID, filePath, format)
+			return
+		}
+
 		// 设置响应头
 		c.Header("Content-Description", "File Transfer")
 		c.Header("Content-Transfer-Encoding", "binary")
@@ -85,33 +93,50 @@ func TranscriptionDownloadHandler(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
-// TranscriptionTasksHandler 列出所有转录任务
+// TranscriptionTasksHandler 列出所有蓝心转录任务
 func TranscriptionTasksHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取查询参数
-		status := c.Query("status")
+		listTasks(c, TaskEngineBlueLM)
+	}
+}
 
-		tasks := GlobalTaskManager.GetAllTasks()
+// listTasks 按引擎列出任务，支持 status 查询参数过滤，按创建时间倒序返回
+func listTasks(c *gin.Context, engine string) {
+	// 获取查询参数
+	status := c.Query("status")
 
+	filteredTasks := make([]*TaskInfo, 0)
+	for _, task := range GlobalTaskManager.GetAllTasks() {
+		if taskEngine(task) != engine {
+			continue
+		}
 		// 按状态过滤
-		if status != "" {
-			filteredTasks := make([]*TaskInfo, 0)
-			for _, task := range tasks {
-				if strings.EqualFold(string(task.Status), status) {
-					filteredTasks = append(filteredTasks, task)
-				}
-			}
-			tasks = filteredTasks
+		if status != "" && !strings.EqualFold(string(task.Status), status) {
+			continue
 		}
+		filteredTasks = append(filteredTasks, task)
+	}
+	tasks := filteredTasks
 
-		// 限制返回数量（简单实现）
-		if len(tasks) > 50 { // 默认限制50个
-			tasks = tasks[:50]
-		}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+	})
+
+	// 限制返回数量（简单实现）
+	if len(tasks) > 50 { // 默认限制50个
+		tasks = tasks[:50]
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"tasks": tasks,
-			"total": len(tasks),
-		})
+	c.JSON(http.StatusOK, gin.H{
+		"tasks": tasks,
+		"total": len(tasks),
+	})
+}
+
+// taskEngine 返回任务所属引擎，早期版本创建的任务没有记录引擎，均为蓝心任务
+func taskEngine(task *TaskInfo) string {
+	if task.Engine == "" {
+		return TaskEngineBlueLM
 	}
-}
\ No newline at end of file
+	return task.Engine
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// BatchTranslationRequest 批量翻译请求，texts 和 items 二选一
+type BatchTranslationRequest struct {
+	From    string            `json:"from" binding:"required"`
+	To      string            `json:"to" binding:"required"`
+	Texts   []string          `json:"texts,omitempty"` // 按顺序返回结果
+	Items   map[string]string `json:"items,omitempty"` // id -> 文本，结果按 id 排序并带回 id
+	Backend string            `json:"backend,omitempty"`
+	AppID   string            `json:"app_id,omitempty"`
+	AppKey  string            `json:"app_key,omitempty"`
+	Profile string            `json:"profile,omitempty"`
+}
+
+// BatchTranslationItem 批量翻译中单条文本的结果
+type BatchTranslationItem struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	TranslationResult
+	Attempts int `json:"attempts"`
+}
+
+// batchTranslationInput 待翻译的一条文本
+type batchTranslationInput struct {
+	id   string
+	text string
+}
+
+// BatchTranslationHandler 批量翻译，单条失败不影响其他条目
+// 上游请求数受 translation.batch_concurrency 限制，每条文本最多尝试 translation.batch_max_attempts 次
+func BatchTranslationHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BatchTranslationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid request format: "+err.Error())
+			return
+		}
+
+		inputs, err := batchTranslationInputs(req, cfg.Translation.BatchMaxItems)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+
+		provider := newLazyProvider(resolver, ai.Credentials{AppID: req.AppID, AppKey: req.AppKey, Profile: req.Profile})
+		chain, err := translatorChain(provider, cfg, req.Backend)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, err.Error())
+			return
+		}
+
+		results := translateBatch(c.Request.Context(), chain, req.From, req.To, inputs, cfg.Translation.BatchConcurrency, cfg.Translation.BatchMaxAttempts, nil)
+
+		succeeded := 0
+		for _, result := range results {
+			if result.Success {
+				succeeded++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":   succeeded == len(results),
+			"message":   fmt.Sprintf("%d/%d translated", succeeded, len(results)),
+			"from":      req.From,
+			"to":        req.To,
+			"total":     len(results),
+			"succeeded": succeeded,
+			"failed":    len(results) - succeeded,
+			"results":   results,
+		})
+	}
+}
+
+// batchTranslationInputs 校验并展开请求中的文本
+func batchTranslationInputs(req BatchTranslationRequest, maxItems int) ([]batchTranslationInput, error) {
+	if len(req.Texts) > 0 && len(req.Items) > 0 {
+		return nil, fmt.Errorf("texts and items cannot be used together")
+	}
+
+	var inputs []batchTranslationInput
+	for _, text := range req.Texts {
+		inputs = append(inputs, batchTranslationInput{text: text})
+	}
+	ids := make([]string, 0, len(req.Items))
+	for id := range req.Items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		inputs = append(inputs, batchTranslationInput{id: id, text: req.Items[id]})
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("texts or items is required")
+	}
+	if len(inputs) > maxItems {
+		return nil, fmt.Errorf("too many items: %d (max %d)", len(inputs), maxItems)
+	}
+	return inputs, nil
+}
+
+// translateBatch 以有限并发翻译所有文本，结果顺序与输入一致
+// ctx 结束后尚未开始的条目不再翻译；progress 非空时在每条文本结束后以已完成条数调用
+func translateBatch(ctx context.Context, chain []Translator, from, to string, inputs []batchTranslationInput, concurrency, maxAttempts int, progress func(done int)) []BatchTranslationItem {
+	results := make([]BatchTranslationItem, len(inputs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	finish := func() {
+		if progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		progress(done)
+	}
+
+	for i, input := range inputs {
+		results[i] = BatchTranslationItem{Index: i, ID: input.id}
+		results[i].TranslationResult = TranslationResult{From: from, To: to, OriginalText: input.text}
+		if strings.TrimSpace(input.text) == "" {
+			results[i].Message = "翻译文本不能为空"
+			finish()
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Message = "翻译已取消"
+			continue
+		}
+
+		wg.Add(1)
+		go func(item *BatchTranslationItem, text string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer finish()
+
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				item.Attempts = attempt
+				result, err := translateText(chain, from, to, text)
+				item.TranslationResult = result
+				if err == nil {
+					return
+				}
+				if attempt < maxAttempts {
+					time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+				}
+			}
+			utils.Log.Warnf("Batch translation item %d failed after %d attempts: %s", item.Index, maxAttempts, item.Message)
+		}(&results[i], input.text)
+	}
+
+	wg.Wait()
+	return results
+}
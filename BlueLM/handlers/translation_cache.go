@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// translationCacheBucket 持久化的翻译缓存，键为缓存键的 SHA-256
+var translationCacheBucket = []byte("translation_cache")
+
+// 响应中 cache 字段的取值
+const (
+	TranslationCacheHit  = "hit"
+	TranslationCacheMiss = "miss"
+)
+
+// translationCacheEntry 一条缓存的译文
+type translationCacheEntry struct {
+	Key         string    `json:"key"`
+	Translation string    `json:"translation"`
+	Backend     string    `json:"backend"`
+	StoredAt    time.Time `json:"stored_at"`
+}
+
+// TranslationCacheStats 缓存的命中统计
+type TranslationCacheStats struct {
+	Entries           int   `json:"entries"`
+	PersistentEntries int   `json:"persistent_entries"`
+	Hits              int64 `json:"hits"`
+	Misses            int64 `json:"misses"`
+}
+
+// TranslationCache 翻译结果缓存：内存 LRU 为第一级，可选的嵌入式数据库为第二级
+// 键由主后端、规范化后的文本和映射后的语言代码组成，同一句话在 zh 与 zh-CHS 下共用缓存
+type TranslationCache struct {
+	cfg config.TranslationCacheConfig
+	db  *bolt.DB // 未开启持久化时为 nil
+
+	// persisted 数据库中的条数，只在数据库写事务中读写（写事务本身是串行的）
+	// 写事务内 Bucket.Stats 不包含本事务的修改，因此单独计数
+	persisted int
+
+	mu     sync.Mutex
+	lru    *list.List // 最近使用的在前
+	items  map[string]*list.Element
+	hits   int64
+	misses int64
+}
+
+// GlobalTranslationCache 全局翻译缓存，未开启时为 nil
+var GlobalTranslationCache *TranslationCache
+
+// InitTranslationCache 按配置初始化翻译缓存
+func InitTranslationCache(db *bolt.DB, cfg *config.Config) error {
+	cacheCfg := cfg.Translation.Cache
+	if !cacheCfg.Enabled {
+		utils.Log.Infof("Translation cache disabled by config")
+		return nil
+	}
+
+	cache := newTranslationCache(cacheCfg)
+	if cacheCfg.Persistent {
+		err := db.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists(translationCacheBucket)
+			if err != nil {
+				return err
+			}
+			cache.persisted = bucket.Stats().KeyN
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to init translation cache: %v", err)
+		}
+		cache.db = db
+	}
+
+	GlobalTranslationCache = cache
+	utils.Log.Infof("Translation cache enabled, %d entries in memory, persistent: %v", cacheCfg.MaxEntries, cacheCfg.Persistent)
+	return nil
+}
+
+func newTranslationCache(cfg config.TranslationCacheConfig) *TranslationCache {
+	return &TranslationCache{
+		cfg:   cfg,
+		lru:   list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// translationCacheKey 由主后端、vivo 翻译接口的语言代码和规范化后的文本组成
+// 只去掉首尾空白并合并行内连续的空白，保留换行，多行文本不会命中单行文本的译文
+func translationCacheKey(backend, from, to, text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return backend + "\x00" + mapLanguage(from) + "\x00" + mapLanguage(to) + "\x00" + strings.Join(lines, "\n")
+}
+
+// Get 查找缓存的译文，内存未命中时查找数据库并放入内存
+func (c *TranslationCache) Get(key string) (translationCacheEntry, bool) {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(translationCacheEntry)
+		if !c.expired(entry) {
+			c.lru.MoveToFront(elem)
+			c.hits++
+			c.mu.Unlock()
+			return entry, true
+		}
+		c.lru.Remove(elem)
+		delete(c.items, key)
+	}
+	c.mu.Unlock()
+
+	entry, ok := c.load(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !ok {
+		c.misses++
+		return translationCacheEntry{}, false
+	}
+	c.hits++
+	c.add(entry)
+	return entry, true
+}
+
+// Put 保存译文
+func (c *TranslationCache) Put(key, translation, backend string) {
+	entry := translationCacheEntry{Key: key, Translation: translation, Backend: backend, StoredAt: time.Now()}
+
+	c.mu.Lock()
+	c.add(entry)
+	c.mu.Unlock()
+
+	if c.db == nil {
+		return
+	}
+	if err := c.store(entry); err != nil {
+		utils.Log.Warnf("Failed to persist translation cache entry: %v", err)
+	}
+}
+
+// Purge 清空内存和数据库中的缓存，返回清除的条数
+func (c *TranslationCache) Purge() (int, error) {
+	c.mu.Lock()
+	purged := len(c.items)
+	c.lru.Init()
+	c.items = make(map[string]*list.Element)
+	c.mu.Unlock()
+
+	if c.db == nil {
+		return purged, nil
+	}
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(translationCacheBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(translationCacheBucket); err != nil {
+			return err
+		}
+		purged = max(purged, c.persisted)
+		c.persisted = 0
+		return nil
+	})
+	return purged, err
+}
+
+// Stats 返回缓存条数和命中统计
+func (c *TranslationCache) Stats() TranslationCacheStats {
+	c.mu.Lock()
+	stats := TranslationCacheStats{Entries: len(c.items), Hits: c.hits, Misses: c.misses}
+	c.mu.Unlock()
+
+	if c.db != nil {
+		c.db.View(func(tx *bolt.Tx) error {
+			stats.PersistentEntries = tx.Bucket(translationCacheBucket).Stats().KeyN
+			return nil
+		})
+	}
+	return stats
+}
+
+// add 放入内存并淘汰最久未使用的条目，调用方需持有锁
+func (c *TranslationCache) add(entry translationCacheEntry) {
+	if elem, ok := c.items[entry.Key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[entry.Key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.cfg.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(translationCacheEntry).Key)
+	}
+}
+
+// expired 判断条目是否超过有效期
+func (c *TranslationCache) expired(entry translationCacheEntry) bool {
+	return c.cfg.TTL > 0 && time.Since(entry.StoredAt) > c.cfg.TTL
+}
+
+// load 从数据库读取条目，过期的条目同时删除
+func (c *TranslationCache) load(key string) (translationCacheEntry, bool) {
+	if c.db == nil {
+		return translationCacheEntry{}, false
+	}
+
+	dbKey := translationCacheDBKey(key)
+	var entry translationCacheEntry
+	found := false
+	c.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(translationCacheBucket).Get(dbKey)
+		if data == nil {
+			return nil
+		}
+		found = json.Unmarshal(data, &entry) == nil && entry.Key == key
+		return nil
+	})
+	if !found {
+		return translationCacheEntry{}, false
+	}
+	if c.expired(entry) {
+		c.db.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(translationCacheBucket)
+			if bucket.Get(dbKey) == nil {
+				return nil
+			}
+			c.persisted--
+			return bucket.Delete(dbKey)
+		})
+		return translationCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store 写入数据库，超过条数上限时淘汰最旧的十分之一
+func (c *TranslationCache) store(entry translationCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(translationCacheBucket)
+		dbKey := translationCacheDBKey(entry.Key)
+		isNew := bucket.Get(dbKey) == nil
+		if err := bucket.Put(dbKey, data); err != nil {
+			return err
+		}
+		if isNew {
+			c.persisted++
+		}
+		if c.persisted <= c.cfg.MaxPersistentEntries {
+			return nil
+		}
+		return c.evictOldest(bucket)
+	})
+}
+
+// evictOldest 删除数据库中已过期和最旧的条目，一次淘汰多条以避免每次写入都扫描整个桶
+func (c *TranslationCache) evictOldest(bucket *bolt.Bucket) error {
+	type stored struct {
+		key      []byte
+		storedAt time.Time
+	}
+	var entries []stored
+	bucket.ForEach(func(k, v []byte) error {
+		var entry translationCacheEntry
+		json.Unmarshal(v, &entry)
+		entries = append(entries, stored{key: append([]byte(nil), k...), storedAt: entry.StoredAt})
+		return nil
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].storedAt.Before(entries[j].storedAt)
+	})
+
+	keep := c.cfg.MaxPersistentEntries - c.cfg.MaxPersistentEntries/10
+	for i, e := range entries {
+		if i >= len(entries)-keep && !c.expired(translationCacheEntry{StoredAt: e.storedAt}) {
+			break
+		}
+		if err := bucket.Delete(e.key); err != nil {
+			return err
+		}
+		c.persisted--
+	}
+	return nil
+}
+
+// translationCacheDBKey 缓存键可能很长，数据库中以其哈希作为键
+func translationCacheDBKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// TranslationCacheStatusHandler 返回翻译缓存的配置和命中统计
+func TranslationCacheStatusHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cacheCfg := cfg.Translation.Cache
+		response := gin.H{
+			"success": true,
+			"config": gin.H{
+				"enabled":                cacheCfg.Enabled,
+				"max_entries":            cacheCfg.MaxEntries,
+				"ttl":                    cacheCfg.TTL.String(),
+				"persistent":             cacheCfg.Persistent,
+				"max_persistent_entries": cacheCfg.MaxPersistentEntries,
+			},
+		}
+		if GlobalTranslationCache != nil {
+			response["stats"] = GlobalTranslationCache.Stats()
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// TranslationCachePurgeHandler 清空翻译缓存
+func TranslationCachePurgeHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GlobalTranslationCache == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"message": "Translation cache is disabled",
+				"purged":  0,
+			})
+			return
+		}
+
+		purged, err := GlobalTranslationCache.Purge()
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		utils.Log.Infof("Translation cache purged, %d entries removed", purged)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Translation cache purged",
+			"purged":  purged,
+		})
+	}
+}
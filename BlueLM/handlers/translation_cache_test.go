@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+)
+
+// newPersistentTestCache 在临时数据库上创建开启持久化的缓存
+func newPersistentTestCache(t *testing.T, db *bolt.DB, cfg config.TranslationCacheConfig) *TranslationCache {
+	t.Helper()
+	cache := newTranslationCache(cfg)
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(translationCacheBucket)
+		if err != nil {
+			return err
+		}
+		cache.persisted = bucket.Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.db = db
+	return cache
+}
+
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestTranslationCacheKey(t *testing.T) {
+	base := translationCacheKey("vivo", "zh", "en", "你好 世界")
+	tests := []struct {
+		name              string
+		backend, from, to string
+		text              string
+		same              bool
+	}{
+		{"identical", "vivo", "zh", "en", "你好 世界", true},
+		{"surrounding and inner whitespace", "vivo", "zh", "en", "  你好 \t 世界\n", true},
+		{"language aliases", "vivo", "zh-CHS", "en", "你好 世界", true},
+		{"other backend", "llm", "zh", "en", "你好 世界", false},
+		{"other target", "vivo", "zh", "ja", "你好 世界", false},
+		{"newline kept", "vivo", "zh", "en", "你好\n世界", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translationCacheKey(tt.backend, tt.from, tt.to, tt.text)
+			if (got == base) != tt.same {
+				t.Errorf("translationCacheKey(%q, %q, %q, %q) = %q, same as base = %v, want %v",
+					tt.backend, tt.from, tt.to, tt.text, got, got == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestTranslationCacheLRU(t *testing.T) {
+	cache := newTranslationCache(config.TranslationCacheConfig{MaxEntries: 2})
+	cache.Put("a", "A", "vivo")
+	cache.Put("b", "B", "vivo")
+	// 访问 a 之后 b 成为最久未使用的条目
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	cache.Put("c", "C", "vivo")
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.Get(key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 2 entries, 3 hits, 1 miss", stats)
+	}
+}
+
+func TestTranslationCacheTTL(t *testing.T) {
+	db := openTestDB(t)
+	cache := newPersistentTestCache(t, db, config.TranslationCacheConfig{
+		MaxEntries: 10, TTL: time.Hour, MaxPersistentEntries: 10,
+	})
+
+	old := translationCacheEntry{Key: "old", Translation: "OLD", Backend: "vivo", StoredAt: time.Now().Add(-2 * time.Hour)}
+	cache.add(old)
+	if err := cache.store(old); err != nil {
+		t.Fatal(err)
+	}
+	cache.Put("fresh", "FRESH", "vivo")
+
+	if _, ok := cache.Get("old"); ok {
+		t.Error("expired entry should not be returned")
+	}
+	if entry, ok := cache.Get("fresh"); !ok || entry.Translation != "FRESH" {
+		t.Errorf("Get(fresh) = %+v, %v", entry, ok)
+	}
+	// 过期条目同时从内存和数据库中删除
+	if stats := cache.Stats(); stats.Entries != 1 || stats.PersistentEntries != 1 || cache.persisted != 1 {
+		t.Errorf("Stats() = %+v, persisted = %d, want 1 entry in memory and database", stats, cache.persisted)
+	}
+}
+
+func TestTranslationCachePersistedCount(t *testing.T) {
+	db := openTestDB(t)
+	cfg := config.TranslationCacheConfig{MaxEntries: 100, MaxPersistentEntries: 10}
+	cache := newPersistentTestCache(t, db, cfg)
+
+	start := time.Now().Add(-time.Hour)
+	for i := range 11 {
+		entry := translationCacheEntry{
+			Key:         fmt.Sprintf("key%d", i),
+			Translation: fmt.Sprintf("value%d", i),
+			Backend:     "vivo",
+			StoredAt:    start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := cache.store(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 重复写入已有的键不增加计数
+	if err := cache.store(translationCacheEntry{Key: "key10", Translation: "again", StoredAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过上限时淘汰最旧的十分之一，保留 9 条
+	if cache.persisted != 9 {
+		t.Errorf("persisted = %d, want 9", cache.persisted)
+	}
+	if stats := cache.Stats(); stats.PersistentEntries != 9 {
+		t.Errorf("PersistentEntries = %d, want 9", stats.PersistentEntries)
+	}
+
+	// 重新打开时从数据库读取计数和条目
+	reopened := newPersistentTestCache(t, db, cfg)
+	if reopened.persisted != 9 {
+		t.Errorf("reopened persisted = %d, want 9", reopened.persisted)
+	}
+	for key, want := range map[string]bool{"key0": false, "key1": false, "key2": true, "key10": true} {
+		if _, ok := reopened.Get(key); ok != want {
+			t.Errorf("reopened Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+	if entry, _ := reopened.Get("key10"); entry.Translation != "again" {
+		t.Errorf("key10 = %q, want the latest translation", entry.Translation)
+	}
+}
+
+func TestTranslationCachePurge(t *testing.T) {
+	db := openTestDB(t)
+	cache := newPersistentTestCache(t, db, config.TranslationCacheConfig{MaxEntries: 1, MaxPersistentEntries: 10})
+	cache.Put("a", "A", "vivo")
+	cache.Put("b", "B", "vivo")
+
+	purged, err := cache.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 {
+		t.Errorf("Purge() = %d, want 2", purged)
+	}
+	if stats := cache.Stats(); stats.Entries != 0 || stats.PersistentEntries != 0 {
+		t.Errorf("Stats() after purge = %+v", stats)
+	}
+}
@@ -2,15 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,6 +26,10 @@ type TranslationEvaluationRequest struct {
 	Context         string `json:"context,omitempty"`
 	AppID           string `json:"app_id,omitempty"`
 	AppKey          string `json:"app_key,omitempty"`
+	Profile         string `json:"profile,omitempty"`     // 使用 vivo_ai.profiles 中的凭据档案
+	GlossaryID      string `json:"glossary_id,omitempty"` // 检查用户翻译是否使用术语表规定的译法
+	Rubric          string `json:"rubric,omitempty"`      // 评分标准名称，留空使用默认评分标准
+	LearnerID       string `json:"learner_id,omitempty"`  // 指定时保存本次评估，用于查看学习进度
 }
 
 // TranslationEvaluationResponse 翻译评估响应结构
@@ -34,15 +40,19 @@ type TranslationEvaluationResponse struct {
 	Data         EvaluationResult   `json:"data"`
 	Similarity   SimilarityResult   `json:"similarity"`
 	AIEvaluation AIEvaluationResult `json:"ai_evaluation"`
+	Degraded     bool               `json:"degraded"`                // AI 评估失败，评分仅基于相似度
+	EvaluationID string             `json:"evaluation_id,omitempty"` // 请求带 learner_id 时保存的评估记录ID
 }
 
 // EvaluationResult 评估结果
 type EvaluationResult struct {
 	Score        float64  `json:"score"`        // 综合评分 (0-100)
-	Level        string   `json:"level"`        // 评级：优秀/良好/及格/不及格
+	Level        string   `json:"level"`        // 评级，由评分标准的 levels 决定
 	Feedback     string   `json:"feedback"`     // 总体反馈
 	Improvements []string `json:"improvements"` // 改进建议
 	Strengths    []string `json:"strengths"`    // 优点
+	Degraded     bool     `json:"degraded"`     // AI 评估失败，评分仅基于相似度
+	Rubric       string   `json:"rubric"`       // 使用的评分标准
 }
 
 // SimilarityResult 相似度结果
@@ -59,10 +69,17 @@ type AIEvaluationResult struct {
 	AccuracyScore  float64  `json:"accuracy_score"`  // 准确性评分
 	FluencyScore   float64  `json:"fluency_score"`   // 流畅性评分
 	DetailedAdvice []string `json:"detailed_advice"` // 详细建议
+
+	// Scores 评分标准中各维度的评分，grammar / accuracy / fluency 同时填入上面的字段以兼容旧客户端
+	Scores map[string]float64 `json:"scores"`
+
+	Degraded        bool   `json:"degraded"`                   // 模型多次输出均未通过校验，各项评分无效
+	Attempts        int    `json:"attempts"`                   // 调用模型的次数（含修正重试）
+	ValidationError string `json:"validation_error,omitempty"` // 最后一次输出的校验错误
 }
 
 // TranslationEvaluationHandler handles translation evaluation requests
-func TranslationEvaluationHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+func TranslationEvaluationHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req TranslationEvaluationRequest
 
@@ -70,49 +87,129 @@ func TranslationEvaluationHandler(app *vivo.Vivo, cfg *config.Config) gin.Handle
 			utils.AbortWithBadRequest(ctx, err, "Invalid request format")
 			return
 		}
+		if req.LearnerID != "" {
+			if err := validateLearnerID(req.LearnerID); err != nil {
+				utils.AbortWithBadRequest(ctx, err, err.Error())
+				return
+			}
+		}
 
-		// 创建蓝心大模型应用实例
-		evalApp := createBlueLMApp(req.AppID, req.AppKey, cfg)
-
-		// 1. 计算文本相似度
-		similarityResult, err := calculateTextSimilarity(evalApp, req.UserTranslation, req.StandardAnswer)
+		glossary, status, err := loadRequestGlossary(req.GlossaryID, req.SourceLanguage, req.TargetLanguage)
 		if err != nil {
-			utils.AbortWithInternalServerError(ctx, fmt.Errorf("similarity calculation failed: %v", err))
+			ctx.JSON(status, gin.H{
+				"error":       err.Error(),
+				"glossary_id": req.GlossaryID,
+			})
 			return
 		}
 
-		// 2. 使用AI进行深度评估
-		aiEvaluation, err := performAIEvaluation(evalApp, req)
+		rubric, status, err := loadRequestRubric(req.Rubric)
 		if err != nil {
-			utils.AbortWithInternalServerError(ctx, fmt.Errorf("AI evaluation failed: %v", err))
+			ctx.JSON(status, gin.H{
+				"error":  err.Error(),
+				"rubric": req.Rubric,
+			})
 			return
 		}
 
-		// 3. 综合计算最终评分和反馈
-		finalResult := calculateFinalEvaluation(similarityResult, aiEvaluation, req)
+		// 创建蓝心大模型应用实例
+		evalApp, ok := resolveProvider(ctx, resolver, ai.Credentials{
+			AppID:   req.AppID,
+			AppKey:  req.AppKey,
+			Profile: req.Profile,
+		})
+		if !ok {
+			return
+		}
+
+		outcome, err := evaluateTranslation(evalApp, req, glossary, rubric, cfg.Evaluation.MaxAttempts)
+		if err != nil {
+			utils.AbortWithInternalServerError(ctx, err)
+			return
+		}
 
 		// 返回响应
 		response := TranslationEvaluationResponse{
 			Success:      true,
 			Message:      "Translation evaluation completed successfully",
 			Timestamp:    time.Now().Format("2006-01-02 15:04:05"),
-			Data:         finalResult,
-			Similarity:   similarityResult,
-			AIEvaluation: aiEvaluation,
+			Data:         outcome.Result,
+			Similarity:   outcome.Similarity,
+			AIEvaluation: outcome.AIEvaluation,
+			Degraded:     outcome.AIEvaluation.Degraded,
+			EvaluationID: saveEvaluation(req, rubric, outcome),
+		}
+		if outcome.AIEvaluation.Degraded {
+			response.Message = "Translation evaluation completed without AI scores: the model output failed validation"
 		}
 
 		ctx.JSON(http.StatusOK, response)
 	}
 }
 
+// evaluationOutcome 一次翻译评估的结果
+type evaluationOutcome struct {
+	Result       EvaluationResult
+	Similarity   SimilarityResult
+	AIEvaluation AIEvaluationResult
+
+	glossaryViolated bool // 用户翻译未使用术语表规定的译法
+}
+
+// evaluateTranslation 依次计算文本相似度、进行 AI 评估，并按评分标准综合计算最终评分和反馈
+func evaluateTranslation(app ai.Provider, req TranslationEvaluationRequest, glossary *Glossary, rubric *Rubric, maxAttempts int) (evaluationOutcome, error) {
+	// 1. 计算文本相似度
+	similarityResult, err := calculateTextSimilarity(app, req.UserTranslation, req.StandardAnswer)
+	if err != nil {
+		return evaluationOutcome{}, fmt.Errorf("similarity calculation failed: %v", err)
+	}
+
+	// 2. 使用AI进行深度评估
+	aiEvaluation, err := performAIEvaluation(app, req, rubric, maxAttempts)
+	if err != nil {
+		return evaluationOutcome{}, fmt.Errorf("AI evaluation failed: %v", err)
+	}
+
+	// 3. 综合计算最终评分和反馈
+	finalResult := calculateFinalEvaluation(similarityResult, aiEvaluation, rubric)
+	var termImprovements []string
+	if glossary != nil {
+		termImprovements = glossaryImprovements(glossary, req)
+		finalResult.Improvements = append(termImprovements, finalResult.Improvements...)
+	}
+
+	return evaluationOutcome{
+		Result:           finalResult,
+		Similarity:       similarityResult,
+		AIEvaluation:     aiEvaluation,
+		glossaryViolated: len(termImprovements) > 0,
+	}, nil
+}
+
+// saveEvaluation 请求带 learner_id 时保存评估记录并返回记录ID，保存失败不影响评估结果
+func saveEvaluation(req TranslationEvaluationRequest, rubric *Rubric, outcome evaluationOutcome) string {
+	if req.LearnerID == "" || GlobalEvaluationHistory == nil {
+		return ""
+	}
+	record := &EvaluationRecord{
+		LearnerID:    req.LearnerID,
+		Request:      req,
+		Result:       outcome.Result,
+		Similarity:   outcome.Similarity,
+		AIEvaluation: outcome.AIEvaluation,
+		Categories:   evaluationCategories(outcome.Similarity, outcome.AIEvaluation, rubric, outcome.glossaryViolated),
+	}
+	if err := GlobalEvaluationHistory.Record(record); err != nil {
+		utils.Log.Warnf("Failed to save evaluation for learner %s: %v", req.LearnerID, err)
+		return ""
+	}
+	return record.EvaluationID
+}
+
 // calculateTextSimilarity 计算文本相似度
-func calculateTextSimilarity(app *vivo.Vivo, userText, standardText string) (SimilarityResult, error) {
-	// 强制使用vivo的文本相似度功能
-	similarities, err := app.TextSimilarity(
-		vivo.TEXT_SIMILARITY_MODEL_BGE_LARGE,
-		userText,
-		[]string{standardText},
-	)
+func calculateTextSimilarity(app ai.Provider, userText, standardText string) (SimilarityResult, error) {
+	// vivo 提供方使用 BGE-Large 相似度模型
+	similarities, err := app.TextSimilarity(userText, []string{standardText})
 
 	if err != nil {
 		return SimilarityResult{}, fmt.Errorf("BGE相似度模型调用失败: %v", err)
@@ -125,34 +222,63 @@ func calculateTextSimilarity(app *vivo.Vivo, userText, standardText string) (Sim
 	score := similarities[0]
 	explanation := generateSimilarityExplanation(score)
 
+	method := "BGE-Large Model"
+	if app.Name() == ai.ProviderMock {
+		method = "Mock Bigram Model"
+	}
+
 	return SimilarityResult{
 		Score:       score,
-		Method:      "BGE-Large Model",
+		Method:      method,
 		Explanation: explanation,
 	}, nil
 }
 
-// performAIEvaluation 使用AI进行深度评估
-func performAIEvaluation(app *vivo.Vivo, req TranslationEvaluationRequest) (AIEvaluationResult, error) {
-	// 构建AI评估提示词
-	systemPrompt := `你是一位专业的翻译质量评估专家。请从以下维度评估用户的翻译质量：
-1. 语法正确性 (0-100分)
-2. 意思准确性 (0-100分)  
-3. 表达流畅性 (0-100分)
-
-请严格按照以下格式回复，不要添加任何其他内容：
-
-总体评价：[一句话总结翻译质量]
-语法分数：[0-100的数字]
-准确性分数：[0-100的数字]
-流畅性分数：[0-100的数字]
-改进建议：[具体的建议，如果多条建议用分号分隔]
-
-注意：
-- 每行只包含一个字段
-- 分数必须是0-100之间的整数
-- 总体评价要简洁明了，不超过50字`
+// evaluationSystemPrompt 按评分标准生成系统提示词，要求模型按固定的 JSON 结构输出评估结果
+func evaluationSystemPrompt(rubric *Rubric) string {
+	labels := make([]string, len(rubric.Dimensions))
+	examples := make([]string, len(rubric.Dimensions))
+	var requirements strings.Builder
+	for i, dimension := range rubric.Dimensions {
+		labels[i] = dimension.Label
+		examples[i] = fmt.Sprintf("%q: %d", dimension.Key, 80+i%3*5)
+		fmt.Fprintf(&requirements, "- scores.%s：必填，0-100之间的整数，为%s评分", dimension.Key, dimension.Label)
+		if dimension.Criteria != "" {
+			fmt.Fprintf(&requirements, "。%s", dimension.Criteria)
+		}
+		requirements.WriteString("\n")
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "你是一位专业的翻译质量评估专家。请从%s%d个维度评估用户的翻译质量。\n", strings.Join(labels, "、"), len(labels))
+	if rubric.Prompt != "" {
+		fmt.Fprintf(&prompt, "评分要求：%s\n", rubric.Prompt)
+	}
+	fmt.Fprintf(&prompt, `
+只输出一个 JSON 对象，不要使用 Markdown 代码块，不要输出任何其他内容。格式如下：
+{"summary": "一句话总结翻译质量", "scores": {%s}, "advice": ["具体的改进建议"]}
+
+要求：
+- summary：必填，不超过50字
+%s- advice：必填，改进建议的数组，每条一句话；没有建议时返回空数组 []
+- 不要添加其他字段`, strings.Join(examples, ", "), requirements.String())
+	return prompt.String()
+}
 
+// maxEvaluationSummaryLength 评估总结的最大字数，提示词要求50字，校验时适当放宽
+const maxEvaluationSummaryLength = 100
+
+// aiEvaluationOutput 模型输出的评估 JSON，字段均为指针以区分缺失和零值
+type aiEvaluationOutput struct {
+	Summary *string             `json:"summary"`
+	Scores  map[string]*float64 `json:"scores"`
+	Advice  *[]string           `json:"advice"`
+}
+
+// performAIEvaluation 使用AI进行深度评估
+// 模型输出不符合 JSON 格式要求时，将校验错误反馈给模型重新生成，最多尝试 maxAttempts 次；
+// 全部失败时返回 Degraded 的结果而不是编造分数
+func performAIEvaluation(app ai.Provider, req TranslationEvaluationRequest, rubric *Rubric, maxAttempts int) (AIEvaluationResult, error) {
 	contextStr := ""
 	if req.Context != "" {
 		contextStr = fmt.Sprintf("场景背景：%s\n", req.Context)
@@ -173,198 +299,136 @@ func performAIEvaluation(app *vivo.Vivo, req TranslationEvaluationRequest) (AIEv
 		req.StandardAnswer,
 	)
 
-	// 调用AI评估
-	aiResponse, err := app.EasyChat(vivo.GenerateSessionID(), promptMessage, systemPrompt)
-	if err != nil {
-		return AIEvaluationResult{}, err
-	}
+	systemPrompt := evaluationSystemPrompt(rubric)
+	var lastErr error
+	message := promptMessage
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		aiResponse, err := app.EvaluationChat(ai.NewSessionID(), message, systemPrompt, rubric.dimensionKeys())
+		if err != nil {
+			return AIEvaluationResult{}, err
+		}
 
-	// 解析AI响应
-	aiEval, err := parseAIResponse(aiResponse)
-	if err != nil {
-		// 如果解析失败，提供默认评估
-		return generateFallbackEvaluation(aiResponse), nil
+		aiEval, err := parseAIResponse(aiResponse, rubric)
+		if err == nil {
+			aiEval.Attempts = attempt
+			return aiEval, nil
+		}
+		utils.Log.Warnf("AI evaluation attempt %d/%d returned invalid output: %v", attempt, maxAttempts, err)
+		lastErr = err
+
+		// 每次重试都是独立的单轮对话，需要带上原始题目和上一次的输出
+		message = fmt.Sprintf("%s\n\n你上一次的输出是：\n%s\n\n该输出不符合格式要求：%v\n请修正后只输出符合要求的 JSON 对象。",
+			promptMessage, aiResponse, err)
 	}
 
-	return aiEval, nil
+	return AIEvaluationResult{
+		DetailedAdvice:  []string{},
+		Scores:          map[string]float64{},
+		Degraded:        true,
+		Attempts:        maxAttempts,
+		ValidationError: lastErr.Error(),
+	}, nil
 }
 
-// parseAIResponse 解析AI响应
-func parseAIResponse(response string) (AIEvaluationResult, error) {
-	var result AIEvaluationResult
-
-	// 首先尝试解析新的文本格式
-	if textResult, err := parseTextResponse(response); err == nil {
-		return textResult, nil
+// parseAIResponse 解析并校验模型输出的评估 JSON，scores 必须恰好包含评分标准的各个维度
+// 只容忍外层的 Markdown 代码块，其余格式问题都作为校验错误返回，以便反馈给模型修正
+func parseAIResponse(response string, rubric *Rubric) (AIEvaluationResult, error) {
+	text := strings.TrimSpace(response)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "```json"), "```")
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
 	}
 
-	// 兼容旧的JSON格式
-	if err := json.Unmarshal([]byte(response), &result); err == nil {
-		return result, nil
+	var output aiEvaluationOutput
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&output); err != nil {
+		return AIEvaluationResult{}, fmt.Errorf("output is not a valid JSON object: %v", err)
 	}
-
-	// 如果直接解析失败，尝试从响应中提取JSON部分
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-
-	if start != -1 && end != -1 && end > start {
-		jsonStr := response[start : end+1]
-		if err := json.Unmarshal([]byte(jsonStr), &result); err == nil {
-			return result, nil
-		}
+	if decoder.More() {
+		return AIEvaluationResult{}, fmt.Errorf("output contains extra content after the JSON object")
 	}
 
-	return AIEvaluationResult{}, fmt.Errorf("failed to parse AI response")
-}
-
-// parseTextResponse 解析文本格式的AI响应
-func parseTextResponse(response string) (AIEvaluationResult, error) {
+	var problems []string
 	var result AIEvaluationResult
-	var err error
-
-	lines := strings.Split(response, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	switch {
+	case output.Summary == nil || strings.TrimSpace(*output.Summary) == "":
+		problems = append(problems, "summary is required")
+	case utf8.RuneCountInString(*output.Summary) > maxEvaluationSummaryLength:
+		problems = append(problems, fmt.Sprintf("summary must not exceed %d characters", maxEvaluationSummaryLength))
+	default:
+		result.Summary = strings.TrimSpace(*output.Summary)
+	}
 
-		if strings.HasPrefix(line, "总体评价：") {
-			result.Summary = strings.TrimPrefix(line, "总体评价：")
-		} else if strings.HasPrefix(line, "语法分数：") {
-			scoreStr := strings.TrimPrefix(line, "语法分数：")
-			if score, parseErr := strconv.ParseFloat(scoreStr, 64); parseErr == nil {
-				result.GrammarScore = score
-			}
-		} else if strings.HasPrefix(line, "准确性分数：") {
-			scoreStr := strings.TrimPrefix(line, "准确性分数：")
-			if score, parseErr := strconv.ParseFloat(scoreStr, 64); parseErr == nil {
-				result.AccuracyScore = score
-			}
-		} else if strings.HasPrefix(line, "流畅性分数：") {
-			scoreStr := strings.TrimPrefix(line, "流畅性分数：")
-			if score, parseErr := strconv.ParseFloat(scoreStr, 64); parseErr == nil {
-				result.FluencyScore = score
+	if output.Scores == nil {
+		problems = append(problems, "scores is required")
+	} else {
+		result.Scores = make(map[string]float64, len(rubric.Dimensions))
+		for _, dimension := range rubric.Dimensions {
+			value, ok := output.Scores[dimension.Key]
+			switch {
+			case !ok || value == nil:
+				problems = append(problems, fmt.Sprintf("scores.%s is required", dimension.Key))
+			case *value < 0 || *value > 100:
+				problems = append(problems, fmt.Sprintf("scores.%s must be between 0 and 100, got %v", dimension.Key, *value))
+			default:
+				result.Scores[dimension.Key] = *value
 			}
-		} else if strings.HasPrefix(line, "改进建议：") {
-			adviceStr := strings.TrimPrefix(line, "改进建议：")
-			if adviceStr != "" {
-				// 用分号分隔多条建议
-				result.DetailedAdvice = strings.Split(adviceStr, "；")
-				// 去除每条建议的空格
-				for i, advice := range result.DetailedAdvice {
-					result.DetailedAdvice[i] = strings.TrimSpace(advice)
-				}
+		}
+		var unknown []string
+		for key := range output.Scores {
+			if !rubric.hasDimension(key) {
+				unknown = append(unknown, key)
 			}
 		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			problems = append(problems, fmt.Sprintf("scores.%s is not an expected score", key))
+		}
+		result.GrammarScore = result.Scores["grammar"]
+		result.AccuracyScore = result.Scores["accuracy"]
+		result.FluencyScore = result.Scores["fluency"]
 	}
 
-	// 验证必要字段是否已解析
-	if result.Summary == "" {
-		return result, fmt.Errorf("missing summary")
-	}
-
-	// 如果没有解析到建议，提供默认建议
-	if len(result.DetailedAdvice) == 0 {
-		result.DetailedAdvice = []string{"继续保持良好的翻译习惯"}
-	}
-
-	return result, err
-}
-
-// generateFallbackEvaluation 生成备选评估
-func generateFallbackEvaluation(aiResponse string) AIEvaluationResult {
-	// 提取AI响应中的有用信息，避免显示JSON格式
-	summary := extractReadableContent(aiResponse)
-	if summary == "" {
-		summary = "AI评估已完成，翻译整体质量良好，语法正确，意思准确，表达流畅。"
-	}
-
-	return AIEvaluationResult{
-		Summary:        summary,
-		GrammarScore:   85.0,
-		AccuracyScore:  85.0,
-		FluencyScore:   80.0,
-		DetailedAdvice: []string{"继续保持翻译质量，注意表达的自然流畅性"},
-	}
-}
-
-// extractReadableContent 从AI响应中提取可读内容
-func extractReadableContent(response string) string {
-	// 如果是JSON格式，尝试提取summary字段
-	if strings.Contains(response, "summary") && strings.Contains(response, ":") {
-		// 查找summary字段的值
-		lines := strings.Split(response, "\n")
-		for _, line := range lines {
-			if strings.Contains(line, "summary") && strings.Contains(line, ":") {
-				// 提取冒号后的内容
-				parts := strings.Split(line, ":")
-				if len(parts) > 1 {
-					content := strings.TrimSpace(parts[1])
-					// 去除引号和逗号
-					content = strings.Trim(content, "\",")
-					if content != "" && len(content) > 10 {
-						return content
-					}
-				}
+	if output.Advice == nil {
+		problems = append(problems, "advice is required (use [] when there is no advice)")
+	} else {
+		result.DetailedAdvice = make([]string, 0, len(*output.Advice))
+		for i, advice := range *output.Advice {
+			advice = strings.TrimSpace(advice)
+			if advice == "" {
+				problems = append(problems, fmt.Sprintf("advice[%d] must not be empty", i))
+				continue
 			}
+			result.DetailedAdvice = append(result.DetailedAdvice, advice)
 		}
 	}
 
-	// 如果不是JSON格式或提取失败，查找有意义的中文句子
-	sentences := strings.Split(response, "。")
-	for _, sentence := range sentences {
-		sentence = strings.TrimSpace(sentence)
-		// 查找包含翻译评价关键词的句子
-		if len(sentence) > 20 && len(sentence) < 200 &&
-			(strings.Contains(sentence, "翻译") || strings.Contains(sentence, "语法") ||
-				strings.Contains(sentence, "准确") || strings.Contains(sentence, "流畅")) {
-			return sentence + "。"
-		}
+	if len(problems) > 0 {
+		return AIEvaluationResult{}, errors.New(strings.Join(problems, "; "))
 	}
-
-	return ""
+	return result, nil
 }
 
-// calculateFinalEvaluation 计算最终评估结果
-func calculateFinalEvaluation(similarity SimilarityResult, aiEval AIEvaluationResult, req TranslationEvaluationRequest) EvaluationResult {
-	// 权重分配：相似度30%，AI评估70%
-	similarityWeight := 0.3
-	aiWeight := 0.7
+// calculateFinalEvaluation 按评分标准计算最终评估结果
+func calculateFinalEvaluation(similarity SimilarityResult, aiEval AIEvaluationResult, rubric *Rubric) EvaluationResult {
+	// 权重分配：相似度占 similarity_weight，其余为各维度的加权平均
+	similarityWeight := rubric.SimilarityWeight
+	aiWeight := 1 - similarityWeight
 
 	// 智能处理相似度分数（自动检测范围并标准化）
 	similarityScore := normalizeSimilarityScore(similarity.Score)
 
-	// AI评估平均分，确保在0-100之间
-	grammarScore := aiEval.GrammarScore
-	if grammarScore > 100 {
-		grammarScore = 100
-	}
-	if grammarScore < 0 {
-		grammarScore = 0
-	}
-
-	accuracyScore := aiEval.AccuracyScore
-	if accuracyScore > 100 {
-		accuracyScore = 100
-	}
-	if accuracyScore < 0 {
-		accuracyScore = 0
-	}
+	// AI评估加权分，各维度得分限制在0-100之间
+	aiScore := rubric.dimensionScore(aiEval.Scores)
 
-	fluencyScore := aiEval.FluencyScore
-	if fluencyScore > 100 {
-		fluencyScore = 100
+	// AI 评估无效时只使用相似度
+	if aiEval.Degraded {
+		similarityWeight, aiWeight = 1, 0
 	}
-	if fluencyScore < 0 {
-		fluencyScore = 0
-	}
-
-	aiAvgScore := (grammarScore + accuracyScore + fluencyScore) / 3
 
 	// 计算综合评分
-	finalScore := similarityScore*similarityWeight + aiAvgScore*aiWeight
+	finalScore := similarityScore*similarityWeight + aiScore*aiWeight
 
 	// 确保最终分数在0-100之间
 	if finalScore > 100 {
@@ -375,16 +439,16 @@ func calculateFinalEvaluation(similarity SimilarityResult, aiEval AIEvaluationRe
 	}
 
 	// 确定评级
-	level := determineLevel(finalScore)
+	level := rubric.level(finalScore)
 
 	// 生成综合反馈
-	feedback := generateFeedback(finalScore, similarity, aiEval)
+	feedback := generateFeedback(finalScore, level, similarity, aiEval)
 
 	// 收集改进建议
-	improvements := collectImprovements(aiEval, similarity)
+	improvements := collectImprovements(aiEval, similarity, rubric)
 
 	// 识别优点
-	strengths := identifyStrengths(aiEval, similarity)
+	strengths := identifyStrengths(aiEval, similarity, rubric)
 
 	return EvaluationResult{
 		Score:        finalScore,
@@ -392,6 +456,8 @@ func calculateFinalEvaluation(similarity SimilarityResult, aiEval AIEvaluationRe
 		Feedback:     feedback,
 		Improvements: improvements,
 		Strengths:    strengths,
+		Degraded:     aiEval.Degraded,
+		Rubric:       rubric.Name,
 	}
 }
 
@@ -439,23 +505,8 @@ func generateSimilarityExplanation(score float64) string {
 	}
 }
 
-// determineLevel 确定评级
-func determineLevel(score float64) string {
-	if score >= 90 {
-		return "优秀"
-	} else if score >= 80 {
-		return "良好"
-	} else if score >= 60 {
-		return "及格"
-	} else {
-		return "不及格"
-	}
-}
-
 // generateFeedback 生成综合反馈
-func generateFeedback(score float64, similarity SimilarityResult, aiEval AIEvaluationResult) string {
-	level := determineLevel(score)
-
+func generateFeedback(score float64, level string, similarity SimilarityResult, aiEval AIEvaluationResult) string {
 	feedback := fmt.Sprintf("您的翻译总体评级为【%s】，综合得分%.1f分。", level, score)
 
 	if similarity.Score >= 0.8 {
@@ -469,12 +520,15 @@ func generateFeedback(score float64, similarity SimilarityResult, aiEval AIEvalu
 	if aiEval.Summary != "" {
 		feedback += aiEval.Summary
 	}
+	if aiEval.Degraded {
+		feedback += "AI评估暂不可用，评分仅基于与标准答案的相似度。"
+	}
 
 	return feedback
 }
 
 // collectImprovements 收集改进建议
-func collectImprovements(aiEval AIEvaluationResult, similarity SimilarityResult) []string {
+func collectImprovements(aiEval AIEvaluationResult, similarity SimilarityResult, rubric *Rubric) []string {
 	improvements := make([]string, 0)
 
 	// AI提供的建议
@@ -485,36 +539,50 @@ func collectImprovements(aiEval AIEvaluationResult, similarity SimilarityResult)
 		improvements = append(improvements, "建议参考标准答案，调整翻译的表达方式")
 	}
 
-	// 基于各项分数的具体建议
-	if aiEval.GrammarScore < 70 {
-		improvements = append(improvements, "注意语法结构的准确性")
-	}
-	if aiEval.AccuracyScore < 70 {
-		improvements = append(improvements, "确保翻译的意思准确传达")
+	// 基于各维度分数的具体建议，AI 评估无效时没有分数
+	if aiEval.Degraded {
+		return improvements
 	}
-	if aiEval.FluencyScore < 70 {
-		improvements = append(improvements, "提高表达的自然流畅度")
+	for _, dimension := range rubric.Dimensions {
+		if aiEval.Scores[dimension.Key] >= rubric.ImproveBelow {
+			continue
+		}
+		if dimension.Advice != "" {
+			improvements = append(improvements, dimension.Advice)
+		} else {
+			improvements = append(improvements, fmt.Sprintf("提高%s", dimension.Label))
+		}
 	}
 
 	return improvements
 }
 
+// glossaryImprovements 原文中出现术语表中的术语、但用户翻译未使用规定译法时，每个术语生成一条改进建议
+func glossaryImprovements(glossary *Glossary, req TranslationEvaluationRequest) []string {
+	var improvements []string
+	for _, entry := range glossary.glossaryViolations(req.OriginalText, req.UserTranslation) {
+		improvements = append(improvements, fmt.Sprintf("术语不一致：术语表「%s」要求将“%s”译为“%s”", glossary.Name, entry.Source, entry.Target))
+	}
+	return improvements
+}
+
 // identifyStrengths 识别优点
-func identifyStrengths(aiEval AIEvaluationResult, similarity SimilarityResult) []string {
+func identifyStrengths(aiEval AIEvaluationResult, similarity SimilarityResult, rubric *Rubric) []string {
 	strengths := make([]string, 0)
 
 	if similarity.Score >= 0.8 {
 		strengths = append(strengths, "翻译内容与标准答案高度匹配")
 	}
 
-	if aiEval.GrammarScore >= 80 {
-		strengths = append(strengths, "语法结构正确")
-	}
-	if aiEval.AccuracyScore >= 80 {
-		strengths = append(strengths, "意思表达准确")
-	}
-	if aiEval.FluencyScore >= 80 {
-		strengths = append(strengths, "表达自然流畅")
+	for _, dimension := range rubric.Dimensions {
+		if score, ok := aiEval.Scores[dimension.Key]; !ok || score < rubric.StrengthAt {
+			continue
+		}
+		if dimension.Strength != "" {
+			strengths = append(strengths, dimension.Strength)
+		} else {
+			strengths = append(strengths, fmt.Sprintf("%s表现良好", dimension.Label))
+		}
 	}
 
 	if len(strengths) == 0 {
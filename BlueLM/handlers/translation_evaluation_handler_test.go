@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAIResponse(t *testing.T) {
+	rubric := &Rubric{Name: StandardRubricName, RubricConfig: standardRubric()}
+	longSummary := strings.Repeat("好", maxEvaluationSummaryLength+1)
+
+	tests := []struct {
+		name       string
+		response   string
+		wantErr    string
+		wantScores map[string]float64
+		wantAdvice []string
+	}{
+		{
+			name:       "valid",
+			response:   `{"summary":" 译文准确 ","scores":{"grammar":90,"accuracy":80.5,"fluency":70},"advice":[" 注意时态 "]}`,
+			wantScores: map[string]float64{"grammar": 90, "accuracy": 80.5, "fluency": 70},
+			wantAdvice: []string{"注意时态"},
+		},
+		{
+			name:       "fenced json",
+			response:   "```json\n{\"summary\":\"好\",\"scores\":{\"grammar\":100,\"accuracy\":0,\"fluency\":50},\"advice\":[]}\n```",
+			wantScores: map[string]float64{"grammar": 100, "accuracy": 0, "fluency": 50},
+			wantAdvice: []string{},
+		},
+		{name: "not json", response: "总体不错，给 85 分", wantErr: "not a valid JSON object"},
+		{name: "trailing content", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1,"fluency":1},"advice":[]} 以上`, wantErr: "extra content"},
+		{name: "unknown field", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1,"fluency":1},"advice":[],"level":"A"}`, wantErr: "unknown field"},
+		{name: "missing summary", response: `{"scores":{"grammar":1,"accuracy":1,"fluency":1},"advice":[]}`, wantErr: "summary is required"},
+		{name: "blank summary", response: `{"summary":"  ","scores":{"grammar":1,"accuracy":1,"fluency":1},"advice":[]}`, wantErr: "summary is required"},
+		{name: "summary too long", response: `{"summary":"` + longSummary + `","scores":{"grammar":1,"accuracy":1,"fluency":1},"advice":[]}`, wantErr: "must not exceed 100 characters"},
+		{name: "missing scores", response: `{"summary":"好","advice":[]}`, wantErr: "scores is required"},
+		{name: "missing score", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1},"advice":[]}`, wantErr: "scores.fluency is required"},
+		{name: "null score", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1,"fluency":null},"advice":[]}`, wantErr: "scores.fluency is required"},
+		{name: "score out of range", response: `{"summary":"好","scores":{"grammar":101,"accuracy":1,"fluency":1},"advice":[]}`, wantErr: "scores.grammar must be between 0 and 100"},
+		{name: "unexpected score", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1,"fluency":1,"style":1},"advice":[]}`, wantErr: "scores.style is not an expected score"},
+		{name: "missing advice", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1,"fluency":1}}`, wantErr: "advice is required"},
+		{name: "empty advice item", response: `{"summary":"好","scores":{"grammar":1,"accuracy":1,"fluency":1},"advice":["a"," "]}`, wantErr: "advice[1] must not be empty"},
+		{name: "all problems reported", response: `{"scores":{"grammar":-1,"accuracy":1,"fluency":1}}`, wantErr: "summary is required; scores.grammar must be between 0 and 100, got -1; advice is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseAIResponse(tt.response, rubric)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseAIResponse() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAIResponse() error = %v", err)
+			}
+			if result.Summary == "" || strings.TrimSpace(result.Summary) != result.Summary {
+				t.Errorf("Summary = %q, want trimmed summary", result.Summary)
+			}
+			for key, want := range tt.wantScores {
+				if result.Scores[key] != want {
+					t.Errorf("Scores[%s] = %v, want %v", key, result.Scores[key], want)
+				}
+			}
+			if result.GrammarScore != tt.wantScores["grammar"] || result.AccuracyScore != tt.wantScores["accuracy"] || result.FluencyScore != tt.wantScores["fluency"] {
+				t.Errorf("legacy scores = %v/%v/%v, want %v", result.GrammarScore, result.AccuracyScore, result.FluencyScore, tt.wantScores)
+			}
+			if !slices.Equal(result.DetailedAdvice, tt.wantAdvice) || result.DetailedAdvice == nil {
+				t.Errorf("DetailedAdvice = %q, want %q", result.DetailedAdvice, tt.wantAdvice)
+			}
+		})
+	}
+}
@@ -1,21 +1,15 @@
 package handlers
 
 import (
-	"crypto/md5"
-	"crypto/rand"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
-	"sort"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 )
 
 // 翻译语言常量
@@ -35,18 +29,15 @@ const (
 
 // 翻译请求结构
 type TranslationRequest struct {
-	From string `json:"from" binding:"required"`
-	To   string `json:"to" binding:"required"`
-	Text string `json:"text" binding:"required"`
-}
-
-// 翻译响应结构
-type TranslationResponse struct {
-	Code int `json:"code"`
-	Data struct {
-		Translation string `json:"translation"`
-	} `json:"data"`
-	Msg string `json:"msg"`
+	From    string `json:"from" binding:"required"`
+	To      string `json:"to" binding:"required"`
+	Text    string `json:"text" binding:"required"`
+	Backend string `json:"backend,omitempty"` // 指定主翻译后端：vivo、llm 或 dictionary，默认使用配置
+	AppID   string `json:"app_id,omitempty"`
+	AppKey  string `json:"app_key,omitempty"`
+	Profile string `json:"profile,omitempty"` // 使用 vivo_ai.profiles 中的凭据档案
+
+	GlossaryID string `json:"glossary_id,omitempty"` // 使用用户术语表，原文中的术语按规定译法翻译
 }
 
 // 翻译结果
@@ -57,6 +48,11 @@ type TranslationResult struct {
 	From         string `json:"from"`
 	To           string `json:"to"`
 	OriginalText string `json:"original_text"`
+	Backend      string `json:"backend,omitempty"`  // 实际产生译文的后端
+	Fallback     bool   `json:"fallback,omitempty"` // 主后端失败后由回退后端完成
+	Cache        string `json:"cache,omitempty"`    // 翻译缓存 hit 或 miss，未开启缓存时为空
+
+	GlossaryTerms []GlossaryTermUsage `json:"glossary_terms,omitempty"` // 原文中命中的术语
 }
 
 // 支持的语言映射
@@ -73,274 +69,175 @@ var languageMap = map[string]string{
 	"ar": TRANSLATE_LANGUAGE_ARABIC,
 }
 
-// 生成请求ID
-func generateRequestID() string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	return hex.EncodeToString(b)
-}
-
-// 创建HTTP客户端并设置签名
-func createSignedRequest(appID, appKey string, formData map[string]string) (*http.Request, error) {
-	// 准备签名参数
-	params := make(map[string]string)
-	for k, v := range formData {
-		params[k] = v
-	}
-
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-	params["appId"] = appID
-	params["timestamp"] = timestamp
-
-	// 生成签名
-	signature := generateSignature(params, appKey)
-	params["signature"] = signature
-
-	// 创建表单数据
-	values := url.Values{}
-	for k, v := range params {
-		values.Set(k, v)
-	}
-
-	// 创建请求
-	req, err := http.NewRequest("POST", "https://api-ai.vivo.com.cn/translation/query/self", strings.NewReader(values.Encode()))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	return req, nil
+// 支持的语言及其中文名称
+var supportedLanguages = map[string]string{
+	"auto": "自动检测",
+	"zh":   "中文",
+	"en":   "英语",
+	"ja":   "日语",
+	"ko":   "韩语",
+	"fr":   "法语",
+	"de":   "德语",
+	"es":   "西班牙语",
+	"it":   "意大利语",
+	"ru":   "俄语",
+	"ar":   "阿拉伯语",
 }
 
-// 生成签名
-func generateSignature(params map[string]string, appKey string) string {
-	// 按键排序
-	keys := make([]string, 0, len(params))
-	for k := range params {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	// 构建签名字符串
-	var signStr strings.Builder
-	for _, k := range keys {
-		if signStr.Len() > 0 {
-			signStr.WriteString("&")
+// TranslationHandler 翻译处理器
+// 依次尝试配置的翻译后端，结果中的 backend 为实际产生译文的后端
+func TranslationHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req TranslationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, TranslationResult{
+				Success: false,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
 		}
-		signStr.WriteString(k)
-		signStr.WriteString("=")
-		signStr.WriteString(params[k])
-	}
-	signStr.WriteString("&key=")
-	signStr.WriteString(appKey)
-
-	// MD5加密
-	hash := md5.Sum([]byte(signStr.String()))
-	return hex.EncodeToString(hash[:])
-}
 
-// 翻译处理器
-func TranslationHandler(c *gin.Context) {
-	var req TranslationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, TranslationResult{
-			Success: false,
-			Message: "请求参数错误: " + err.Error(),
-		})
-		return
-	}
-
-	// 检查输入文本是否为空
-	if strings.TrimSpace(req.Text) == "" {
-		c.JSON(http.StatusBadRequest, TranslationResult{
-			Success: false,
-			Message: "翻译文本不能为空",
-		})
-		return
-	}
-
-	// 映射语言代码
-	fromLang, ok := languageMap[req.From]
-	if !ok {
-		fromLang = req.From // 如果映射不存在，直接使用原值
-	}
+		// 检查输入文本是否为空
+		if strings.TrimSpace(req.Text) == "" {
+			c.JSON(http.StatusBadRequest, TranslationResult{
+				Success: false,
+				Message: "翻译文本不能为空",
+			})
+			return
+		}
 
-	toLang, ok := languageMap[req.To]
-	if !ok {
-		toLang = req.To // 如果映射不存在，直接使用原值
-	}
+		provider := newLazyProvider(resolver, ai.Credentials{AppID: req.AppID, AppKey: req.AppKey, Profile: req.Profile})
+		chain, err := translatorChain(provider, cfg, req.Backend)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, TranslationResult{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
 
-	// 如果源语言和目标语言相同，直接返回原文
-	if fromLang == toLang {
-		c.JSON(http.StatusOK, TranslationResult{
-			Success:      true,
-			Translation:  req.Text,
-			From:         req.From,
-			To:           req.To,
-			OriginalText: req.Text,
-			Message:      "源语言和目标语言相同，直接返回原文",
-		})
-		return
-	}
+		glossary, status, err := loadRequestGlossary(req.GlossaryID, req.From, req.To)
+		if err != nil {
+			c.JSON(status, TranslationResult{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
 
-	// 这里需要从配置中获取vivo的AppID和AppKey
-	appID := "your_vivo_app_id"   // 需要替换为实际的AppID
-	appKey := "your_vivo_app_key" // 需要替换为实际的AppKey
+		result, err := translateWithGlossary(chain, glossary, req.From, req.To, req.Text)
+		if err != nil {
+			logrus.Error("翻译失败:", err)
+			c.JSON(http.StatusInternalServerError, result)
+			return
+		}
 
-	// 如果没有配置vivo的凭据，使用模拟翻译
-	if appID == "your_vivo_app_id" || appKey == "your_vivo_app_key" {
-		logrus.Warn("Vivo API credentials not configured, using mock translation")
-		mockTranslation := getMockTranslation(req.Text, req.From, req.To)
-		c.JSON(http.StatusOK, TranslationResult{
-			Success:      true,
-			Translation:  mockTranslation,
-			From:         req.From,
-			To:           req.To,
-			OriginalText: req.Text,
-			Message:      "使用模拟翻译服务",
-		})
-		return
+		// 返回翻译结果
+		c.JSON(http.StatusOK, result)
 	}
+}
 
-	// 准备请求数据
-	formData := map[string]string{
-		"from":      fromLang,
-		"to":        toLang,
-		"text":      req.Text,
-		"app":       "auralab",
-		"requestId": generateRequestID(),
+// translateText 翻译一段文本，源语言和目标语言相同时直接返回原文
+// 开启缓存时先查缓存，缓存按主后端区分；只缓存主后端的结果，以免主后端恢复后仍返回回退后端的译文
+func translateText(chain []Translator, from, to, text string) (TranslationResult, error) {
+	result := TranslationResult{From: from, To: to, OriginalText: text}
+	if mapLanguage(from) == mapLanguage(to) {
+		result.Success = true
+		result.Translation = text
+		result.Message = "源语言和目标语言相同，直接返回原文"
+		return result, nil
+	}
+
+	cacheKey := translationCacheKey(chain[0].Name(), from, to, text)
+	if GlobalTranslationCache != nil {
+		if entry, ok := GlobalTranslationCache.Get(cacheKey); ok {
+			result.Success = true
+			result.Translation = entry.Translation
+			result.Backend = entry.Backend
+			result.Cache = TranslationCacheHit
+			result.Message = "翻译成功（缓存）"
+			return result, nil
+		}
+		result.Cache = TranslationCacheMiss
 	}
 
-	// 创建带签名的请求
-	httpReq, err := createSignedRequest(appID, appKey, formData)
+	translation, backend, err := translateWithFallback(chain, from, to, text)
 	if err != nil {
-		logrus.Error("创建HTTP请求失败:", err)
-		c.JSON(http.StatusInternalServerError, TranslationResult{
-			Success: false,
-			Message: "创建请求失败",
-		})
-		return
+		result.Message = "翻译服务请求失败: " + err.Error()
+		return result, err
 	}
-
-	// 发送请求
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		logrus.Error("发送翻译请求失败:", err)
-		c.JSON(http.StatusInternalServerError, TranslationResult{
-			Success: false,
-			Message: "翻译服务请求失败",
-		})
-		return
+	result.Success = true
+	result.Translation = translation
+	result.Backend = backend
+	result.Fallback = backend != chain[0].Name()
+	if GlobalTranslationCache != nil && !result.Fallback {
+		GlobalTranslationCache.Put(cacheKey, translation, backend)
 	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		logrus.Error("读取响应失败:", err)
-		c.JSON(http.StatusInternalServerError, TranslationResult{
-			Success: false,
-			Message: "读取响应失败",
-		})
-		return
+	result.Message = "翻译成功"
+	if result.Fallback {
+		result.Message = fmt.Sprintf("%s 翻译失败，已由 %s 完成翻译", chain[0].Name(), backend)
 	}
+	return result, nil
+}
 
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		logrus.Error("HTTP请求失败, 状态码:", resp.StatusCode, "响应:", string(body))
-		c.JSON(http.StatusInternalServerError, TranslationResult{
-			Success: false,
-			Message: "翻译服务返回错误",
-		})
-		return
+// translateWithGlossary 使用术语表翻译：术语先替换为占位符，翻译后再替换为规定的译法
+// 缓存以替换后的文本为键，因此不同术语表可以共用同一条缓存
+func translateWithGlossary(chain []Translator, glossary *Glossary, from, to, text string) (TranslationResult, error) {
+	if glossary == nil {
+		return translateText(chain, from, to, text)
 	}
-
-	// 解析响应
-	var translationResp TranslationResponse
-	if err := json.Unmarshal(body, &translationResp); err != nil {
-		logrus.Error("解析响应失败:", err)
-		c.JSON(http.StatusInternalServerError, TranslationResult{
-			Success: false,
-			Message: "解析响应失败",
-		})
-		return
+	protected, terms := glossary.protect(text)
+	if len(terms) == 0 {
+		return translateText(chain, from, to, text)
 	}
 
-	// 检查业务状态码
-	if translationResp.Code != 0 {
-		logrus.Error("翻译失败:", translationResp.Msg)
-		c.JSON(http.StatusOK, TranslationResult{
-			Success: false,
-			Message: translationResp.Msg,
-		})
-		return
+	result, err := translateText(chain, from, to, protected)
+	result.OriginalText = text
+	if err != nil {
+		return result, err
 	}
-
-	// 返回翻译结果
-	c.JSON(http.StatusOK, TranslationResult{
-		Success:      true,
-		Translation:  translationResp.Data.Translation,
-		From:         req.From,
-		To:           req.To,
-		OriginalText: req.Text,
-		Message:      "翻译成功",
-	})
+	result.Translation = restoreGlossaryTerms(result.Translation, terms)
+	result.GlossaryTerms = terms
+	return result, nil
 }
 
-// 模拟翻译函数（当没有配置vivo API时使用）
-func getMockTranslation(text, from, to string) string {
-	// 这里可以接入其他翻译服务或返回模拟数据
-	mockTranslations := map[string]map[string]string{
-		"hello": {
-			"zh": "你好",
-			"ja": "こんにちは",
-			"ko": "안녕하세요",
-			"fr": "bonjour",
-			"de": "hallo",
-		},
-		"world": {
-			"zh": "世界",
-			"ja": "世界",
-			"ko": "세계",
-			"fr": "monde",
-			"de": "welt",
-		},
-		"你好": {
-			"en": "hello",
-			"ja": "こんにちは",
-			"ko": "안녕하세요",
-		},
-	}
+// dictionaryEntries 离线词典收录的词条，按原文（小写）和目标语言查找
+var dictionaryEntries = map[string]map[string]string{
+	"hello": {
+		"zh": "你好",
+		"ja": "こんにちは",
+		"ko": "안녕하세요",
+		"fr": "bonjour",
+		"de": "hallo",
+	},
+	"world": {
+		"zh": "世界",
+		"ja": "世界",
+		"ko": "세계",
+		"fr": "monde",
+		"de": "welt",
+	},
+	"你好": {
+		"en": "hello",
+		"ja": "こんにちは",
+		"ko": "안녕하세요",
+	},
+}
 
-	lowerText := strings.ToLower(text)
-	if translations, ok := mockTranslations[lowerText]; ok {
-		if translation, ok := translations[to]; ok {
-			return translation
-		}
+// lookupDictionary 在离线词典中查找译文，未收录时返回 false
+func lookupDictionary(text, to string) (string, bool) {
+	translations, ok := dictionaryEntries[strings.ToLower(strings.TrimSpace(text))]
+	if !ok {
+		return "", false
 	}
-
-	return fmt.Sprintf("[模拟翻译] %s -> %s: %s", from, to, text)
+	translation, ok := translations[to]
+	return translation, ok
 }
 
 // 获取支持的语言列表
 func GetSupportedLanguagesHandler(c *gin.Context) {
-	languages := map[string]string{
-		"auto": "自动检测",
-		"zh":   "中文",
-		"en":   "英语",
-		"ja":   "日语",
-		"ko":   "韩语",
-		"fr":   "法语",
-		"de":   "德语",
-		"es":   "西班牙语",
-		"it":   "意大利语",
-		"ru":   "俄语",
-		"ar":   "阿拉伯语",
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
-		"languages": languages,
+		"languages": supportedLanguages,
 	})
 }
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 翻译后端名称，对应 config.yaml 中的 translation.backend / translation.fallback
+const (
+	TranslatorVivo       = "vivo"       // vivo 机器翻译接口
+	TranslatorLLM        = "llm"        // 蓝心大模型对话 + 翻译提示词
+	TranslatorDictionary = "dictionary" // 离线词典，无需网络
+)
+
+// Translator 翻译后端，from/to 为请求中的语言代码（见 GetSupportedLanguagesHandler）
+type Translator interface {
+	Name() string
+	Translate(from, to, text string) (string, error)
+}
+
+// lazyProvider 在第一次需要时才解析凭据，只使用离线后端的请求不要求凭据
+// 凭据无效时返回解析错误，由调用方回退到下一个后端
+type lazyProvider struct {
+	once     sync.Once
+	resolve  func() (ai.Provider, error)
+	provider ai.Provider
+	err      error
+}
+
+func newLazyProvider(resolver *ai.Resolver, creds ai.Credentials) *lazyProvider {
+	return &lazyProvider{resolve: func() (ai.Provider, error) {
+		return resolver.Resolve(creds)
+	}}
+}
+
+func (p *lazyProvider) get() (ai.Provider, error) {
+	p.once.Do(func() {
+		p.provider, p.err = p.resolve()
+	})
+	return p.provider, p.err
+}
+
+// vivoTranslator 调用 vivo 机器翻译接口
+type vivoTranslator struct {
+	provider *lazyProvider
+}
+
+func (t vivoTranslator) Name() string {
+	return TranslatorVivo
+}
+
+func (t vivoTranslator) Translate(from, to, text string) (string, error) {
+	provider, err := t.provider.get()
+	if err != nil {
+		return "", err
+	}
+	return provider.Translate(mapLanguage(from), mapLanguage(to), text)
+}
+
+// llmTranslator 使用大模型对话完成翻译，可处理 vivo 翻译接口不支持的语言
+type llmTranslator struct {
+	provider *lazyProvider
+}
+
+func (t llmTranslator) Name() string {
+	return TranslatorLLM
+}
+
+func (t llmTranslator) Translate(from, to, text string) (string, error) {
+	systemPrompt := "你是一位专业翻译。只输出译文本身，不要添加解释、注音或引号，保留原文的换行和标点风格。形如 {{G0}} 的占位符是专有术语，请原样保留在译文中的对应位置。"
+	prompt := fmt.Sprintf("请将以下文本从%s翻译成%s：\n%s", languageName(from), languageName(to), text)
+
+	provider, err := t.provider.get()
+	if err != nil {
+		return "", err
+	}
+	reply, err := provider.EasyChat(ai.NewSessionID(), prompt, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	reply = strings.Trim(strings.TrimSpace(reply), "\"“”")
+	if reply == "" {
+		return "", fmt.Errorf("empty translation from model")
+	}
+	return reply, nil
+}
+
+// dictionaryTranslator 离线词典，只能翻译收录的词条，未收录的文本返回错误
+type dictionaryTranslator struct{}
+
+func (dictionaryTranslator) Name() string {
+	return TranslatorDictionary
+}
+
+func (dictionaryTranslator) Translate(from, to, text string) (string, error) {
+	translation, ok := lookupDictionary(text, to)
+	if !ok {
+		return "", fmt.Errorf("text not found in offline dictionary (%s -> %s)", from, to)
+	}
+	return translation, nil
+}
+
+// newTranslator 按名称创建翻译后端
+func newTranslator(name string, provider *lazyProvider) (Translator, error) {
+	switch name {
+	case TranslatorVivo:
+		return vivoTranslator{provider: provider}, nil
+	case TranslatorLLM:
+		return llmTranslator{provider: provider}, nil
+	case TranslatorDictionary:
+		return dictionaryTranslator{}, nil
+	}
+	return nil, fmt.Errorf("unsupported translation backend %q (supported: vivo, llm, dictionary)", name)
+}
+
+// translatorChain 返回本次请求依次尝试的翻译后端
+// 请求指定 backend 时以其为主后端，其余按配置的顺序作为回退
+func translatorChain(provider *lazyProvider, cfg *config.Config, backend string) ([]Translator, error) {
+	if backend == "" {
+		backend = cfg.Translation.Backend
+	}
+	names := append([]string{backend}, cfg.Translation.Fallback...)
+
+	var chain []Translator
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		translator, err := newTranslator(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, translator)
+	}
+	return chain, nil
+}
+
+// translateWithFallback 依次尝试各后端，返回第一个成功的结果及其后端名称
+func translateWithFallback(chain []Translator, from, to, text string) (string, string, error) {
+	var errs []string
+	for _, translator := range chain {
+		translation, err := translator.Translate(from, to, text)
+		if err == nil {
+			return translation, translator.Name(), nil
+		}
+		utils.Log.Warnf("Translation backend %s failed: %v", translator.Name(), err)
+		errs = append(errs, fmt.Sprintf("%s: %v", translator.Name(), err))
+	}
+	return "", "", fmt.Errorf("all translation backends failed (%s)", strings.Join(errs, "; "))
+}
+
+// mapLanguage 将请求中的语言代码映射为 vivo 翻译接口的代码
+func mapLanguage(code string) string {
+	if mapped, ok := languageMap[code]; ok {
+		return mapped
+	}
+	return code // 如果映射不存在，直接使用原值
+}
+
+// languageName 返回语言的中文名称，用于大模型提示词
+func languageName(code string) string {
+	if name, ok := supportedLanguages[code]; ok {
+		return name
+	}
+	return code
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/audio"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 响应头 X-TTS-Cache 和 cache 字段的取值
+const (
+	TTSCacheHit  = "hit"
+	TTSCacheMiss = "miss"
+)
+
+// ttsCacheHashPattern 缓存键为 SHA-256 的十六进制表示
+var ttsCacheHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ttsCacheEntry 一个缓存的音频文件 <hash>.<ext>
+type ttsCacheEntry struct {
+	Hash string
+	Ext  string
+	Size int64
+}
+
+// TTSCacheStats 缓存的占用和命中统计
+type TTSCacheStats struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// TTSCache 合成音频的内容寻址缓存，文件保存在缓存目录中，内存中只保存索引
+// 键为模式、音色、文本、输出格式和采样率的哈希，总大小超出上限时淘汰最久未使用的文件
+// 命中时更新文件的修改时间，重启后按修改时间恢复使用顺序
+type TTSCache struct {
+	dir      string
+	maxBytes int64
+
+	mu     sync.Mutex
+	lru    *list.List // 最近使用的在前
+	items  map[string]*list.Element
+	size   int64
+	hits   int64
+	misses int64
+}
+
+// GlobalTTSCache 全局合成音频缓存，未开启时为 nil
+var GlobalTTSCache *TTSCache
+
+// InitTTSCache 按配置初始化合成音频缓存，并从缓存目录恢复索引
+func InitTTSCache(cfg *config.Config) error {
+	cacheCfg := cfg.TTS.Cache
+	if !cacheCfg.Enabled {
+		utils.Log.Infof("TTS cache disabled by config")
+		return nil
+	}
+
+	dir := cacheCfg.Dir
+	if dir == "" {
+		dir = filepath.Join(cfg.FilePaths.DataDir, "tts_cache")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create TTS cache dir %s: %v", dir, err)
+	}
+
+	cache := &TTSCache{
+		dir:      dir,
+		maxBytes: cacheCfg.MaxSizeMB * 1024 * 1024,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+	}
+	if err := cache.load(); err != nil {
+		return err
+	}
+	GlobalTTSCache = cache
+	utils.Log.Infof("TTS cache enabled at %s, %d entries (%d bytes)", dir, len(cache.items), cache.size)
+	return nil
+}
+
+// ttsCacheKey 返回合成参数的哈希；句间静音影响拼接结果，一并计入
+func ttsCacheKey(mode, vcn, text, format string, silence time.Duration) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{mode, vcn, text, format, silence.String()}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ttsAudioURL 返回缓存音频的稳定地址
+func ttsAudioURL(hash string) string {
+	return "/bluelm/tts/audio/" + hash
+}
+
+// load 扫描缓存目录重建索引，删除上次未写完的临时文件
+func (c *TTSCache) load() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read TTS cache dir %s: %v", c.dir, err)
+	}
+
+	type file struct {
+		entry   ttsCacheEntry
+		modTime time.Time
+	}
+	var files []file
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		hash, ext, ok := strings.Cut(name, ".")
+		if !ok || !ttsCacheHashPattern.MatchString(hash) || audio.ContentType(ext) == "" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{ttsCacheEntry{Hash: hash, Ext: ext, Size: info.Size()}, info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.add(f.entry)
+	}
+	return nil
+}
+
+// path 返回缓存文件的路径
+func (c *TTSCache) path(entry ttsCacheEntry) string {
+	return filepath.Join(c.dir, entry.Hash+"."+entry.Ext)
+}
+
+// Get 查找缓存的音频，返回条目和打开的文件，调用方负责关闭
+// 文件在持有锁时打开，之后即使条目被并发的 Put 淘汰、文件被删除，已打开的文件仍然可读
+func (c *TTSCache) Get(hash string) (ttsCacheEntry, *os.File, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[hash]
+	if !ok {
+		c.misses++
+		return ttsCacheEntry{}, nil, false
+	}
+	entry := elem.Value.(ttsCacheEntry)
+	path := c.path(entry)
+	file, err := os.Open(path)
+	if err != nil {
+		// 文件被外部删除
+		c.remove(elem)
+		c.misses++
+		return ttsCacheEntry{}, nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.hits++
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return entry, file, true
+}
+
+// Put 调用 write 将音频写入临时文件后放入缓存，返回条目和打开的文件，调用方负责关闭
+// 写入完成后才重命名为正式文件，并发的相同请求不会读到写了一半的文件
+func (c *TTSCache) Put(hash, ext string, write func(w io.Writer) error) (ttsCacheEntry, *os.File, error) {
+	tmp, err := os.CreateTemp(c.dir, hash+".*.tmp")
+	if err != nil {
+		return ttsCacheEntry{}, nil, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ttsCacheEntry{}, nil, err
+	}
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return ttsCacheEntry{}, nil, err
+	}
+	entry := ttsCacheEntry{Hash: hash, Ext: ext, Size: info.Size()}
+	path := c.path(entry)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmpPath, path); err != nil {
+		return ttsCacheEntry{}, nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ttsCacheEntry{}, nil, err
+	}
+	c.add(entry)
+	return entry, file, nil
+}
+
+// Purge 删除全部缓存文件，返回删除的条数
+func (c *TTSCache) Purge() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	purged := 0
+	var firstErr error
+	for c.lru.Len() > 0 {
+		if err := c.remove(c.lru.Back()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		purged++
+	}
+	return purged, firstErr
+}
+
+// Stats 返回缓存条数、总大小和命中统计
+func (c *TTSCache) Stats() TTSCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return TTSCacheStats{Entries: len(c.items), Bytes: c.size, Hits: c.hits, Misses: c.misses}
+}
+
+// add 登记条目并淘汰最久未使用的文件，刚登记的条目不会被淘汰，调用方需持有锁
+func (c *TTSCache) add(entry ttsCacheEntry) {
+	if elem, ok := c.items[entry.Hash]; ok {
+		c.size += entry.Size - elem.Value.(ttsCacheEntry).Size
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+	} else {
+		c.items[entry.Hash] = c.lru.PushFront(entry)
+		c.size += entry.Size
+	}
+
+	for c.maxBytes > 0 && c.size > c.maxBytes && c.lru.Len() > 1 {
+		if err := c.remove(c.lru.Back()); err != nil {
+			utils.Log.Warnf("Failed to evict TTS cache entry: %v", err)
+		}
+	}
+}
+
+// remove 删除条目及其文件，调用方需持有锁
+func (c *TTSCache) remove(elem *list.Element) error {
+	entry := elem.Value.(ttsCacheEntry)
+	c.lru.Remove(elem)
+	delete(c.items, entry.Hash)
+	c.size -= entry.Size
+	if err := os.Remove(c.path(entry)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// etagMatches 判断 If-None-Match 请求头是否包含 etag（弱比较）
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// serveTTSCacheFile 从打开的文件返回缓存的音频，带 ETag 和长期缓存头，If-None-Match 匹配时返回 304
+func serveTTSCacheFile(c *gin.Context, entry ttsCacheEntry, file *os.File, cacheStatus string) {
+	etag := `"` + entry.Hash + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("Content-Location", ttsAudioURL(entry.Hash))
+	c.Header("X-TTS-Cache", cacheStatus)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("Content-Type", audio.ContentType(entry.Ext))
+	name := entry.Hash[:16] + "." + entry.Ext
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	var modTime time.Time
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+	http.ServeContent(c.Writer, c.Request, name, modTime, file)
+}
+
+// TTSAudioHandler 按哈希返回缓存的合成音频，地址在音频被淘汰前保持不变
+func TTSAudioHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hash := c.Param("hash")
+		if GlobalTTSCache == nil || !ttsCacheHashPattern.MatchString(hash) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Audio not found",
+				"hash":  hash,
+			})
+			return
+		}
+		entry, file, ok := GlobalTTSCache.Get(hash)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Audio not found",
+				"hash":  hash,
+			})
+			return
+		}
+		defer file.Close()
+		serveTTSCacheFile(c, entry, file, TTSCacheHit)
+	}
+}
+
+// TTSCacheStatusHandler 返回合成音频缓存的配置和统计
+func TTSCacheStatusHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cacheCfg := cfg.TTS.Cache
+		response := gin.H{
+			"success": true,
+			"config": gin.H{
+				"enabled":     cacheCfg.Enabled,
+				"max_size_mb": cacheCfg.MaxSizeMB,
+			},
+		}
+		if GlobalTTSCache != nil {
+			response["stats"] = GlobalTTSCache.Stats()
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// TTSCachePurgeHandler 清空合成音频缓存
+func TTSCachePurgeHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GlobalTTSCache == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"message": "TTS cache is disabled",
+				"purged":  0,
+			})
+			return
+		}
+
+		purged, err := GlobalTTSCache.Purge()
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		utils.Log.Infof("TTS cache purged, %d entries removed", purged)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "TTS cache purged",
+			"purged":  purged,
+		})
+	}
+}
@@ -1,93 +1,418 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/audio"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 )
 
+// defaultTTSFormat 未通过 format 字段或 Accept 请求头指定时的输出格式
+const defaultTTSFormat = audio.FormatWAV
+
+// ttsPCMSpec 合成接口返回的 PCM 参数
+var ttsPCMSpec = audio.Spec{
+	SampleRate:    ai.TTSSampleRate,
+	Channels:      ai.TTSChannels,
+	BitsPerSample: ai.TTSBitsPerSample,
+}
+
+// ttsOutput 合成音频的输出格式和采样率
+type ttsOutput struct {
+	Format     string
+	SampleRate int
+}
+
+// encode 将拼接后的 PCM 按输出格式编码写入 w
+func (o ttsOutput) encode(w io.Writer, pcm []byte) error {
+	return audio.Encode(w, o.Format, pcm, ttsPCMSpec, o.SampleRate)
+}
+
+// cacheFormat 返回计入缓存键的格式，同一格式不同采样率的音频分别缓存
+func (o ttsOutput) cacheFormat() string {
+	return fmt.Sprintf("%s@%d", o.Format, o.SampleRate)
+}
+
+// resolveTTSOutput 确定输出格式和采样率：format 字段优先，其次是 Accept 请求头，最后使用默认格式；
+// sample_rate 为0时使用合成接口的原始采样率
+func resolveTTSOutput(format, accept string, sampleRate int) (ttsOutput, []utils.FieldError) {
+	var fieldErrs []utils.FieldError
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = audio.Negotiate(accept)
+	}
+	if format == "" {
+		format = defaultTTSFormat
+	}
+	if audio.ContentType(format) == "" {
+		fieldErrs = append(fieldErrs, utils.FieldError{
+			Field: "format",
+			Error: fmt.Sprintf("unsupported format %q, valid formats: %s", format, strings.Join(audio.Formats(), ", ")),
+		})
+	}
+	if sampleRate == 0 {
+		sampleRate = ai.TTSSampleRate
+	}
+	if sampleRate < audio.MinSampleRate || sampleRate > audio.MaxSampleRate {
+		fieldErrs = append(fieldErrs, utils.FieldError{
+			Field: "sample_rate",
+			Error: fmt.Sprintf("sample_rate must be between %d and %d", audio.MinSampleRate, audio.MaxSampleRate),
+		})
+	}
+	return ttsOutput{Format: format, SampleRate: sampleRate}, fieldErrs
+}
+
+// ttsResultFileName 返回长文本合成任务的结果文件名，扩展名为输出格式
+func ttsResultFileName(taskID, format string) string {
+	return "tts_" + taskID + "." + format
+}
+
+// findTTSResult 在下载目录中查找长文本合成任务的结果文件
+func findTTSResult(dir, taskID string) (string, bool) {
+	for _, format := range audio.Formats() {
+		path := filepath.Join(dir, ttsResultFileName(taskID, format))
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
 // TTSHandler 处理文本到语音的转换请求
-func TTSHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+// 文本按句切分后并发合成再拼接；句数超过 tts.async_segments 或请求带 async 时创建异步任务并返回 task_id
+func TTSHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var requestBody struct {
-			Mode   string `json:"mode"`
-			Text   string `json:"text"`
-			Vcn    string `json:"vcn"`
-			AppID  string `json:"app_id,omitempty"`  // 前端传递的AppID
-			AppKey string `json:"app_key,omitempty"` // 前端传递的AppKey
-		}
-		requestBody.Mode = "TTS_MODE_HUMAN"
-		requestBody.Vcn = "M24"
+			Mode           string `json:"mode"` // short、long、human、replica 或引擎ID
+			Text           string `json:"text"`
+			Vcn            string `json:"vcn"`                   // 音色，可选值见 /bluelm/tts/voices，留空使用该模式的默认音色
+			Format         string `json:"format,omitempty"`      // wav、pcm 或 flac，留空时按 Accept 请求头选择，默认 wav
+			SampleRate     int    `json:"sample_rate,omitempty"` // 输出采样率，8000-48000，默认 24000
+			Async          bool   `json:"async,omitempty"`       // 强制以异步任务处理
+			ReturnURL      bool   `json:"return_url,omitempty"`  // 返回缓存音频的稳定地址而不是音频本身，需要开启 tts.cache
+			AppID          string `json:"app_id,omitempty"`      // 前端传递的AppID
+			AppKey         string `json:"app_key,omitempty"`     // 前端传递的AppKey
+			Profile        string `json:"profile,omitempty"`     // 使用 vivo_ai.profiles 中的凭据档案
+			CallbackURL    string `json:"callback_url,omitempty"`
+			CallbackSecret string `json:"callback_secret,omitempty"`
+		}
+		requestBody.Mode = "human"
 		requestBody.Text = "你好，这是蓝心大模型的音频生成功能。"
 		// json传入
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
 			utils.AbortWithBadRequest(c, err, "Invalid request body")
 			return
 		}
-		switch requestBody.Mode {
-		case "short":
-			requestBody.Mode = "short_audio_synthesis_jovi"
-		case "long":
-			requestBody.Mode = "long_audio_synthesis_screen"
-		case "human":
-			requestBody.Mode = "tts_humanoid_lam"
-		case "replica":
-			requestBody.Mode = "tts_replica" // 音色复刻专用
-		}
-
-		// 创建蓝心大模型应用实例，考虑配置优先级
-		ttsApp := createBlueLMApp(requestBody.AppID, requestBody.AppKey, cfg)
-
-		// 检查配置是否为占位符
-		if cfg.VivoAI.AppID == "YOUR_VIVO_APP_ID" || cfg.VivoAI.AppKey == "YOUR_VIVO_APP_KEY" {
-			utils.Log.Errorf("TTS service configuration error: Vivo AI credentials not configured")
-			c.JSON(500, gin.H{
-				"message": "TTS service configuration error: Please configure valid Vivo AI credentials in config.yaml. See config.example.yaml for reference.",
-				"error":   "Invalid or placeholder credentials detected",
-				"details": "Current app_id and app_key are placeholder values. Please replace them with actual Vivo AI credentials.",
+		engine, vcn, fieldErrs := resolveTTSVoice(requestBody.Mode, requestBody.Vcn)
+		if fieldErrs != nil {
+			utils.AbortWithValidationErrors(c, "Invalid mode or voice", fieldErrs)
+			return
+		}
+		requestBody.Mode, requestBody.Vcn = engine, vcn
+		output, fieldErrs := resolveTTSOutput(requestBody.Format, c.GetHeader("Accept"), requestBody.SampleRate)
+		if fieldErrs != nil {
+			utils.AbortWithValidationErrors(c, "Invalid output format", fieldErrs)
+			return
+		}
+
+		if n := utf8.RuneCountInString(requestBody.Text); n > cfg.TTS.MaxTextLength {
+			utils.AbortWithBadRequest(c, nil, fmt.Sprintf("Text too long: %d characters (max %d)", n, cfg.TTS.MaxTextLength))
+			return
+		}
+		segments := splitTTSText(requestBody.Text, ttsSegmentLimit(requestBody.Mode, cfg.TTS.MaxSegmentChars))
+		if len(segments) == 0 {
+			utils.AbortWithBadRequest(c, nil, "Text has nothing to synthesize")
+			return
+		}
+		if requestBody.ReturnURL && GlobalTTSCache == nil {
+			utils.AbortWithBadRequest(c, nil, "return_url requires the TTS cache (tts.cache.enabled)")
+			return
+		}
+		async := requestBody.Async || len(segments) > cfg.TTS.AsyncSegments
+		callback, err := newCallbackTarget(requestBody.CallbackURL, requestBody.CallbackSecret)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid callback parameters")
+			return
+		}
+
+		// 按凭据查找顺序获取提供方，占位符凭据视为未配置
+		ttsApp, ok := resolveProvider(c, resolver, ai.Credentials{
+			AppID:   requestBody.AppID,
+			AppKey:  requestBody.AppKey,
+			Profile: requestBody.Profile,
+		})
+		if !ok {
+			return
+		}
+
+		// 以切分后的文本计算缓存键，只有空白不同的文本共用缓存
+		hash := ttsCacheKey(requestBody.Mode, requestBody.Vcn, strings.Join(segments, "\n"), output.cacheFormat(), cfg.TTS.SentenceSilence)
+		if GlobalTTSCache != nil {
+			if entry, file, ok := GlobalTTSCache.Get(hash); ok {
+				defer file.Close()
+				respondTTSAudio(c, entry, file, TTSCacheHit, output, requestBody.ReturnURL)
+				return
+			}
+		}
+
+		if async {
+			taskID := ai.NewSessionID()
+			GlobalTaskManager.CreateTask(taskID, "tts_"+requestBody.Vcn, TaskEngineTTS)
+			GlobalTaskManager.UpdateTask(taskID, func(task *TaskInfo) {
+				task.Status = TaskStatusProcessing
+				task.Message = fmt.Sprintf("Synthesizing %d segments", len(segments))
+				callback.apply(task)
+			})
+
+			go runTTSJob(startTaskPoller(taskID), cfg, taskID, hash, ttsApp, requestBody.Mode, requestBody.Vcn, segments, output)
+
+			c.JSON(http.StatusOK, gin.H{
+				"task_id":  taskID,
+				"segments": len(segments),
 			})
 			return
 		}
 
-		//调用蓝心大模型生成pcm切片
-		res, e := ttsApp.TTS(requestBody.Mode, requestBody.Vcn, requestBody.Text)
+		//调用蓝心大模型逐句生成pcm切片
+		res, e := synthesizeSegments(c.Request.Context(), ttsApp, requestBody.Mode, requestBody.Vcn, segments, cfg.TTS.Concurrency, nil)
 		if e != nil {
-			utils.Log.Errorf("TTS service error: %v", e)
-			// 检查是否是配置问题
-			if e.Error() == "invalid app_id or app_key" || e.Error() == "unauthorized" {
-				c.JSON(500, gin.H{
-					"message": "TTS service configuration error: Please check your Vivo AI credentials in config.yaml",
-					"error":   e.Error(),
-					"details": "The provided Vivo AI credentials appear to be invalid. Please verify your app_id and app_key.",
-				})
-			} else if e.Error() == "websocket: bad handshake" {
-				c.JSON(500, gin.H{
-					"message": "TTS service connection error: Unable to establish WebSocket connection with Vivo AI service",
-					"error":   e.Error(),
-					"details": "This usually indicates network connectivity issues or invalid credentials. Please check your internet connection and Vivo AI credentials.",
-				})
-			} else {
-				c.JSON(500, gin.H{
-					"message": "TTS service error: " + e.Error(),
-					"error":   e.Error(),
-				})
+			respondTTSError(c, e)
+			return
+		}
+		//将pcm切片拼接后按输出格式编码
+		pcm := joinPCM(res, cfg.TTS.SentenceSilence)
+
+		if GlobalTTSCache != nil {
+			entry, file, err := GlobalTTSCache.Put(hash, output.Format, func(w io.Writer) error {
+				return output.encode(w, pcm)
+			})
+			if err != nil {
+				utils.AbortWithInternalServerError(c, err)
+				return
 			}
+			defer file.Close()
+			respondTTSAudio(c, entry, file, TTSCacheMiss, output, requestBody.ReturnURL)
 			return
 		}
-		fileName := time.Now().Format("20060102150405") + ".wav"
-		downloadFilePath := cfg.FilePaths.DownloadDir + "temp_" + fileName
-		//将pcm切片转换为wav文件
-		err := utils.PcmtoWav(res, downloadFilePath, 1, 16, 24000)
+
+		// 未开启缓存时直接编码写入响应
+		c.Header("Content-Type", audio.ContentType(output.Format))
+		c.Header("X-Audio-Sample-Rate", strconv.Itoa(output.SampleRate))
+		c.Header("Content-Disposition", "attachment; filename="+time.Now().Format("20060102150405")+"."+output.Format)
+		c.Status(http.StatusOK)
+		if err := output.encode(c.Writer, pcm); err != nil {
+			utils.Log.Errorf("Failed to write TTS audio: %v", err)
+		}
+	}
+}
+
+// respondTTSAudio 返回缓存的音频；return_url 时只返回音频的稳定地址
+func respondTTSAudio(c *gin.Context, entry ttsCacheEntry, file *os.File, cacheStatus string, output ttsOutput, returnURL bool) {
+	if returnURL {
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"audio_url":    ttsAudioURL(entry.Hash),
+			"hash":         entry.Hash,
+			"format":       output.Format,
+			"sample_rate":  output.SampleRate,
+			"content_type": audio.ContentType(entry.Ext),
+			"size":         entry.Size,
+			"cache":        cacheStatus,
+		})
+		return
+	}
+	c.Header("X-Audio-Sample-Rate", strconv.Itoa(output.SampleRate))
+	serveTTSCacheFile(c, entry, file, cacheStatus)
+}
+
+// respondTTSError 按合成失败的原因返回错误信息
+func respondTTSError(c *gin.Context, e error) {
+	utils.Log.Errorf("TTS service error: %v", e)
+	cause := e
+	for errors.Unwrap(cause) != nil {
+		cause = errors.Unwrap(cause)
+	}
+	// 检查是否是配置问题
+	if cause.Error() == "invalid app_id or app_key" || cause.Error() == "unauthorized" {
+		c.JSON(500, gin.H{
+			"message": "TTS service configuration error: Please check your Vivo AI credentials in config.yaml",
+			"error":   e.Error(),
+			"details": "The provided Vivo AI credentials appear to be invalid. Please verify your app_id and app_key.",
+		})
+	} else if cause.Error() == "websocket: bad handshake" {
+		c.JSON(500, gin.H{
+			"message": "TTS service connection error: Unable to establish WebSocket connection with Vivo AI service",
+			"error":   e.Error(),
+			"details": "This usually indicates network connectivity issues or invalid credentials. Please check your internet connection and Vivo AI credentials.",
+		})
+	} else {
+		c.JSON(500, gin.H{
+			"message": "TTS service error: " + e.Error(),
+			"error":   e.Error(),
+		})
+	}
+}
+
+// synthesizeSegments 以有限的并发逐句合成，返回与 segments 顺序一致的 PCM 切片
+// 任意一句失败时不再开始新的句子并返回该错误，多句时错误信息中带有句子序号
+func synthesizeSegments(ctx context.Context, app ai.Provider, mode, vcn string, segments []string, concurrency int, progress func(done int)) ([][]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]byte, len(segments))
+	errs := make([]error, len(segments))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i, text := range segments {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			pcm, err := app.TTS(mode, vcn, text)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			results[i] = pcm
+			if progress != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				done++
+				progress(done)
+			}
+		}(i, text)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if len(segments) > 1 {
+			return nil, fmt.Errorf("segment %d/%d: %w", i+1, len(segments), err)
+		}
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// joinPCM 拼接各句的 PCM，句与句之间插入 silence 时长的静音
+func joinPCM(chunks [][]byte, silence time.Duration) []byte {
+	frameSize := ai.TTSChannels * ai.TTSBitsPerSample / 8
+	gap := make([]byte, int(silence.Seconds()*ai.TTSSampleRate)*frameSize)
+
+	size := len(gap) * max(len(chunks)-1, 0)
+	for _, chunk := range chunks {
+		size += len(chunk)
+	}
+	pcm := make([]byte, 0, size)
+	for i, chunk := range chunks {
+		if i > 0 {
+			pcm = append(pcm, gap...)
+		}
+		// 丢弃不完整的采样帧，避免之后的声道和字节错位
+		pcm = append(pcm, chunk[:len(chunk)-len(chunk)%frameSize]...)
+	}
+	return pcm
+}
+
+// runTTSJob 合成全部句子并按输出格式写出拼接后的音频，ctx 被取消时停止；开启缓存时同时放入缓存
+func runTTSJob(ctx context.Context, cfg *config.Config, taskID, hash string, app ai.Provider, mode, vcn string, segments []string, output ttsOutput) {
+	defer finishTaskPoller(taskID)
+
+	chunks, err := synthesizeSegments(ctx, app, mode, vcn, segments, cfg.TTS.Concurrency, func(done int) {
+		updateRunningTask(taskID, func(task *TaskInfo) {
+			task.Progress = done * 100 / len(segments)
+			task.Message = fmt.Sprintf("Synthesized %d/%d segments", done, len(segments))
+		})
+	})
+	if ctx.Err() != nil {
+		utils.Log.Infof("TTS task %s stopped: %v", taskID, ctx.Err())
+		return
+	}
+	if err != nil {
+		utils.Log.Errorf("TTS task %s failed: %v", taskID, err)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, "Speech synthesis failed: "+err.Error())
+		return
+	}
+
+	downloadFilePath := filepath.Join(cfg.FilePaths.DownloadDir, ttsResultFileName(taskID, output.Format))
+	var encoded bytes.Buffer
+	err = output.encode(&encoded, joinPCM(chunks, cfg.TTS.SentenceSilence))
+	if err == nil {
+		err = os.WriteFile(downloadFilePath, encoded.Bytes(), 0644)
+	}
+	if err != nil {
+		utils.Log.Errorf("Failed to write audio for TTS task %s: %v", taskID, err)
+		updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error writing file: %v", err))
+		return
+	}
+	if GlobalTTSCache != nil {
+		_, file, err := GlobalTTSCache.Put(hash, output.Format, func(w io.Writer) error {
+			_, err := w.Write(encoded.Bytes())
+			return err
+		})
 		if err != nil {
-			utils.AbortWithInternalServerError(c, err)
+			utils.Log.Warnf("Failed to cache audio of TTS task %s: %v", taskID, err)
+		} else {
+			file.Close()
+		}
+	}
+
+	GlobalTaskManager.SetTaskFilePath(taskID, downloadFilePath)
+	updateRunningTask(taskID, func(task *TaskInfo) {
+		task.Status = TaskStatusCompleted
+		task.Message = "Speech synthesis completed successfully"
+		task.Progress = 100
+	})
+	utils.Log.Infof("TTS task %s completed. Audio saved to %s", taskID, downloadFilePath)
+}
+
+// TTSDownloadHandler 下载长文本合成任务的音频
+func TTSDownloadHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filePath, ok := completedTaskResult(c, cfg, TaskEngineTTS)
+		if !ok {
 			return
 		}
-		//返回wav文件
-		c.Header("Content-Type", "audio/wav")
-		c.Header("Content-Disposition", "attachment; filename="+fileName)
-		c.File(downloadFilePath)
+		sendResultFile(c, filePath, audio.ContentType(strings.TrimPrefix(filepath.Ext(filePath), ".")))
+	}
+}
+
+// TTSTasksHandler 列出所有长文本合成任务
+func TTSTasksHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listTasks(c, TaskEngineTTS)
 	}
 }
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestJoinPCM(t *testing.T) {
+	// 24kHz 16 位单声道，1ms 为 24 个采样、48 字节
+	gap := make([]byte, 48)
+	tests := []struct {
+		name    string
+		chunks  [][]byte
+		silence time.Duration
+		want    []byte
+	}{
+		{"no chunks", nil, time.Millisecond, []byte{}},
+		{"single chunk has no gap", [][]byte{{1, 2}}, time.Millisecond, []byte{1, 2}},
+		{"no silence", [][]byte{{1, 2}, {3, 4}}, 0, []byte{1, 2, 3, 4}},
+		{"silence between chunks", [][]byte{{1, 2}, {3, 4}, {5, 6}}, time.Millisecond,
+			bytes.Join([][]byte{{1, 2}, gap, {3, 4}, gap, {5, 6}}, nil)},
+		{"incomplete sample dropped", [][]byte{{1, 2, 9}, {3, 4}}, 0, []byte{1, 2, 3, 4}},
+		{"empty chunk", [][]byte{{1, 2}, {}, {3, 4}}, 0, []byte{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := joinPCM(tt.chunks, tt.silence)
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("joinPCM() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
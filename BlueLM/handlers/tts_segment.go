@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultTTSSegmentChars 未登记引擎的单次合成字数上限
+const defaultTTSSegmentChars = 300
+
+// ttsEngineMaxChars 各合成引擎单次调用的字数上限
+var ttsEngineMaxChars = map[string]int{
+	TTSEngineShort:   300,
+	TTSEngineLong:    1000,
+	TTSEngineHuman:   500,
+	TTSEngineReplica: 500,
+}
+
+// ttsSegmentLimit 返回引擎单次合成的字数上限，配置的 max_segment_chars 只能调小
+func ttsSegmentLimit(engine string, configured int) int {
+	limit, ok := ttsEngineMaxChars[engine]
+	if !ok {
+		limit = defaultTTSSegmentChars
+	}
+	if configured > 0 && configured < limit {
+		limit = configured
+	}
+	return limit
+}
+
+// isSentenceEnd 中文句末标点和换行总是断句，西文句末标点只在其后为空白或文本结尾时断句（避免拆开 3.14 等小数）
+func isSentenceEnd(r rune, next rune, last bool) bool {
+	switch r {
+	case '。', '！', '？', '；', '…', '\n':
+		return true
+	case '.', '!', '?', ';':
+		return last || unicode.IsSpace(next)
+	}
+	return false
+}
+
+// isClosingMark 句末标点之后的引号和括号属于同一句
+func isClosingMark(r rune) bool {
+	switch r {
+	case '”', '’', '"', '\'', '）', ')', '」', '』', '】', '》':
+		return true
+	}
+	return false
+}
+
+// isClauseEnd 句内可以断开的标点
+func isClauseEnd(r rune) bool {
+	switch r {
+	case '，', '、', '：', '—', ',', ':':
+		return true
+	}
+	return false
+}
+
+// splitTTSText 将文本切分为逐句合成的片段，每段不超过 maxChars 个字符
+// 先按句末标点断句；超长的句子在逗号等处断开并尽量合并到上限内，仍然超长的部分在空白处或按字数硬切分
+// 不含文字和数字的片段（如单独的省略号）合并到前一句，出现在开头时丢弃
+func splitTTSText(text string, maxChars int) []string {
+	var segments []string
+	for _, sentence := range splitSentences(text) {
+		if utf8.RuneCountInString(sentence) <= maxChars {
+			segments = append(segments, sentence)
+			continue
+		}
+		segments = append(segments, splitLongSentence(sentence, maxChars)...)
+	}
+	return segments
+}
+
+// splitSentences 按句末标点断句，去掉首尾空白
+func splitSentences(text string) []string {
+	runes := []rune(text)
+	var sentences []string
+	var cur strings.Builder
+	flush := func() {
+		sentence := strings.TrimSpace(cur.String())
+		cur.Reset()
+		if sentence == "" {
+			return
+		}
+		if !hasSpeakableRune(sentence) {
+			if len(sentences) > 0 {
+				sentences[len(sentences)-1] += sentence
+			}
+			return
+		}
+		sentences = append(sentences, sentence)
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		cur.WriteRune(r)
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if !isSentenceEnd(r, next, i+1 == len(runes)) {
+			continue
+		}
+		// 连续的句末标点（如 "？！"、"……"）和其后的引号、括号一起归入本句
+		for i+1 < len(runes) && (isClosingMark(runes[i+1]) || isSentenceEnd(runes[i+1], 0, true) && runes[i+1] != '\n') {
+			i++
+			cur.WriteRune(runes[i])
+		}
+		flush()
+	}
+	flush()
+	return sentences
+}
+
+// hasSpeakableRune 判断片段中是否有可朗读的文字或数字
+func hasSpeakableRune(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLongSentence 在句内标点处断开超长的句子，相邻分句合并到 maxChars 以内
+func splitLongSentence(sentence string, maxChars int) []string {
+	var clauses []string
+	start := 0
+	for i, r := range sentence {
+		if isClauseEnd(r) {
+			end := i + utf8.RuneLen(r)
+			clauses = append(clauses, sentence[start:end])
+			start = end
+		}
+	}
+	if start < len(sentence) {
+		clauses = append(clauses, sentence[start:])
+	}
+
+	var parts []string
+	var cur string
+	for _, clause := range clauses {
+		if utf8.RuneCountInString(clause) > maxChars {
+			if strings.TrimSpace(cur) != "" {
+				parts = append(parts, strings.TrimSpace(cur))
+			}
+			cur = ""
+			parts = append(parts, hardSplit(clause, maxChars)...)
+			continue
+		}
+		if utf8.RuneCountInString(cur)+utf8.RuneCountInString(clause) > maxChars {
+			parts = append(parts, strings.TrimSpace(cur))
+			cur = ""
+		}
+		cur += clause
+	}
+	if strings.TrimSpace(cur) != "" {
+		parts = append(parts, strings.TrimSpace(cur))
+	}
+	return parts
+}
+
+// hardSplit 没有可用标点时按字数切分，优先在上限内最后一个空白处断开，避免拆开西文单词
+func hardSplit(text string, maxChars int) []string {
+	var parts []string
+	runes := []rune(strings.TrimSpace(text))
+	for len(runes) > maxChars {
+		cut := maxChars
+		for i := maxChars; i > maxChars/2; i-- {
+			if unicode.IsSpace(runes[i]) {
+				cut = i
+				break
+			}
+		}
+		if part := strings.TrimSpace(string(runes[:cut])); part != "" {
+			parts = append(parts, part)
+		}
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitTTSText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{"sentences", "你好。今天天气不错！", 300, []string{"你好。", "今天天气不错！"}},
+		{"decimal point", "Pi is 3.14. Really?", 300, []string{"Pi is 3.14.", "Really?"}},
+		{"closing quote", "他说：“走吧。”然后离开了。", 300, []string{"他说：“走吧。”", "然后离开了。"}},
+		{"repeated marks", "真的吗？！……好吧", 300, []string{"真的吗？！……", "好吧"}},
+		{"leading punctuation dropped", "……开头。第二句", 300, []string{"开头。", "第二句"}},
+		{"trailing punctuation merged", "结束了。……", 300, []string{"结束了。……"}},
+		{"newline", "第一行\n\n  第二行  ", 300, []string{"第一行", "第二行"}},
+		{"clauses", "一二三，四五六，七八九。", 7, []string{"一二三，", "四五六，", "七八九。"}},
+		{"clauses merged", "一二三，四五六，七八九。", 8, []string{"一二三，四五六，", "七八九。"}},
+		{"hard split at space", "aaaa bbbb cccc", 10, []string{"aaaa bbbb", "cccc"}},
+		{"hard split by chars", "一二三四五", 2, []string{"一二", "三四", "五"}},
+		{"whitespace only", "  \n ", 300, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitTTSText(tt.text, tt.maxChars)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitTTSText(%q, %d) = %q, want %q", tt.text, tt.maxChars, got, tt.want)
+			}
+		})
+	}
+}
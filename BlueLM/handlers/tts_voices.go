@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 语音合成引擎ID
+const (
+	TTSEngineShort   = "short_audio_synthesis_jovi"
+	TTSEngineLong    = "long_audio_synthesis_screen"
+	TTSEngineHuman   = "tts_humanoid_lam"
+	TTSEngineReplica = "tts_replica" // 音色复刻专用，vcn 为复刻得到的音色ID
+)
+
+// ttsModes 请求中的简写模式与合成引擎ID的对应关系
+var ttsModes = []struct {
+	Mode   string `json:"mode"`
+	Engine string `json:"engine"`
+}{
+	{"short", TTSEngineShort},
+	{"long", TTSEngineLong},
+	{"human", TTSEngineHuman},
+	{"replica", TTSEngineReplica},
+}
+
+// TTSVoice 一个可用的合成音色
+type TTSVoice struct {
+	Mode     string `json:"mode"`   // 请求中的简写模式
+	Engine   string `json:"engine"` // 合成引擎ID
+	Vcn      string `json:"vcn"`
+	Name     string `json:"name"`
+	Gender   string `json:"gender,omitempty"`  // female 或 male，未标明时为空
+	Language string `json:"language"`          // zh、en、hmn（苗语）
+	Dialect  string `json:"dialect,omitempty"` // cantonese、sichuanese，普通话为空
+	Style    string `json:"style,omitempty"`   // 音色风格
+	Default  bool   `json:"default,omitempty"` // 请求未指定 vcn 时使用该引擎的这个音色
+}
+
+// ttsVoices 音色登记表，整理自 TTS音色.md
+var ttsVoices = []TTSVoice{
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "vivoHelper", Name: "奕雯", Gender: "female", Language: "zh", Default: true},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "yunye", Name: "云野", Gender: "female", Language: "zh", Style: "温柔"},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "wanqing", Name: "婉清", Gender: "female", Language: "zh", Style: "御姐"},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "xiaofu", Name: "晓芙", Gender: "female", Language: "zh", Style: "少女"},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "yige_child", Name: "小萌", Gender: "female", Language: "zh", Style: "女童"},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "yige", Name: "依格", Gender: "female", Language: "zh"},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "yiyi", Name: "依依", Gender: "female", Language: "zh"},
+	{Mode: "short", Engine: TTSEngineShort, Vcn: "xiaoming", Name: "小茗", Language: "zh"},
+
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_vivoHelper", Name: "奕雯", Gender: "female", Language: "zh", Default: true},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_yige", Name: "依格", Gender: "female", Language: "zh", Style: "甜美"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_yige_news", Name: "依格", Gender: "female", Language: "zh", Style: "稳重"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_yunye", Name: "云野", Gender: "female", Language: "zh", Style: "温柔"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_yunye_news", Name: "云野", Gender: "female", Language: "zh", Style: "稳重"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_M02", Name: "怀斌", Gender: "male", Language: "zh", Style: "浑厚"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_M05", Name: "兆坤", Gender: "male", Language: "zh", Style: "成熟"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_M10", Name: "亚恒", Gender: "male", Language: "zh", Style: "磁性"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_F163", Name: "晓云", Gender: "female", Language: "zh", Style: "稳重"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_F25", Name: "倩倩", Gender: "female", Language: "zh", Style: "清甜"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_F22", Name: "海蔚", Gender: "female", Language: "zh", Style: "大气"},
+	{Mode: "long", Engine: TTSEngineLong, Vcn: "x2_F82", Name: "英文女声", Gender: "female", Language: "en"},
+
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "F245_natural", Name: "知性柔美", Gender: "female", Language: "zh", Style: "知性"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "M24", Name: "俊朗男声", Gender: "male", Language: "zh", Style: "俊朗", Default: true},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "M193", Name: "理性男声", Gender: "male", Language: "zh", Style: "理性"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "GAME_GIR_YG", Name: "游戏少女", Gender: "female", Language: "zh", Style: "游戏"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "GAME_GIR_MB", Name: "游戏萌宝", Gender: "female", Language: "zh", Style: "游戏"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "GAME_GIR_YJ", Name: "游戏御姐", Gender: "female", Language: "zh", Style: "游戏"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "GAME_GIR_LTY", Name: "电台主播", Gender: "female", Language: "zh", Style: "电台"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "YIGEXIAOV", Name: "依格", Gender: "female", Language: "zh"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "FY_CANTONESE", Name: "粤语", Language: "zh", Dialect: "cantonese", Style: "方言"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "FY_SICHUANHUA", Name: "四川话", Language: "zh", Dialect: "sichuanese", Style: "方言"},
+	{Mode: "human", Engine: TTSEngineHuman, Vcn: "FY_MIAOYU", Name: "苗语", Language: "hmn", Style: "方言"},
+}
+
+// ttsEngineID 将请求中的简写模式转换为合成引擎ID，引擎ID原样返回，无法识别时返回空字符串
+func ttsEngineID(mode string) string {
+	for _, m := range ttsModes {
+		if mode == m.Mode || mode == m.Engine {
+			return m.Engine
+		}
+	}
+	return ""
+}
+
+// ttsEngineVoices 返回引擎登记的全部音色
+func ttsEngineVoices(engine string) []TTSVoice {
+	var voices []TTSVoice
+	for _, v := range ttsVoices {
+		if v.Engine == engine {
+			voices = append(voices, v)
+		}
+	}
+	return voices
+}
+
+// validModes 列出可用的模式，用于错误信息
+func validModes() string {
+	modes := make([]string, len(ttsModes))
+	for i, m := range ttsModes {
+		modes[i] = m.Mode
+	}
+	return strings.Join(modes, ", ")
+}
+
+// resolveTTSVoice 检查模式和音色是否匹配，返回引擎ID和实际使用的音色
+// 未指定 vcn 时使用引擎的默认音色；复刻模式的 vcn 为复刻得到的音色ID，不在登记表中，只检查是否填写
+func resolveTTSVoice(mode, vcn string) (string, string, []utils.FieldError) {
+	engine := ttsEngineID(mode)
+	if engine == "" {
+		return "", "", []utils.FieldError{{
+			Field: "mode",
+			Error: fmt.Sprintf("unsupported mode %q, must be one of: %s", mode, validModes()),
+		}}
+	}
+	if engine == TTSEngineReplica {
+		if vcn == "" {
+			return "", "", []utils.FieldError{{
+				Field: "vcn",
+				Error: "vcn is required for mode replica: use the voice ID returned by voice replication",
+			}}
+		}
+		return engine, vcn, nil
+	}
+
+	voices := ttsEngineVoices(engine)
+	choices := make([]string, len(voices))
+	for i, v := range voices {
+		if vcn == "" && v.Default || v.Vcn == vcn {
+			return engine, v.Vcn, nil
+		}
+		choices[i] = v.Vcn
+	}
+
+	message := fmt.Sprintf("vcn %q is not available for mode %s, valid choices: %s", vcn, voices[0].Mode, strings.Join(choices, ", "))
+	for _, v := range ttsVoices {
+		if v.Vcn == vcn {
+			message += fmt.Sprintf(" (%s is a voice of mode %s)", vcn, v.Mode)
+			break
+		}
+	}
+	return "", "", []utils.FieldError{{Field: "vcn", Error: message}}
+}
+
+// TTSVoicesHandler 列出可用的合成音色，可按 mode、gender、language 过滤
+func TTSVoicesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		engine := ""
+		if mode := c.Query("mode"); mode != "" {
+			if engine = ttsEngineID(mode); engine == "" {
+				utils.AbortWithBadRequest(c, nil, fmt.Sprintf("Unsupported mode %q, must be one of: %s", mode, validModes()))
+				return
+			}
+		}
+		gender := c.Query("gender")
+		language := c.Query("language")
+
+		voices := make([]TTSVoice, 0, len(ttsVoices))
+		for _, v := range ttsVoices {
+			if engine != "" && v.Engine != engine ||
+				gender != "" && v.Gender != gender ||
+				language != "" && v.Language != language {
+				continue
+			}
+			voices = append(voices, v)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"modes":   ttsModes,
+			"voices":  voices,
+			"total":   len(voices),
+		})
+	}
+}
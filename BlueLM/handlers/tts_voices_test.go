@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveTTSVoice(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode, vcn  string
+		wantEngine string
+		wantVcn    string
+		errField   string
+		errText    string
+	}{
+		{name: "default voice", mode: "short", wantEngine: TTSEngineShort, wantVcn: "vivoHelper"},
+		{name: "listed voice", mode: "long", vcn: "x2_M02", wantEngine: TTSEngineLong, wantVcn: "x2_M02"},
+		{name: "engine id as mode", mode: TTSEngineHuman, wantEngine: TTSEngineHuman, wantVcn: "M24"},
+		{name: "replica voice", mode: "replica", vcn: "my_voice", wantEngine: TTSEngineReplica, wantVcn: "my_voice"},
+		{name: "replica without vcn", mode: "replica", errField: "vcn", errText: "required"},
+		{name: "unknown mode", mode: "fast", errField: "mode", errText: "short, long, human, replica"},
+		{name: "voice of another mode", mode: "short", vcn: "x2_M02", errField: "vcn", errText: "x2_M02 is a voice of mode long"},
+		{name: "unknown voice", mode: "human", vcn: "nobody", errField: "vcn", errText: "valid choices: F245_natural"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, vcn, errs := resolveTTSVoice(tt.mode, tt.vcn)
+			if tt.errField != "" {
+				if len(errs) != 1 || errs[0].Field != tt.errField || !strings.Contains(errs[0].Error, tt.errText) {
+					t.Fatalf("resolveTTSVoice() errors = %+v, want %s error containing %q", errs, tt.errField, tt.errText)
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("resolveTTSVoice() errors = %+v", errs)
+			}
+			if engine != tt.wantEngine || vcn != tt.wantVcn {
+				t.Errorf("resolveTTSVoice() = %s, %s; want %s, %s", engine, vcn, tt.wantEngine, tt.wantVcn)
+			}
+		})
+	}
+}
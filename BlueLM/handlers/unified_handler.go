@@ -6,9 +6,9 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-gonic/gin"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
 )
@@ -19,11 +19,13 @@ import (
 // POST /model/?model=bluelm&action=submit
 // GET /model/?model=whisperx&action=status&task_id=xxx
 // GET /model/?model=bluelm&action=status&task_id=xxx
-// GET /model/?model=whisperx&action=download&task_id=xxx&file_name=xxx
-// GET /model/?model=bluelm&action=download&task_id=xxx
+// GET /model/?model=whisperx&action=download&task_id=xxx&file_name=xxx[&format=srt|vtt|txt|tsv]
+// GET /model/?model=bluelm&action=download&task_id=xxx[&format=srt|vtt|txt|tsv]
 // GET /model/?model=whisperx&action=list
 // GET /model/?model=bluelm&action=list
-func UnifiedModelHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
+// POST /model/?model=whisperx&action=cancel&task_id=xxx
+// POST /model/?model=bluelm&action=cancel&task_id=xxx
+func UnifiedModelHandler(resolver *ai.Resolver, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取模型类型
 		model := strings.ToLower(c.Query("model"))
@@ -35,7 +37,7 @@ func UnifiedModelHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 		// 获取操作类型
 		action := strings.ToLower(c.Query("action"))
 		if action == "" {
-			utils.AbortWithBadRequest(c, nil, "Action parameter is required (submit, status, download, list)")
+			utils.AbortWithBadRequest(c, nil, "Action parameter is required (submit, status, download, list, cancel)")
 			return
 		}
 
@@ -44,7 +46,7 @@ func UnifiedModelHandler(app *vivo.Vivo, cfg *config.Config) gin.HandlerFunc {
 		case "whisperx":
 			handleWhisperXRequest(c, cfg, action)
 		case "bluelm":
-			handleBlueLMRequest(c, app, cfg, action)
+			handleBlueLMRequest(c, resolver, cfg, action)
 		default:
 			utils.AbortWithBadRequest(c, nil, "Unsupported model. Supported models: whisperx, bluelm")
 		}
@@ -73,6 +75,10 @@ func handleWhisperXRequest(c *gin.Context, cfg *config.Config, action string) {
 			utils.AbortWithBadRequest(c, nil, "task_id parameter is required")
 			return
 		}
+		if fileName == "" && c.Query("format") != "" {
+			// 导出字幕时默认以基础转录结果为时间轴
+			fileName = "transcription"
+		}
 		if fileName == "" {
 			utils.AbortWithBadRequest(c, nil, "file_name parameter is required (transcription, wordstamps, diarization, speaker)")
 			return
@@ -81,20 +87,28 @@ func handleWhisperXRequest(c *gin.Context, cfg *config.Config, action string) {
 	case "list":
 		// 处理任务列表查询
 		handleWhisperXList(c, cfg)
+	case "cancel":
+		// 处理任务取消
+		taskID := c.Query("task_id")
+		if taskID == "" {
+			utils.AbortWithBadRequest(c, nil, "task_id parameter is required")
+			return
+		}
+		respondTaskCancel(c, cfg, taskID, TaskEngineWhisperX)
 	case "models":
 		// 处理模型信息查询
 		handleWhisperXModels(c, cfg)
 	default:
-		utils.AbortWithBadRequest(c, nil, "Unsupported action for WhisperX. Supported actions: submit, status, download, list, models")
+		utils.AbortWithBadRequest(c, nil, "Unsupported action for WhisperX. Supported actions: submit, status, download, list, models, cancel")
 	}
 }
 
 // handleBlueLMRequest 处理BlueLM相关请求
-func handleBlueLMRequest(c *gin.Context, app *vivo.Vivo, cfg *config.Config, action string) {
+func handleBlueLMRequest(c *gin.Context, resolver *ai.Resolver, cfg *config.Config, action string) {
 	switch action {
 	case "submit":
 		// 调用支持配置优先级的BlueLM转录处理器
-		TranscriptionHandler(app, cfg)(c)
+		TranscriptionHandler(resolver, cfg)(c)
 	case "status":
 		// 处理状态查询
 		taskID := c.Query("task_id")
@@ -114,32 +128,55 @@ func handleBlueLMRequest(c *gin.Context, app *vivo.Vivo, cfg *config.Config, act
 	case "list":
 		// 处理任务列表查询
 		handleBlueLMList(c, cfg)
+	case "cancel":
+		// 处理任务取消
+		taskID := c.Query("task_id")
+		if taskID == "" {
+			utils.AbortWithBadRequest(c, nil, "task_id parameter is required")
+			return
+		}
+		respondTaskCancel(c, cfg, taskID, TaskEngineBlueLM)
 	default:
-		utils.AbortWithBadRequest(c, nil, "Unsupported action for BlueLM. Supported actions: submit, status, download, list")
+		utils.AbortWithBadRequest(c, nil, "Unsupported action for BlueLM. Supported actions: submit, status, download, list, cancel")
 	}
 }
 
 // WhisperX相关的具体处理函数
+// handleWhisperXStatus 优先返回 WhisperX 服务的实时状态（包含转录数据）；
+// 服务不可用或已丢失该任务时，回退到本地任务注册表中的记录
 func handleWhisperXStatus(c *gin.Context, cfg *config.Config, taskID string) {
-	// 直接调用WhisperX服务的状态查询API
-	statusURL := fmt.Sprintf("%s/whisperx/status/%s", cfg.WhisperX.URL, taskID)
-	resp, err := http.Get(statusURL)
-	if err != nil {
-		utils.AbortWithInternalServerError(c, err)
+	statusCode, body, err := fetchWhisperXStatus(taskID, cfg)
+	if err == nil && statusCode != http.StatusNotFound {
+		c.Data(statusCode, "application/json", body)
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		utils.AbortWithInternalServerError(c, err)
+	task, exists := GlobalTaskManager.GetTask(taskID)
+	if !exists || task.Engine != TaskEngineWhisperX {
+		if err != nil {
+			utils.AbortWithInternalServerError(c, err)
+			return
+		}
+		c.Data(statusCode, "application/json", body)
 		return
 	}
 
-	c.Data(resp.StatusCode, "application/json", body)
+	if err != nil {
+		utils.Log.Warnf("WhisperX service unavailable, serving local status for task %s: %v", taskID, err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"source":  "local",
+		"task":    task,
+	})
 }
 
 func handleWhisperXDownload(c *gin.Context, cfg *config.Config, taskID, fileName string) {
+	if format := c.Query("format"); format != "" && format != "json" {
+		renderWhisperXSubtitle(c, cfg, taskID, fileName, format)
+		return
+	}
+
 	// 直接调用WhisperX服务的下载API
 	downloadURL := fmt.Sprintf("%s/whisperx/download/%s/%s", cfg.WhisperX.URL, taskID, fileName)
 	resp, err := http.Get(downloadURL)
@@ -165,11 +202,8 @@ func handleWhisperXDownload(c *gin.Context, cfg *config.Config, taskID, fileName
 }
 
 func handleWhisperXList(c *gin.Context, _ *config.Config) {
-	// WhisperX暂时没有列表功能，返回提示信息
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error":   "List functionality not implemented for WhisperX",
-		"message": "WhisperX service does not support task listing",
-	})
+	// 从本地任务注册表中列出WhisperX任务
+	listTasks(c, TaskEngineWhisperX)
 }
 
 func handleWhisperXModels(c *gin.Context, cfg *config.Config) {
@@ -206,7 +240,6 @@ func handleBlueLMDownload(c *gin.Context, cfg *config.Config, taskID string) {
 	TranscriptionDownloadHandler(cfg)(c)
 }
 
-func handleBlueLMList(c *gin.Context, cfg *config.Config) {
-	// 调用原有的任务列表处理器
-	TranscriptionTasksHandler(cfg)(c)
+func handleBlueLMList(c *gin.Context, _ *config.Config) {
+	listTasks(c, TaskEngineBlueLM)
 }
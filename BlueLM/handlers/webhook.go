@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
+)
+
+// 回调投递状态
+const (
+	CallbackStatusPending   = "pending"
+	CallbackStatusDelivered = "delivered"
+	CallbackStatusFailed    = "failed"
+)
+
+// 回调请求头
+const (
+	WebhookHeaderEvent     = "X-AuraLab-Event"
+	WebhookHeaderDelivery  = "X-AuraLab-Delivery"
+	WebhookHeaderTimestamp = "X-AuraLab-Timestamp"
+	WebhookHeaderSignature = "X-AuraLab-Signature"
+)
+
+// CallbackAttempt 一次回调投递的记录
+type CallbackAttempt struct {
+	Event      string    `json:"event"` // 投递的事件，每个事件单独计算重试次数
+	Attempt    int       `json:"attempt"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// callbackTarget 提交任务时指定的回调地址和签名密钥
+type callbackTarget struct {
+	URL    string
+	Secret string
+}
+
+// apply 将回调设置写入任务，未指定回调地址时不做任何修改
+func (t callbackTarget) apply(task *TaskInfo) {
+	if t.URL == "" {
+		return
+	}
+	task.CallbackURL = t.URL
+	task.CallbackSecret = t.Secret
+}
+
+// parseCallbackTarget 读取并校验表单中的 callback_url 和 callback_secret
+func parseCallbackTarget(c *gin.Context) (callbackTarget, error) {
+	return newCallbackTarget(c.PostForm("callback_url"), c.PostForm("callback_secret"))
+}
+
+// newCallbackTarget 校验回调地址和密钥，用于 JSON 请求体中的 callback_url / callback_secret
+func newCallbackTarget(callbackURL, secret string) (callbackTarget, error) {
+	target := callbackTarget{URL: callbackURL, Secret: secret}
+	if target.URL == "" {
+		if target.Secret != "" {
+			return callbackTarget{}, fmt.Errorf("callback_secret requires callback_url")
+		}
+		return target, nil
+	}
+
+	u, err := url.Parse(target.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return callbackTarget{}, fmt.Errorf("callback_url must be an absolute http(s) URL")
+	}
+	return target, nil
+}
+
+// WebhookPayload 回调请求体
+type WebhookPayload struct {
+	Event     string            `json:"event"` // task.completed / task.failed / task.cancelled
+	Task      *TaskInfo         `json:"task"`
+	Downloads map[string]string `json:"downloads,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// signWebhook 计算回调签名：HMAC-SHA256(secret, timestamp + "." + body)
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookDispatcher 在任务进入终止状态时向回调地址投递通知，失败时按指数退避重试
+// 同一任务的投递串行执行，任务状态变化后旧事件的重试随即停止
+type webhookDispatcher struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	locks map[string]*webhookTaskLock
+}
+
+// webhookTaskLock 单个任务的投递锁，没有等待者时从 locks 中删除
+type webhookTaskLock struct {
+	sync.Mutex
+	refs int
+}
+
+// newWebhookDispatcher 创建回调投递器
+func newWebhookDispatcher(cfg *config.Config) *webhookDispatcher {
+	return &webhookDispatcher{
+		cfg:    cfg.Webhook,
+		client: &http.Client{Timeout: cfg.Webhook.Timeout},
+		locks:  make(map[string]*webhookTaskLock),
+	}
+}
+
+// lockTask 获取任务的投递锁，返回解锁函数
+func (d *webhookDispatcher) lockTask(taskID string) func() {
+	d.mu.Lock()
+	lock, exists := d.locks[taskID]
+	if !exists {
+		lock = &webhookTaskLock{}
+		d.locks[taskID] = lock
+	}
+	lock.refs++
+	d.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(d.locks, taskID)
+		}
+	}
+}
+
+// countAttempts 返回事件已经投递的次数
+func countAttempts(task *TaskInfo, event string) int {
+	n := 0
+	for _, attempt := range task.CallbackAttempts {
+		if attempt.Event == event {
+			n++
+		}
+	}
+	return n
+}
+
+// onTaskChange 任务变更监听器，只在任务首次进入终止状态时触发投递
+func (d *webhookDispatcher) onTaskChange(prev, cur *TaskInfo) {
+	if cur.CallbackURL == "" || !cur.Status.IsFinished() {
+		return
+	}
+	if prev != nil && prev.Status == cur.Status {
+		return
+	}
+	// 监听器在任务管理器的锁内执行，投递和记录结果需要另起协程
+	go d.deliver(cur.TaskID)
+}
+
+// resumePending 服务重启后继续投递未完成的回调
+func (d *webhookDispatcher) resumePending() {
+	for _, task := range GlobalTaskManager.GetAllTasks() {
+		if task.CallbackURL == "" || !task.Status.IsFinished() {
+			continue
+		}
+		if task.CallbackStatus == "" || task.CallbackStatus == CallbackStatusPending {
+			utils.Log.Infof("Resuming webhook delivery for task %s", task.TaskID)
+			go d.deliver(task.TaskID)
+		}
+	}
+}
+
+// deliver 投递任务当前终止状态对应的回调，直到成功、达到最大次数或任务状态再次变化
+func (d *webhookDispatcher) deliver(taskID string) {
+	unlock := d.lockTask(taskID)
+	defer unlock()
+
+	task, exists := GlobalTaskManager.GetTask(taskID)
+	if !exists || !task.Status.IsFinished() {
+		return
+	}
+	status := task.Status
+	// stale 判断任务是否已离开本次投递的状态，此时由新状态的投递接管
+	stale := func() bool {
+		current, exists := GlobalTaskManager.GetTask(taskID)
+		return !exists || current.Status != status
+	}
+
+	payload := WebhookPayload{
+		Event:     "task." + string(task.Status),
+		Task:      task,
+		Timestamp: time.Now(),
+	}
+	if task.Status == TaskStatusCompleted {
+		payload.Downloads = taskDownloadLinks(task)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		utils.Log.Errorf("Failed to encode webhook payload for task %s: %v", taskID, err)
+		return
+	}
+
+	GlobalTaskManager.UpdateTask(taskID, func(t *TaskInfo) {
+		if t.Status == status {
+			t.CallbackStatus = CallbackStatusPending
+		}
+	})
+
+	// 同一任务同一终止状态的多次投递使用相同的 ID，便于接收方去重
+	deliveryID := taskID + ":" + string(status)
+	backoff := d.cfg.InitialBackoff
+	for attempt := countAttempts(task, payload.Event) + 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		if stale() {
+			utils.Log.Infof("Webhook %s for task %s superseded by a later status", payload.Event, taskID)
+			return
+		}
+		record := d.post(task, payload.Event, deliveryID, body)
+		record.Event = payload.Event
+		record.Attempt = attempt
+
+		delivered := record.Error == ""
+		GlobalTaskManager.UpdateTask(taskID, func(t *TaskInfo) {
+			t.CallbackAttempts = append(t.CallbackAttempts, record)
+			if t.Status != status {
+				return
+			}
+			switch {
+			case delivered:
+				t.CallbackStatus = CallbackStatusDelivered
+			case attempt == d.cfg.MaxAttempts:
+				t.CallbackStatus = CallbackStatusFailed
+			}
+		})
+
+		if delivered {
+			utils.Log.Infof("Webhook for task %s delivered on attempt %d", taskID, attempt)
+			return
+		}
+		utils.Log.Warnf("Webhook for task %s attempt %d/%d failed: %s", taskID, attempt, d.cfg.MaxAttempts, record.Error)
+		if attempt == d.cfg.MaxAttempts {
+			break
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+
+	// 达到最大次数（包括重启前已用完次数的情况）
+	GlobalTaskManager.UpdateTask(taskID, func(t *TaskInfo) {
+		if t.Status == status && t.CallbackStatus != CallbackStatusDelivered {
+			t.CallbackStatus = CallbackStatusFailed
+		}
+	})
+}
+
+// post 发送一次回调请求，非 2xx 响应视为失败
+func (d *webhookDispatcher) post(task *TaskInfo, event, deliveryID string, body []byte) (record CallbackAttempt) {
+	record.At = time.Now()
+	defer func() {
+		record.DurationMs = time.Since(record.At).Milliseconds()
+	}()
+
+	req, err := http.NewRequest("POST", task.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	timestamp := strconv.FormatInt(record.At.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, event)
+	req.Header.Set(WebhookHeaderDelivery, deliveryID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if task.CallbackSecret != "" {
+		req.Header.Set(WebhookHeaderSignature, signWebhook(task.CallbackSecret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	record.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		record.Error = fmt.Sprintf("callback returned status %d", resp.StatusCode)
+	}
+	return record
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "known signature",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      `{"event":"task.completed"}`,
+			want:      "sha256=8476087d712b027a668e5e7019c04b9b70e5a33b56bba5e57c8aa39aa44ea5e3",
+		},
+		{
+			name: "empty input",
+			want: "sha256=0d0ab78babcce47b6860946aad720dcc13630f70074364b65665c4caefb81ecf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signWebhook(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("signWebhook() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// 接收方按文档中的方式校验签名
+	body := []byte(`{"task_id":"abc"}`)
+	mac := hmac.New(sha256.New, []byte("k"))
+	mac.Write([]byte("42." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signWebhook("k", "42", body) != want {
+		t.Error("signature does not match HMAC-SHA256(secret, timestamp + \".\" + body)")
+	}
+	// 时间戳参与签名，防止重放时篡改
+	if signWebhook("k", "42", body) == signWebhook("k", "43", body) {
+		t.Error("signature should depend on the timestamp")
+	}
+}
+
+func TestCountAttempts(t *testing.T) {
+	task := &TaskInfo{CallbackAttempts: []CallbackAttempt{
+		{Event: "task.failed", Attempt: 1},
+		{Event: "task.completed", Attempt: 1},
+		{Event: "task.completed", Attempt: 2},
+		{Event: "task.completed", Attempt: 3},
+	}}
+	tests := []struct {
+		event string
+		want  int
+	}{
+		{"task.completed", 3},
+		{"task.failed", 1},
+		{"task.cancelled", 0},
+	}
+	for _, tt := range tests {
+		if got := countAttempts(task, tt.event); got != tt.want {
+			t.Errorf("countAttempts(%q) = %d, want %d", tt.event, got, tt.want)
+		}
+	}
+	if got := countAttempts(&TaskInfo{}, "task.completed"); got != 0 {
+		t.Errorf("countAttempts() without attempts = %d, want 0", got)
+	}
+}
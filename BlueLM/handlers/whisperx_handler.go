@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
@@ -18,6 +20,12 @@ import (
 // WhisperXHandler 代理对 Flask WhisperX 服务的请求
 func WhisperXHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		callback, err := parseCallbackTarget(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid callback parameters")
+			return
+		}
+
 		// 1. 保存上传的文件
 		uploadFilePath, err := utils.SaveUploadedFile(c, cfg.FilePaths.UploadDir)
 		if err != nil {
@@ -26,7 +34,7 @@ func WhisperXHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 2. 异步调用 callWhisperXService 函数
-		taskID, err := startWhisperXService(uploadFilePath, cfg)
+		taskID, err := startWhisperXService(uploadFilePath, uploadedFilename(c), cfg, callback)
 		if err != nil {
 			utils.AbortWithInternalServerError(c, err)
 			return
@@ -40,6 +48,12 @@ func WhisperXHandler(cfg *config.Config) gin.HandlerFunc {
 // EnhancedWhisperXHandler 增强版的WhisperX处理器，支持更多参数
 func EnhancedWhisperXHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		callback, err := parseCallbackTarget(c)
+		if err != nil {
+			utils.AbortWithBadRequest(c, err, "Invalid callback parameters")
+			return
+		}
+
 		// 1. 保存上传的文件
 		uploadFilePath, err := utils.SaveUploadedFile(c, cfg.FilePaths.UploadDir)
 		if err != nil {
@@ -57,7 +71,7 @@ func EnhancedWhisperXHandler(cfg *config.Config) gin.HandlerFunc {
 		params.ModelName = c.PostForm("model_name")
 
 		// 3. 异步调用增强的WhisperX服务
-		taskID, err := startEnhancedWhisperXService(uploadFilePath, cfg, params)
+		taskID, err := startEnhancedWhisperXService(uploadFilePath, uploadedFilename(c), cfg, params, callback)
 		if err != nil {
 			utils.AbortWithInternalServerError(c, err)
 			return
@@ -79,7 +93,7 @@ type WhisperXParams struct {
 }
 
 // startWhisperXService 启动 WhisperX 服务并返回任务 ID
-func startWhisperXService(filePath string, cfg *config.Config) (string, error) {
+func startWhisperXService(filePath, filename string, cfg *config.Config, callback callbackTarget) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %v", err)
@@ -139,59 +153,173 @@ func startWhisperXService(filePath string, cfg *config.Config) (string, error) {
 		return "", fmt.Errorf("no task_id in response")
 	}
 
-	go pollWhisperXStatus(taskID, cfg)
+	// 与 WhisperX 服务端的默认参数保持一致
+	registerWhisperXTask(taskID, filename, filePath, WhisperXParams{EnableWordTimestamps: true}, callback)
+	go pollWhisperXStatus(startTaskPoller(taskID), taskID, cfg)
 
 	return taskID, nil
 }
 
-// pollWhisperXStatus 轮询 WhisperX 任务状态
-func pollWhisperXStatus(taskID string, cfg *config.Config) {
+// registerWhisperXTask 在本地任务注册表中登记 WhisperX 任务
+// HuggingFace Token 属于敏感信息，不随任务参数保存
+func registerWhisperXTask(taskID, filename, uploadPath string, params WhisperXParams, callback callbackTarget) {
+	params.HuggingFaceToken = ""
+
+	GlobalTaskManager.CreateTask(taskID, filename, TaskEngineWhisperX)
+	GlobalTaskManager.UpdateTask(taskID, func(task *TaskInfo) {
+		task.Options = &params
+		task.UploadPath = uploadPath
+		task.Message = "Task submitted to WhisperX service"
+		callback.apply(task)
+	})
+}
+
+// mapWhisperXStatus 将 WhisperX 服务的细分状态映射为本地任务状态、处理阶段和估算进度
+// WhisperX 不提供百分比进度，这里按流水线阶段估算；返回的阶段为空表示保持原阶段不变
+func mapWhisperXStatus(status string) (TaskStatus, string, int) {
+	switch status {
+	case "queued":
+		return TaskStatusPending, "", 0
+	case "processing", "transcription_processing":
+		return TaskStatusProcessing, WhisperXStageTranscription, 10
+	case "transcription_completed":
+		return TaskStatusProcessing, WhisperXStageTranscription, 40
+	case "alignment_processing":
+		return TaskStatusProcessing, WhisperXStageAlignment, 50
+	case "alignment_completed":
+		return TaskStatusProcessing, WhisperXStageAlignment, 70
+	case "diarization_processing":
+		return TaskStatusProcessing, WhisperXStageDiarization, 80
+	case "completed":
+		return TaskStatusCompleted, "", 100
+	case "failed":
+		return TaskStatusFailed, "", -1
+	case "cancelled":
+		return TaskStatusCancelled, "", -1
+	default:
+		return TaskStatusProcessing, "", -1
+	}
+}
+
+// whisperXStatusResponse WhisperX 状态接口中与任务注册表相关的字段
+type whisperXStatusResponse struct {
+	Status         string   `json:"status"`
+	Message        string   `json:"message"`
+	Error          string   `json:"error"`
+	AvailableFiles []string `json:"available_files"`
+}
+
+// whisperXResultFileName 返回本地保存的 WhisperX 结果文件名
+func whisperXResultFileName(taskID string) string {
+	return "whisperx_result_" + taskID + ".json"
+}
+
+// pollWhisperXStatus 轮询 WhisperX 任务状态并同步到本地任务注册表，ctx 被取消时停止轮询
+func pollWhisperXStatus(ctx context.Context, taskID string, cfg *config.Config) {
+	defer finishTaskPoller(taskID)
+
 	maxAttempts := 450 // 最大轮询次数 (450次 * 2秒 = 15分钟)
 	attempts := 0
 
 	for attempts < maxAttempts {
 		attempts++
-		time.Sleep(2 * time.Second) // 每2秒查询一次
+		select {
+		case <-ctx.Done():
+			utils.Log.Infof("WhisperX task %s polling stopped: %v", taskID, ctx.Err())
+			return
+		case <-time.After(2 * time.Second): // 每2秒查询一次
+		}
 
-		statusResp, err := http.Get(fmt.Sprintf("%s/whisperx/status/%s", cfg.WhisperX.URL, taskID))
+		statusCode, statusBody, err := fetchWhisperXStatus(taskID, cfg)
 		if err != nil {
 			utils.Log.Errorf("failed to get task status: %v", err)
 			continue
 		}
-		defer statusResp.Body.Close()
+		if ctx.Err() != nil {
+			return
+		}
 
-		statusBody, err := io.ReadAll(statusResp.Body)
-		if err != nil {
-			utils.Log.Errorf("failed to read status response body: %v", err)
-			continue
+		// WhisperX 服务只在内存中保存任务，服务重启后任务会丢失
+		if statusCode == http.StatusNotFound {
+			utils.Log.Errorf("WhisperX task %s no longer exists on WhisperX service", taskID)
+			updateRunningTaskStatus(taskID, TaskStatusFailed, "Task lost by WhisperX service (the service may have been restarted), please submit the file again")
+			return
 		}
 
-		var statusResult map[string]interface{}
+		var statusResult whisperXStatusResponse
 		if err := json.Unmarshal(statusBody, &statusResult); err != nil {
 			utils.Log.Errorf("failed to parse status JSON response: %v", err)
 			continue
 		}
 
-		if status, ok := statusResult["status"].(string); ok && status == "completed" {
-			// 将结果保存到文件
-			fileName := fmt.Sprintf("%swhisperx_result_%s.json", cfg.FilePaths.DownloadDir, taskID)
-			if err := os.WriteFile(fileName, statusBody, 0644); err != nil {
+		status, stage, progress := mapWhisperXStatus(statusResult.Status)
+		message := statusResult.Message
+		if status == TaskStatusFailed && statusResult.Error != "" {
+			message += ": " + statusResult.Error
+		}
+
+		// 完成时先保存结果，再与完成状态一起写入文件路径，终止事件和回调发出时结果已经可以下载
+		var resultPath string
+		if status == TaskStatusCompleted {
+			resultPath = filepath.Join(cfg.FilePaths.DownloadDir, whisperXResultFileName(taskID))
+			if err := os.WriteFile(resultPath, statusBody, 0644); err != nil {
 				utils.Log.Errorf("failed to save result to file: %v", err)
+				updateRunningTaskStatus(taskID, TaskStatusFailed, fmt.Sprintf("Error writing file: %v", err))
+				return
+			}
+		}
+
+		updateRunningTask(taskID, func(task *TaskInfo) {
+			task.Status = status
+			task.Message = message
+			if stage != "" {
+				task.Stage = stage
+			}
+			if progress >= 0 {
+				task.Progress = progress
+			}
+			if len(statusResult.AvailableFiles) > 0 {
+				task.AvailableFiles = statusResult.AvailableFiles
 			}
+			if resultPath != "" {
+				task.FilePath = resultPath
+			}
+		})
+
+		if status == TaskStatusCompleted {
 			utils.Log.Infof("WhisperX task %s completed successfully", taskID)
 			return
-		} else if status == "failed" {
-			utils.Log.Errorf("WhisperX task %s failed: %v", taskID, statusResult["error"])
+		} else if status == TaskStatusFailed {
+			utils.Log.Errorf("WhisperX task %s failed: %v", taskID, statusResult.Error)
+			return
+		} else if status == TaskStatusCancelled {
+			utils.Log.Infof("WhisperX task %s was cancelled on WhisperX service", taskID)
 			return
 		}
 	}
 
 	// 如果达到最大轮询次数仍未完成，记录超时错误
+	updateRunningTaskStatus(taskID, TaskStatusFailed, "WhisperX task polling timeout after 15 minutes")
 	utils.Log.Errorf("WhisperX task %s polling timeout after %d attempts (15 minutes)", taskID, maxAttempts)
 }
 
+// fetchWhisperXStatus 查询 WhisperX 服务上的任务状态，返回状态码和原始响应体
+func fetchWhisperXStatus(taskID string, cfg *config.Config) (int, []byte, error) {
+	statusResp, err := http.Get(fmt.Sprintf("%s/whisperx/status/%s", cfg.WhisperX.URL, taskID))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer statusResp.Body.Close()
+
+	statusBody, err := io.ReadAll(statusResp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read status response body: %v", err)
+	}
+	return statusResp.StatusCode, statusBody, nil
+}
+
 // startEnhancedWhisperXService 启动增强版WhisperX服务，支持更多参数
-func startEnhancedWhisperXService(filePath string, cfg *config.Config, params WhisperXParams) (string, error) {
+func startEnhancedWhisperXService(filePath, filename string, cfg *config.Config, params WhisperXParams, callback callbackTarget) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %v", err)
@@ -273,7 +401,8 @@ func startEnhancedWhisperXService(filePath string, cfg *config.Config, params Wh
 		return "", fmt.Errorf("no task_id in response")
 	}
 
-	go pollWhisperXStatus(taskID, cfg)
+	registerWhisperXTask(taskID, filename, filePath, params, callback)
+	go pollWhisperXStatus(startTaskPoller(taskID), taskID, cfg)
 
 	return taskID, nil
 }
@@ -3,10 +3,11 @@ package main
 import (
 	"fmt"
 
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/ai"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/config"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/handlers"
+	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/storage"
 	"github.com/AimMetal-jy/AuraLab-backend/BlueLM/utils"
-	"github.com/dingdinglz/vivo"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +22,37 @@ func main() {
 		utils.Log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// 打开嵌入式数据库并恢复任务状态
+	db, err := storage.Open(cfg.FilePaths.DataDir)
+	if err != nil {
+		utils.Log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer db.Close()
+	if err := handlers.InitTaskManager(db, cfg); err != nil {
+		utils.Log.Fatalf("Failed to init task manager: %v", err)
+	}
+	if err := handlers.InitConversationStore(db); err != nil {
+		utils.Log.Fatalf("Failed to init conversation store: %v", err)
+	}
+	if err := handlers.InitGlossaryStore(db); err != nil {
+		utils.Log.Fatalf("Failed to init glossary store: %v", err)
+	}
+	if err := handlers.InitRubricStore(db, cfg); err != nil {
+		utils.Log.Fatalf("Failed to init rubric store: %v", err)
+	}
+	if err := handlers.InitEvaluationHistory(db, cfg); err != nil {
+		utils.Log.Fatalf("Failed to init evaluation history: %v", err)
+	}
+	if err := handlers.InitTranslationCache(db, cfg); err != nil {
+		utils.Log.Fatalf("Failed to init translation cache: %v", err)
+	}
+	if err := handlers.InitTTSCache(cfg); err != nil {
+		utils.Log.Fatalf("Failed to init TTS cache: %v", err)
+	}
+
+	// 启动上传和下载目录的定期清理
+	handlers.StartJanitor(cfg)
+
 	ginServer := gin.Default()
 
 	// 配置CORS中间件
@@ -28,39 +60,86 @@ func main() {
 	corsConfig.AllowAllOrigins = true
 	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
 	ginServer.Use(cors.New(corsConfig))
-	app := vivo.NewVivoAIGC(vivo.Config{
-		AppID:  cfg.VivoAI.AppID,
-		AppKey: cfg.VivoAI.AppKey,
-	})
+	resolver, err := ai.NewResolver(cfg)
+	if err != nil {
+		utils.Log.Fatalf("Failed to create AI provider: %v", err)
+	}
+	utils.Log.Infof("Using AI provider: %s", resolver.Name())
 
 	// Register handlers
 	// Health check endpoint
 	ginServer.GET("/bluelm/health", handlers.HealthHandler)
 
 	// Legacy endpoints (保持向后兼容)
-	ginServer.POST("/bluelm/tts", handlers.TTSHandler(app, cfg))
-	ginServer.POST("/bluelm/transcription", handlers.TranscriptionHandler(app, cfg))
-	ginServer.POST("/bluelm/chat", handlers.ChatHandler(app, cfg))
-	ginServer.POST("/bluelm/chat/multimodal", handlers.MultimodalChatHandler(app, cfg))
+	ginServer.POST("/bluelm/tts", handlers.TTSHandler(resolver, cfg))
+	ginServer.GET("/bluelm/tts/voices", handlers.TTSVoicesHandler(cfg))
+	ginServer.GET("/bluelm/tts/audio/:hash", handlers.TTSAudioHandler(cfg))
+	ginServer.GET("/bluelm/tts/status/:task_id", handlers.TranscriptionStatusHandler(cfg))
+	ginServer.GET("/bluelm/tts/download/:task_id", handlers.TTSDownloadHandler(cfg))
+	ginServer.GET("/bluelm/tts/tasks", handlers.TTSTasksHandler(cfg))
+	ginServer.POST("/bluelm/transcription", handlers.TranscriptionHandler(resolver, cfg))
+	ginServer.POST("/bluelm/chat", handlers.ChatHandler(resolver, cfg))
+	ginServer.POST("/bluelm/chat/stream", handlers.ChatStreamHandler(resolver, cfg))
+	ginServer.POST("/bluelm/chat/multimodal", handlers.MultimodalChatHandler(resolver, cfg))
+	ginServer.GET("/bluelm/chat/sessions", handlers.ChatSessionsHandler(cfg))
+	ginServer.GET("/bluelm/chat/sessions/:session_id", handlers.ChatSessionHandler(cfg))
+	ginServer.PATCH("/bluelm/chat/sessions/:session_id", handlers.ChatSessionRenameHandler(cfg))
+	ginServer.POST("/bluelm/chat/sessions/:session_id/fork", handlers.ChatSessionForkHandler(cfg))
+	ginServer.DELETE("/bluelm/chat/sessions/:session_id", handlers.ChatSessionDeleteHandler(cfg))
+	ginServer.GET("/bluelm/chat/images/:image_id", handlers.ChatImageHandler(cfg))
 	ginServer.POST("/whisperx", handlers.WhisperXHandler(cfg))
 	ginServer.GET("/bluelm/transcription/status/:task_id", handlers.TranscriptionStatusHandler(cfg))
 	ginServer.GET("/bluelm/transcription/download/:task_id", handlers.TranscriptionDownloadHandler(cfg))
 	ginServer.GET("/bluelm/transcription/tasks", handlers.TranscriptionTasksHandler(cfg))
+	ginServer.POST("/bluelm/bilingual", handlers.BilingualHandler(resolver, cfg))
+	ginServer.GET("/bluelm/bilingual/status/:task_id", handlers.TranscriptionStatusHandler(cfg))
+	ginServer.GET("/bluelm/bilingual/download/:task_id", handlers.BilingualDownloadHandler(cfg))
+	ginServer.GET("/bluelm/bilingual/tasks", handlers.BilingualTasksHandler(cfg))
+	ginServer.POST("/tasks/:task_id/cancel", handlers.TaskCancelHandler(cfg))
+	ginServer.GET("/tasks/:task_id/events", handlers.TaskEventsHandler(cfg))
 	// WhisperX状态和下载接口现在通过统一API提供
 
 	// 统一的模型API接口
-	ginServer.POST("/model", handlers.UnifiedModelHandler(app, cfg))
-	ginServer.GET("/model", handlers.UnifiedModelHandler(app, cfg))
+	ginServer.POST("/model", handlers.UnifiedModelHandler(resolver, cfg))
+	ginServer.GET("/model", handlers.UnifiedModelHandler(resolver, cfg))
 
 	// 翻译接口
-	ginServer.POST("/translate", handlers.TranslationHandler)
+	ginServer.POST("/translate", handlers.TranslationHandler(resolver, cfg))
+	ginServer.POST("/translate/batch", handlers.BatchTranslationHandler(resolver, cfg))
 	ginServer.GET("/translate/languages", handlers.GetSupportedLanguagesHandler)
+	ginServer.POST("/translate/glossaries", handlers.GlossaryCreateHandler(cfg))
+	ginServer.GET("/translate/glossaries", handlers.GlossaryListHandler(cfg))
+	ginServer.GET("/translate/glossaries/:glossary_id", handlers.GlossaryHandler(cfg))
+	ginServer.PUT("/translate/glossaries/:glossary_id", handlers.GlossaryReplaceHandler(cfg))
+	ginServer.DELETE("/translate/glossaries/:glossary_id", handlers.GlossaryDeleteHandler(cfg))
 
 	// 翻译AI评估接口
-	ginServer.POST("/translate/evaluate", handlers.TranslationEvaluationHandler(app, cfg))
+	ginServer.POST("/translate/evaluate", handlers.TranslationEvaluationHandler(resolver, cfg))
+	ginServer.POST("/translate/evaluate/batch", handlers.BatchEvaluationHandler(resolver, cfg))
+	ginServer.GET("/translate/evaluate/batch/status/:task_id", handlers.TranscriptionStatusHandler(cfg))
+	ginServer.GET("/translate/evaluate/batch/download/:task_id", handlers.BatchEvaluationDownloadHandler(cfg))
+	ginServer.GET("/translate/evaluate/batch/tasks", handlers.BatchEvaluationTasksHandler(cfg))
+	ginServer.POST("/translate/rubrics", handlers.RubricCreateHandler(cfg))
+	ginServer.GET("/translate/rubrics", handlers.RubricListHandler(cfg))
+	ginServer.GET("/translate/rubrics/:name", handlers.RubricHandler(cfg))
+	ginServer.PUT("/translate/rubrics/:name", handlers.RubricReplaceHandler(cfg))
+	ginServer.DELETE("/translate/rubrics/:name", handlers.RubricDeleteHandler(cfg))
+	ginServer.GET("/translate/learners/:learner_id/evaluations", handlers.LearnerEvaluationsHandler(cfg))
+	ginServer.DELETE("/translate/learners/:learner_id/evaluations", handlers.LearnerHistoryDeleteHandler(cfg))
+	ginServer.GET("/translate/learners/:learner_id/progress", handlers.LearnerProgressHandler(cfg))
+	ginServer.GET("/translate/learners/:learner_id/improvements", handlers.LearnerImprovementsHandler(cfg))
+	ginServer.GET("/translate/learners/:learner_id/summary", handlers.LearnerSummaryHandler(cfg))
 
 	// OCR接口
-	ginServer.POST("/ocr", handlers.OCRHandler(app, cfg))
+	ginServer.POST("/ocr", handlers.OCRHandler(resolver, cfg))
+
+	// 管理接口
+	ginServer.GET("/admin/janitor", handlers.JanitorStatusHandler(cfg))
+	ginServer.POST("/admin/janitor/run", handlers.JanitorRunHandler(cfg))
+	ginServer.GET("/admin/translation/cache", handlers.TranslationCacheStatusHandler(cfg))
+	ginServer.POST("/admin/translation/cache/purge", handlers.TranslationCachePurgeHandler(cfg))
+	ginServer.GET("/admin/tts/cache", handlers.TTSCacheStatusHandler(cfg))
+	ginServer.POST("/admin/tts/cache/purge", handlers.TTSCachePurgeHandler(cfg))
 
 	// 测试接口
 	ginServer.GET("/test", handlers.TestHandler)
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DBFileName 嵌入式数据库文件名，位于 file_paths.data_dir 下
+const DBFileName = "bluelm.db"
+
+// Open 在数据目录下打开（或创建）BlueLM 的嵌入式数据库
+// 数据库被其他进程占用时最多等待1秒，避免同时启动两个实例时卡死
+func Open(dataDir string) (*bolt.DB, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data dir %s: %v", dataDir, err)
+	}
+
+	dbPath := filepath.Join(dataDir, DBFileName)
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %v", dbPath, err)
+	}
+	return db, nil
+}
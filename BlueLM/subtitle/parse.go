@@ -0,0 +1,109 @@
+package subtitle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// blueLMSegment 蓝心长语音转写结果中的一句，时间单位为毫秒
+type blueLMSegment struct {
+	Bg      int    `json:"bg"`
+	Ed      int    `json:"ed"`
+	Onebest string `json:"onebest"`
+}
+
+// ParseBlueLM 解析蓝心转写结果文件 transcription_<id>.json
+func ParseBlueLM(data []byte) ([]Segment, error) {
+	var raw []blueLMSegment
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid BlueLM transcription result: %v", err)
+	}
+
+	segments := make([]Segment, 0, len(raw))
+	for _, r := range raw {
+		text := strings.TrimSpace(r.Onebest)
+		if text == "" {
+			continue
+		}
+		segments = append(segments, Segment{
+			Start: time.Duration(r.Bg) * time.Millisecond,
+			End:   time.Duration(r.Ed) * time.Millisecond,
+			Text:  text,
+		})
+	}
+	return segments, nil
+}
+
+// whisperXResult WhisperX 的 transcription.json / wordstamps.json / speaker_segments.json，时间单位为秒
+type whisperXResult struct {
+	Segments []struct {
+		Start   float64 `json:"start"`
+		End     float64 `json:"end"`
+		Text    string  `json:"text"`
+		Speaker string  `json:"speaker"`
+		Words   []struct {
+			Word    string   `json:"word"`
+			Start   *float64 `json:"start"` // 数字等无法对齐的词没有时间戳
+			End     *float64 `json:"end"`
+			Speaker string   `json:"speaker"`
+		} `json:"words"`
+	} `json:"segments"`
+}
+
+// ParseWhisperX 解析 WhisperX 的结果文件，三种结果文件结构相同，只是字段多少不同
+func ParseWhisperX(data []byte) ([]Segment, error) {
+	var raw whisperXResult
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid WhisperX result: %v", err)
+	}
+
+	segments := make([]Segment, 0, len(raw.Segments))
+	for _, r := range raw.Segments {
+		text := strings.TrimSpace(r.Text)
+		if text == "" {
+			continue
+		}
+		seg := Segment{
+			Start:   seconds(r.Start),
+			End:     seconds(r.End),
+			Text:    text,
+			Speaker: r.Speaker,
+		}
+		for _, w := range r.Words {
+			word := Word{Text: strings.TrimSpace(w.Word), Speaker: w.Speaker}
+			if w.Start != nil && w.End != nil {
+				word.Start, word.End = seconds(*w.Start), seconds(*w.End)
+			}
+			seg.Words = append(seg.Words, word)
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// AssignSpeakers 按时间重叠为没有说话人的字幕补充说话人标签
+// 用于将 speaker_segments 的结果合并到 transcription / wordstamps 上
+func AssignSpeakers(segments, speakerSegments []Segment) {
+	for i := range segments {
+		if segments[i].Speaker != "" {
+			continue
+		}
+		var best time.Duration
+		for _, s := range speakerSegments {
+			if s.Speaker == "" {
+				continue
+			}
+			overlap := min(segments[i].End, s.End) - max(segments[i].Start, s.Start)
+			if overlap > best {
+				best = overlap
+				segments[i].Speaker = s.Speaker
+			}
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,57 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBlueLM(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		data    string
+		want    []Segment
+		wantErr bool
+	}{
+		{
+			name: "segments",
+			data: `[{"bg":0,"ed":1200,"onebest":" 你好 "},{"bg":1200,"ed":2500,"onebest":"世界"}]`,
+			want: []Segment{
+				{Start: 0, End: 1200 * ms, Text: "你好"},
+				{Start: 1200 * ms, End: 2500 * ms, Text: "世界"},
+			},
+		},
+		{
+			name: "skip empty text",
+			data: `[{"bg":0,"ed":500,"onebest":"  "},{"bg":500,"ed":900,"onebest":"嗯"}]`,
+			want: []Segment{{Start: 500 * ms, End: 900 * ms, Text: "嗯"}},
+		},
+		{
+			name: "empty result",
+			data: `[]`,
+			want: []Segment{},
+		},
+		{
+			name:    "not an array",
+			data:    `{"bg":0}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			data:    `[{"bg":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBlueLM([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBlueLM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBlueLM() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
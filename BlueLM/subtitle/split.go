@@ -0,0 +1,255 @@
+package subtitle
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options 长句切分限制，值为0表示不限制
+type Options struct {
+	MaxLineLength int           // 每条字幕的最大字符数
+	MaxDuration   time.Duration // 每条字幕的最长显示时间
+}
+
+// Split 按字数和时长限制切分过长的字幕
+// 有单词级时间戳时按单词边界和真实时间切分，并在说话人变化处断开；否则按标点和字数切分并按字数比例分配时间
+func Split(segments []Segment, opts Options) []Segment {
+	result := make([]Segment, 0, len(segments))
+	for _, seg := range segments {
+		if !opts.exceeds(seg) && !mixedSpeakers(seg.Words) {
+			result = append(result, seg)
+			continue
+		}
+		if timedWords(seg.Words) {
+			result = append(result, splitByWords(seg, opts)...)
+		} else {
+			result = append(result, splitByText(seg, opts)...)
+		}
+	}
+	return result
+}
+
+// exceeds 判断字幕是否超出限制
+func (o Options) exceeds(seg Segment) bool {
+	return (o.MaxLineLength > 0 && utf8.RuneCountInString(seg.Text) > o.MaxLineLength) ||
+		(o.MaxDuration > 0 && seg.End-seg.Start > o.MaxDuration)
+}
+
+// timedWords 判断是否至少有一个单词带时间戳
+func timedWords(words []Word) bool {
+	for _, w := range words {
+		if w.End > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// mixedSpeakers 判断一句话中是否出现了多个说话人
+func mixedSpeakers(words []Word) bool {
+	speaker := ""
+	for _, w := range words {
+		if w.Speaker == "" {
+			continue
+		}
+		if speaker != "" && w.Speaker != speaker {
+			return true
+		}
+		speaker = w.Speaker
+	}
+	return false
+}
+
+// splitByWords 按单词时间戳贪心切分
+func splitByWords(seg Segment, opts Options) []Segment {
+	// 原文有空格（如英文）时用空格连接单词，中文等直接拼接
+	sep := ""
+	if strings.Contains(seg.Text, " ") {
+		sep = " "
+	}
+
+	var result []Segment
+	var cur *Segment
+	for _, w := range seg.Words {
+		if w.Text == "" {
+			continue
+		}
+		// 没有时间戳的单词沿用前一个单词的结束时间
+		if w.End == 0 {
+			if cur != nil {
+				w.Start, w.End = cur.End, cur.End
+			} else {
+				w.Start, w.End = seg.Start, seg.Start
+			}
+		}
+		speaker := w.Speaker
+		if speaker == "" {
+			speaker = seg.Speaker
+		}
+
+		if cur != nil {
+			candidate := Segment{Start: cur.Start, End: max(cur.End, w.End), Text: cur.Text + sep + w.Text}
+			if opts.exceeds(candidate) || (speaker != "" && cur.Speaker != "" && speaker != cur.Speaker) {
+				result = append(result, *cur)
+				cur = nil
+			} else {
+				cur.End, cur.Text = candidate.End, candidate.Text
+				if cur.Speaker == "" {
+					cur.Speaker = speaker
+				}
+				continue
+			}
+		}
+		cur = &Segment{Start: w.Start, End: w.End, Text: w.Text, Speaker: speaker}
+	}
+	if cur != nil {
+		result = append(result, *cur)
+	}
+	if len(result) == 0 {
+		return []Segment{seg}
+	}
+	return result
+}
+
+// splitByText 没有单词时间戳时按标点和字数切分，时间按字数比例分配
+// 先按标点拆成分句并尽量合并到字数上限内，过长的分句再平均拆分，避免出现过短的尾句
+func splitByText(seg Segment, opts Options) []Segment {
+	total := utf8.RuneCountInString(seg.Text)
+	if total == 0 {
+		return []Segment{seg}
+	}
+
+	var chunks [][]string
+	var cur []string
+	for _, clause := range clauses(tokenize(seg.Text)) {
+		n := runeCount(clause)
+		if opts.MaxLineLength > 0 && n > opts.MaxLineLength {
+			if len(cur) > 0 {
+				chunks = append(chunks, cur)
+				cur = nil
+			}
+			chunks = append(chunks, evenSplit(clause, (n+opts.MaxLineLength-1)/opts.MaxLineLength)...)
+			continue
+		}
+		if opts.MaxLineLength > 0 && len(cur) > 0 && runeCount(cur)+n > opts.MaxLineLength {
+			chunks = append(chunks, cur)
+			cur = nil
+		}
+		cur = append(cur, clause...)
+	}
+	if len(cur) > 0 {
+		chunks = append(chunks, cur)
+	}
+
+	// 按字数比例估算时长，超出时长限制的块继续平均拆分
+	duration := seg.End - seg.Start
+	if opts.MaxDuration > 0 && duration > opts.MaxDuration {
+		var limited [][]string
+		for _, chunk := range chunks {
+			d := duration * time.Duration(runeCount(chunk)) / time.Duration(total)
+			if d > opts.MaxDuration {
+				limited = append(limited, evenSplit(chunk, int((d+opts.MaxDuration-1)/opts.MaxDuration))...)
+			} else {
+				limited = append(limited, chunk)
+			}
+		}
+		chunks = limited
+	}
+
+	result := make([]Segment, 0, len(chunks))
+	offset := 0
+	for _, chunk := range chunks {
+		n := runeCount(chunk)
+		result = append(result, Segment{
+			Start:   seg.Start + duration*time.Duration(offset)/time.Duration(total),
+			End:     seg.Start + duration*time.Duration(offset+n)/time.Duration(total),
+			Text:    strings.TrimSpace(strings.Join(chunk, "")),
+			Speaker: seg.Speaker,
+		})
+		offset += n
+	}
+	return result
+}
+
+// tokenize 将文本拆成不可再分的片段：中日韩文字和全角标点逐字拆分，其他文字按单词拆分（保留后随空格）
+func tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			word.WriteRune(r)
+			flush()
+		case isCJK(r) || unicode.IsPunct(r) && r > unicode.MaxLatin1:
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// clauses 在以标点结尾的片段之后断开，得到分句
+func clauses(tokens []string) [][]string {
+	var result [][]string
+	start := 0
+	for i, token := range tokens {
+		if endsWithPunct(token) {
+			result = append(result, tokens[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		result = append(result, tokens[start:])
+	}
+	return result
+}
+
+// evenSplit 将片段平均拆成 parts 块
+func evenSplit(tokens []string, parts int) [][]string {
+	if parts <= 1 {
+		return [][]string{tokens}
+	}
+	target := (runeCount(tokens) + parts - 1) / parts
+
+	var result [][]string
+	start, length := 0, 0
+	for i, token := range tokens {
+		length += utf8.RuneCountInString(token)
+		if length >= target && len(result) < parts-1 {
+			result = append(result, tokens[start:i+1])
+			start, length = i+1, 0
+		}
+	}
+	if start < len(tokens) {
+		result = append(result, tokens[start:])
+	}
+	return result
+}
+
+func runeCount(tokens []string) int {
+	n := 0
+	for _, t := range tokens {
+		n += utf8.RuneCountInString(t)
+	}
+	return n
+}
+
+func endsWithPunct(token string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimRight(token, " "))
+	return unicode.IsPunct(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
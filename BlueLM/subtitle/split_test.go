@@ -0,0 +1,102 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name     string
+		segments []Segment
+		opts     Options
+		want     []Segment
+	}{
+		{
+			name:     "within limits",
+			segments: []Segment{{Start: 0, End: 2 * s, Text: "短句"}},
+			opts:     Options{MaxLineLength: 10, MaxDuration: 5 * s},
+			want:     []Segment{{Start: 0, End: 2 * s, Text: "短句"}},
+		},
+		{
+			name:     "no limits",
+			segments: []Segment{{Start: 0, End: 60 * s, Text: "一句很长很长很长很长很长很长的话"}},
+			want:     []Segment{{Start: 0, End: 60 * s, Text: "一句很长很长很长很长很长很长的话"}},
+		},
+		{
+			name:     "split text at punctuation",
+			segments: []Segment{{Start: 0, End: 12 * s, Text: "你好，世界。今天也好。", Speaker: "A"}},
+			opts:     Options{MaxLineLength: 6},
+			want: []Segment{
+				{Start: 0, End: 12 * s * 6 / 11, Text: "你好，世界。", Speaker: "A"},
+				{Start: 12 * s * 6 / 11, End: 12 * s, Text: "今天也好。", Speaker: "A"},
+			},
+		},
+		{
+			name:     "split long clause evenly",
+			segments: []Segment{{Start: 0, End: 8 * s, Text: "一二三四五六七八"}},
+			opts:     Options{MaxLineLength: 5},
+			want: []Segment{
+				{Start: 0, End: 4 * s, Text: "一二三四"},
+				{Start: 4 * s, End: 8 * s, Text: "五六七八"},
+			},
+		},
+		{
+			name:     "split text by duration",
+			segments: []Segment{{Start: 0, End: 10 * s, Text: "one two three four"}},
+			opts:     Options{MaxDuration: 6 * s},
+			want: []Segment{
+				{Start: 0, End: 10 * s * 14 / 18, Text: "one two three"},
+				{Start: 10 * s * 14 / 18, End: 10 * s, Text: "four"},
+			},
+		},
+		{
+			name: "split words by duration",
+			segments: []Segment{{Start: 0, End: 3 * s, Text: "a b c", Words: []Word{
+				{Start: 0, End: s, Text: "a"},
+				{Start: s, End: 2 * s, Text: "b"},
+				{Start: 2 * s, End: 3 * s, Text: "c"},
+			}}},
+			opts: Options{MaxDuration: 2 * s},
+			want: []Segment{
+				{Start: 0, End: 2 * s, Text: "a b"},
+				{Start: 2 * s, End: 3 * s, Text: "c"},
+			},
+		},
+		{
+			name: "split words at speaker change",
+			segments: []Segment{{Start: 0, End: 3 * s, Text: "hello there world", Words: []Word{
+				{Start: 0, End: s, Text: "hello", Speaker: "A"},
+				{Start: s, End: 2 * s, Text: "there", Speaker: "A"},
+				{Start: 2 * s, End: 3 * s, Text: "world", Speaker: "B"},
+			}}},
+			want: []Segment{
+				{Start: 0, End: 2 * s, Text: "hello there", Speaker: "A"},
+				{Start: 2 * s, End: 3 * s, Text: "world", Speaker: "B"},
+			},
+		},
+		{
+			name: "untimed words follow previous word",
+			segments: []Segment{{Start: s, End: 4 * s, Text: "你好世界", Words: []Word{
+				{Start: s, End: 2 * s, Text: "你好"},
+				{Text: "世"},
+				{Start: 3 * s, End: 4 * s, Text: "界"},
+			}}},
+			opts: Options{MaxLineLength: 3},
+			want: []Segment{
+				{Start: s, End: 2 * s, Text: "你好世"},
+				{Start: 3 * s, End: 4 * s, Text: "界"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.segments, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
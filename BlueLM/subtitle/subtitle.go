@@ -0,0 +1,130 @@
+// Package subtitle 将蓝心和 WhisperX 的转写结果转换为 SRT、WebVTT、纯文本和 TSV 字幕
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format 字幕格式
+type Format string
+
+const (
+	FormatSRT Format = "srt"
+	FormatVTT Format = "vtt"
+	FormatTXT Format = "txt"
+	FormatTSV Format = "tsv"
+)
+
+// ParseFormat 解析格式名称（不区分大小写，webvtt 视为 vtt）
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "srt":
+		return FormatSRT, nil
+	case "vtt", "webvtt":
+		return FormatVTT, nil
+	case "txt", "text":
+		return FormatTXT, nil
+	case "tsv":
+		return FormatTSV, nil
+	}
+	return "", fmt.Errorf("unsupported subtitle format %q (supported: srt, vtt, txt, tsv)", name)
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatSRT:
+		return "application/x-subrip; charset=utf-8"
+	case FormatVTT:
+		return "text/vtt; charset=utf-8"
+	case FormatTSV:
+		return "text/tab-separated-values; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension 返回格式对应的文件扩展名（含点号）
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Word 单词级时间戳，Start/End 为零表示对齐模型未给出时间
+type Word struct {
+	Start   time.Duration
+	End     time.Duration
+	Text    string
+	Speaker string
+}
+
+// Segment 一条字幕
+type Segment struct {
+	Start   time.Duration
+	End     time.Duration
+	Text    string
+	Speaker string // 说话人标签，未做说话人分离时为空
+	Words   []Word // 单词级时间戳，用于精确切分长句
+}
+
+// Render 将字幕按指定格式写入 w
+func Render(w io.Writer, format Format, segments []Segment) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatSRT:
+		for i, seg := range segments {
+			fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1,
+				formatTimestamp(seg.Start, ","), formatTimestamp(seg.End, ","), labelled(seg, "[%s] "))
+		}
+	case FormatVTT:
+		bw.WriteString("WEBVTT\n\n")
+		for _, seg := range segments {
+			text := seg.Text
+			if seg.Speaker != "" {
+				text = fmt.Sprintf("<v %s>%s", seg.Speaker, seg.Text)
+			}
+			fmt.Fprintf(bw, "%s --> %s\n%s\n\n",
+				formatTimestamp(seg.Start, "."), formatTimestamp(seg.End, "."), text)
+		}
+	case FormatTXT:
+		// 说话人变化时才输出标签，便于阅读
+		lastSpeaker := ""
+		for _, seg := range segments {
+			if seg.Speaker != "" && seg.Speaker != lastSpeaker {
+				fmt.Fprintf(bw, "%s: %s\n", seg.Speaker, seg.Text)
+			} else {
+				fmt.Fprintf(bw, "%s\n", seg.Text)
+			}
+			lastSpeaker = seg.Speaker
+		}
+	case FormatTSV:
+		// 与 Whisper 的 TSV 输出一致，时间单位为毫秒
+		bw.WriteString("start\tend\tspeaker\ttext\n")
+		for _, seg := range segments {
+			fmt.Fprintf(bw, "%d\t%d\t%s\t%s\n", seg.Start.Milliseconds(), seg.End.Milliseconds(),
+				seg.Speaker, strings.NewReplacer("\t", " ", "\n", " ").Replace(seg.Text))
+		}
+	default:
+		return fmt.Errorf("unsupported subtitle format %q", format)
+	}
+	return bw.Flush()
+}
+
+// labelled 为带说话人的字幕添加前缀
+func labelled(seg Segment, prefix string) string {
+	if seg.Speaker == "" {
+		return seg.Text
+	}
+	return fmt.Sprintf(prefix, seg.Speaker) + seg.Text
+}
+
+// formatTimestamp 格式化为 HH:MM:SS<sep>mmm
+func formatTimestamp(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
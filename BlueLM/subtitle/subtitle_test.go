@@ -0,0 +1,67 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	segments := []Segment{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "hi", Speaker: "S1"},
+		{Start: 3 * time.Second, End: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, Text: "there\tyou", Speaker: "S1"},
+		{Start: -time.Second, End: time.Second, Text: "bye"},
+	}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatSRT, "1\n00:00:01,500 --> 00:00:03,000\n[S1] hi\n\n" +
+			"2\n00:00:03,000 --> 01:02:03,004\n[S1] there\tyou\n\n" +
+			"3\n00:00:00,000 --> 00:00:01,000\nbye\n\n"},
+		{FormatVTT, "WEBVTT\n\n" +
+			"00:00:01.500 --> 00:00:03.000\n<v S1>hi\n\n" +
+			"00:00:03.000 --> 01:02:03.004\n<v S1>there\tyou\n\n" +
+			"00:00:00.000 --> 00:00:01.000\nbye\n\n"},
+		{FormatTXT, "S1: hi\nthere\tyou\nbye\n"},
+		{FormatTSV, "start\tend\tspeaker\ttext\n" +
+			"1500\t3000\tS1\thi\n" +
+			"3000\t3723004\tS1\tthere you\n" +
+			"-1000\t1000\t\tbye\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b strings.Builder
+			if err := Render(&b, tt.format, segments); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Render() =\n%q\nwant\n%q", b.String(), tt.want)
+			}
+		})
+	}
+
+	if err := Render(&strings.Builder{}, Format("ass"), segments); err == nil {
+		t.Error("Render() with unsupported format returned no error")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Format
+		wantErr bool
+	}{
+		{"srt", FormatSRT, false},
+		{" WebVTT ", FormatVTT, false},
+		{"text", FormatTXT, false},
+		{"TSV", FormatTSV, false},
+		{"ass", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
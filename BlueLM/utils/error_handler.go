@@ -13,6 +13,15 @@ type Error struct {
 	// The error message
 	// in: body
 	Message string `json:"message"`
+	// Field-level validation errors
+	// in: body
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a validation error for a single request field.
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
 }
 
 // ErrorHandler is a middleware to handle errors gracefully.
@@ -29,4 +38,10 @@ func AbortWithInternalServerError(c *gin.Context, err error) {
 // AbortWithBadRequest responds with a 400 Bad Request error.
 func AbortWithBadRequest(c *gin.Context, err error, message string) {
 	ErrorHandler(c, err, http.StatusBadRequest, message)
-}
\ No newline at end of file
+}
+
+// AbortWithValidationErrors responds with a 400 Bad Request error listing the invalid fields.
+func AbortWithValidationErrors(c *gin.Context, message string, errs []FieldError) {
+	logrus.WithField("errors", errs).Error(message)
+	c.JSON(http.StatusBadRequest, Error{Message: message, Errors: errs})
+}
@@ -27,6 +27,10 @@ os.makedirs(DOWNLOAD_FOLDER, exist_ok=True)
 processing_tasks: Dict[str, Dict[str, Any]] = {}
 whisperx_service = WhisperXService()
 
+class TaskCancelled(Exception):
+    """任务已被取消，用于在处理阶段之间中断WhisperX流水线"""
+    pass
+
 def allowed_file(filename):
     return '.' in filename and filename.rsplit('.', 1)[1].lower() in ALLOWED_EXTENSIONS
 
@@ -44,6 +48,10 @@ def progress_callback(task_id: str, step: int, message: str, data: Optional[Dict
     
     task = processing_tasks[task_id]
     
+    # 已取消的任务不再更新状态
+    if task['status'] == 'cancelled':
+        return
+    
     if step == -1:  # 错误
         task['status'] = 'failed'
         task['message'] = message
@@ -98,11 +106,18 @@ def process_audio_async(task_id: str, audio_file_path: str, output_dir: str,
     异步处理音频文件
     """
     try:
+        if processing_tasks[task_id]['status'] == 'cancelled':
+            if os.path.exists(audio_file_path):
+                os.remove(audio_file_path)
+            return
+        
         processing_tasks[task_id]['status'] = 'processing'
         processing_tasks[task_id]['message'] = 'Processing audio file...'
         
-        # 定义回调函数
+        # 定义回调函数，任务被取消后在下一个处理阶段开始前中断流水线
         def callback(step, message, data=None):
+            if step >= 0 and processing_tasks[task_id]['status'] == 'cancelled':
+                raise TaskCancelled(f'Task {task_id} cancelled')
             progress_callback(task_id, step, message, data)
         
         # 调用WhisperX服务处理音频
@@ -117,16 +132,21 @@ def process_audio_async(task_id: str, audio_file_path: str, output_dir: str,
             compute_type=compute_type
         )
         
+        # 清理临时上传文件
+        if os.path.exists(audio_file_path):
+            os.remove(audio_file_path)
+        
+        if processing_tasks[task_id]['status'] == 'cancelled':
+            return
+        
         if not result['success']:
             processing_tasks[task_id]['status'] = 'failed'
         processing_tasks[task_id]['message'] = result['message']
         processing_tasks[task_id]['error'] = result.get('error', '')
-        
-        # 清理临时上传文件
-        if os.path.exists(audio_file_path):
-            os.remove(audio_file_path)
             
     except Exception as e:
+        if processing_tasks[task_id]['status'] == 'cancelled':
+            return
         processing_tasks[task_id]['status'] = 'failed'
         processing_tasks[task_id]['message'] = f'Processing failed: {str(e)}'
         processing_tasks[task_id]['error'] = str(e)
@@ -292,6 +312,35 @@ def get_task_status(task_id):
     
     return jsonify(response)
 
+@app.route('/whisperx/cancel/<task_id>', methods=['POST'])
+def cancel_task(task_id):
+    """
+    取消任务接口
+    正在执行的模型推理无法被打断，流水线会在下一个处理阶段开始前停止
+    """
+    if task_id not in processing_tasks:
+        return jsonify({
+            'success': False,
+            'message': 'Task not found'
+        }), 404
+    
+    task = processing_tasks[task_id]
+    if task['status'] in ['completed', 'failed', 'cancelled']:
+        return jsonify({
+            'success': False,
+            'message': f'Task already finished. Current status: {task["status"]}'
+        }), 409
+    
+    task['status'] = 'cancelled'
+    task['message'] = 'Task cancelled by user'
+    
+    return jsonify({
+        'success': True,
+        'task_id': task_id,
+        'status': task['status'],
+        'message': task['message']
+    })
+
 @app.route('/whisperx/result/<task_id>', methods=['GET'])
 def get_task_result(task_id):
     """